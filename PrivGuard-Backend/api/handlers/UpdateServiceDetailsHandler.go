@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// UpdateServiceDetailsHandler replaces the username, URIs and custom fields of an entry
+func UpdateServiceDetailsHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		serviceID := c.Params("id")
+
+		var req services.EntryDetails
+		if err := c.BodyParser(&req); err != nil || serviceID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		err := services.UpdateServiceDetails(repo, userID, serviceID, req)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, crypto.ErrSealed) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is sealed"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update entry details",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Service details updated successfully",
+		})
+	}
+}
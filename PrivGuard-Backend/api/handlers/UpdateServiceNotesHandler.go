@@ -13,6 +13,7 @@ func UpdateServiceNotesHandler(repo storage.Repository) fiber.Handler {
 
 		var req struct {
 			Notes string `json:"notes"`
+			EncryptedNotes *services.ClientEnvelope `json:"encrypted_notes"`
 		}
 
 
@@ -22,13 +23,21 @@ func UpdateServiceNotesHandler(repo storage.Repository) fiber.Handler {
 			})
 		}
 
-		if serviceID == "" || req.Notes == "" {
+		if serviceID == "" || (req.Notes == "" && req.EncryptedNotes == nil) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Service ID and notes are required",
 			})
 		}
 
-		err := services.UpdateServiceNotes(repo, userID, serviceID, req.Notes)
+		var err error
+		if req.EncryptedNotes != nil {
+			err = services.UpdateClientEncryptedNotes(repo, userID, serviceID, *req.EncryptedNotes)
+		} else {
+			err = services.UpdateServiceNotes(repo, userID, serviceID, req.Notes)
+		}
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
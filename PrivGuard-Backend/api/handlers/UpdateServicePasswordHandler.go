@@ -13,7 +13,8 @@ func UpdateServicePasswordHandler(repo storage.Repository) fiber.Handler {
 
 		var req struct {
 			Password string `json:"password"`
-			Strength int `json:"strength"`
+			Strength int `json:"strength"` // zero-knowledge vaults only
+			EncryptedPassword *services.ClientEnvelope `json:"encrypted_password"`
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -22,13 +23,21 @@ func UpdateServicePasswordHandler(repo storage.Repository) fiber.Handler {
 			})
 		}
 
-		if serviceID == "" || req.Password == "" {
+		if serviceID == "" || (req.Password == "" && req.EncryptedPassword == nil) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Service ID and new password are required",
 			})
 		}
 
-		err := services.UpdateServicePassword(repo, userID, serviceID, req.Password, int8(req.Strength))
+		var err error
+		if req.EncryptedPassword != nil {
+			err = services.UpdateClientEncryptedPassword(repo, userID, serviceID, *req.EncryptedPassword, req.Strength)
+		} else {
+			err = services.UpdateServicePassword(repo, userID, serviceID, req.Password)
+		}
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
@@ -12,7 +12,14 @@ type AddPasswordRequest struct {
 	Logo        string `json:"logo"`
 	Password    string `json:"password"`
 	Notes       string `json:"notes"`
-	StrengthScore int  `json:"strength"`
+	StrengthScore int  `json:"strength"` // only used by zero-knowledge vaults; the server scores other passwords
+
+	// Zero-knowledge vaults send client-encrypted envelopes instead of Password/Notes
+	EncryptedPassword *services.ClientEnvelope `json:"encrypted_password"`
+	EncryptedNotes    *services.ClientEnvelope `json:"encrypted_notes"`
+
+	// Username, URIs and custom fields
+	services.EntryDetails
 }
 
 func AddPasswordHandler(repo storage.Repository) fiber.Handler {
@@ -32,8 +39,16 @@ func AddPasswordHandler(repo storage.Repository) fiber.Handler {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 		}
 
-		err := services.AddPasswordToVault(repo, userID, req.ServiceName, req.Domain, req.Logo, req.Password, req.Notes, int8(req.StrengthScore))
+		var err error
+		if req.EncryptedPassword != nil {
+			err = services.AddClientEncryptedPasswordToVault(repo, userID, req.ServiceName, req.Domain, req.Logo, *req.EncryptedPassword, req.EncryptedNotes, req.StrengthScore, req.EntryDetails)
+		} else {
+			err = services.AddPasswordToVault(repo, userID, req.ServiceName, req.Domain, req.Logo, req.Password, req.Notes, req.EntryDetails)
+		}
 
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save password"})
 		}
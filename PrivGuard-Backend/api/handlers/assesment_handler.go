@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	vaultservices "github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -11,6 +13,9 @@ import (
 
 // getAssessment retrieves user's vault services and passkey count,
 // returning service details and average strength score along with passkey count.
+// Entries not revealed for a long time are flagged as cleanup candidates, and
+// entries sharing a password are grouped by their stored fingerprints. Breached
+// entries come from the last background breach check, nothing is decrypted here.
 func GetAssessmentHandler(repo storage.Repository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Authenticate
@@ -30,27 +35,61 @@ func GetAssessmentHandler(repo storage.Repository) fiber.Handler {
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Vault not found"})
 		}
 
-		// Fetch services
+		// Fetch logins; other item types have no password to assess
 		var services []models.Service
-		if err := repo.DB.Where("vault_id = ?", vault.ID).Find(&services).Error; err != nil {
+		if err := repo.DB.Where("vault_id = ? AND item_type = ?", vault.ID, vaultservices.ItemLogin).Find(&services).Error; err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch services"})
 		}
 
 		// Build response entries and compute average strength
 		type serviceEntry struct {
-			ServiceName   string `json:"service_name"`
-			LogoURL       string `json:"logo_url"`
-			StrengthScore int8   `json:"strength_score"`
+			ID            uuid.UUID  `json:"id"`
+			ServiceName   string     `json:"service_name"`
+			LogoURL       string     `json:"logo_url"`
+			StrengthScore int8       `json:"strength_score"`
+			LastUsedAt    *time.Time `json:"last_used_at"`
+			Stale         bool       `json:"stale"`
+			Reused        bool       `json:"reused"`
+			Breached      bool       `json:"breached"`
+			BreachCount   int        `json:"breach_count"`
+			BreachChecked bool       `json:"breach_checked"`
+		}
+
+		// Group entries sharing a password; zero-knowledge vaults have no fingerprints
+		reusedGroups := [][]uuid.UUID{}
+		reused := make(map[uuid.UUID]bool)
+		for _, group := range vaultservices.ReusedPasswordGroups(services) {
+			ids := make([]uuid.UUID, len(group))
+			for i, svc := range group {
+				ids[i] = svc.ID
+				reused[svc.ID] = true
+			}
+			reusedGroups = append(reusedGroups, ids)
 		}
 
 		var result []serviceEntry
-		var totalScore int
-		for _, svc := range services {
+		var totalScore, staleCount, breachedCount int
+		now := time.Now()
+		for i, svc := range services {
+			stale := vaultservices.IsStaleEntry(&services[i], now)
+			if stale {
+				staleCount++
+			}
 			result = append(result, serviceEntry{
+				ID:            svc.ID,
 				ServiceName:   svc.ServiceName,
 				LogoURL:       svc.LogoURL,
 				StrengthScore: svc.StrengthScore,
+				LastUsedAt:    svc.LastUsedAt,
+				Stale:         stale,
+				Reused:        reused[svc.ID],
+				Breached:      svc.BreachCount > 0,
+				BreachCount:   svc.BreachCount,
+				BreachChecked: svc.BreachCheckedAt != nil,
 			})
+			if svc.BreachCount > 0 {
+				breachedCount++
+			}
 			totalScore += int(svc.StrengthScore)
 		}
 
@@ -68,10 +107,16 @@ func GetAssessmentHandler(repo storage.Repository) fiber.Handler {
 
 		// Return combined assessment
 		return c.JSON(fiber.Map{
-			"services":       result,
-			"average_score":  avgStrength,
-			"service_count":  len(result),
-			"passkey_count":  passkeyCount,
+			"services":         result,
+			"average_score":    avgStrength,
+			"service_count":    len(result),
+			"passkey_count":    passkeyCount,
+			"stale_count":      staleCount,
+			"stale_after_days": int(vaultservices.StaleEntryAge().Hours() / 24),
+			"reused_groups":    reusedGroups,
+			"reused_count":     len(reused),
+			"reuse_checked":    !vault.ZeroKnowledge,
+			"breached_count":   breachedCount,
 		})
 	}
 }
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/blobstore"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// attachmentErrorStatus maps attachment errors to a status code, or 0 if unknown
+func attachmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound),
+		errors.Is(err, blobstore.ErrNotFound),
+		errors.Is(err, gorm.ErrRecordNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, services.ErrAttachmentTooLarge), errors.Is(err, services.ErrAttachmentQuota):
+		return fiber.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrInvalidAttachment):
+		return fiber.StatusBadRequest
+	case errors.Is(err, services.ErrAttachmentsDisabled), errors.Is(err, crypto.ErrSealed):
+		return fiber.StatusServiceUnavailable
+	}
+	return vaultErrorStatus(err)
+}
+
+func attachmentJSON(a *models.Attachment) fiber.Map {
+	return fiber.Map{
+		"id":               a.ID,
+		"file_name":        a.FileName,
+		"content_type":     a.ContentType,
+		"size":             a.Size,
+		"client_encrypted": a.ClientEncrypted,
+		"created_at":       a.CreatedAt,
+	}
+}
+
+// ListAttachmentsHandler lists the files attached to an entry
+func ListAttachmentsHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		attachments, err := services.ListAttachments(repo, userID, c.Params("id"))
+		if status := attachmentErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load attachments",
+			})
+		}
+
+		used, err := services.AttachmentUsage(repo, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load attachments",
+			})
+		}
+
+		out := make([]fiber.Map, 0, len(attachments))
+		for i := range attachments {
+			out = append(out, attachmentJSON(&attachments[i]))
+		}
+		return c.JSON(fiber.Map{
+			"attachments": out,
+			"used_bytes":  used,
+			"quota_bytes": services.AttachmentQuota(),
+			"max_bytes":   services.AttachmentMaxSize(),
+		})
+	}
+}
+
+// UploadAttachmentHandler attaches the multipart "file" field to an entry.
+// Zero-knowledge clients upload the file already encrypted.
+func UploadAttachmentHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A multipart file field named file is required"})
+		}
+		file, err := header.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not read uploaded file"})
+		}
+		defer file.Close()
+
+		attachment, err := services.AddAttachment(c.UserContext(), repo, userID, c.Params("id"), services.AttachmentUpload{
+			FileName:    header.Filename,
+			ContentType: header.Header.Get(fiber.HeaderContentType),
+			Size:        header.Size,
+			Body:        file,
+		})
+		if status := attachmentErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			log.Printf(" Attachment upload failed: %v\n", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to store attachment",
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(attachmentJSON(attachment))
+	}
+}
+
+// DownloadAttachmentHandler streams an attachment back, decrypted unless the
+// vault is zero-knowledge
+func DownloadAttachmentHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		attachment, content, err := services.OpenAttachment(c.UserContext(), repo, userID, c.Params("id"), c.Params("attachmentId"))
+		if status := attachmentErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			log.Printf(" Attachment download failed: %v\n", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load attachment",
+			})
+		}
+
+		c.Attachment(attachment.FileName)
+		if attachment.ClientEncrypted {
+			c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+		} else {
+			c.Set(fiber.HeaderContentType, attachment.ContentType)
+		}
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		c.Set(fiber.HeaderCacheControl, "no-store")
+
+		// The stream is closed once it has been sent
+		return c.SendStream(content, int(attachment.Size))
+	}
+}
+
+// DeleteAttachmentHandler removes an attachment from an entry
+func DeleteAttachmentHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		err := services.DeleteAttachment(c.UserContext(), repo, userID, c.Params("id"), c.Params("attachmentId"))
+		if status := attachmentErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to delete attachment",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Attachment deleted successfully",
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/breach"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// BreachRangeHandler proxies a k-anonymity range lookup so browsers don't call
+// the breach API directly. The client sends the first 5 hex characters of the
+// password's SHA-1 hash and matches its suffix in the returned range itself.
+func BreachRangeHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		if repo.Breaches == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Breach checks are disabled"})
+		}
+
+		body, err := repo.Breaches.Range(c.Context(), c.Params("prefix"))
+		switch {
+		case errors.Is(err, breach.ErrInvalidPrefix):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, breach.ErrUnavailable):
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Breach lookup is unavailable"})
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Breach lookup failed"})
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+		return c.SendString(body)
+	}
+}
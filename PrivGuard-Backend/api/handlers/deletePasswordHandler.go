@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
@@ -25,6 +26,11 @@ func DeletePasswordHandler(repo storage.Repository) fiber.Handler {
 		}
 
 		err := services.DeleteServiceFromVault(repo, userID.(string), passwordID)
+		if errors.Is(err, services.ErrIntegrityMismatch) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		if err != nil {
 			log.Println("❌ Failed to delete password:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
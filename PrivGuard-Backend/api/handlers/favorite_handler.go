@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// FavoriteRequest marks (true) or unmarks (false) an entry as a favorite
+type FavoriteRequest struct {
+	Favorite bool `json:"favorite"`
+}
+
+// SetFavoriteHandler marks or unmarks an entry as a favorite
+func SetFavoriteHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req FavoriteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		err := services.SetServiceFavorite(repo, userID, c.Params("id"), req.Favorite)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Password entry not found"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update favorite",
+			})
+		}
+
+		return c.JSON(fiber.Map{"favorite": req.Favorite})
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// MoveEntriesRequest moves entries into a folder; a null folder_id moves them out of any folder
+type MoveEntriesRequest struct {
+	ServiceIDs []uuid.UUID `json:"service_ids"`
+	FolderID   *uuid.UUID  `json:"folder_id"`
+}
+
+func folderJSON(f *models.Folder) fiber.Map {
+	return fiber.Map{
+		"id":        f.ID,
+		"name":      f.Name,
+		"parent_id": f.ParentID,
+	}
+}
+
+// ListFoldersHandler returns the vault's folders; clients build the tree from parent_id
+func ListFoldersHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		folders, err := services.ListFolders(repo, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load folders",
+			})
+		}
+
+		out := make([]fiber.Map, 0, len(folders))
+		for i := range folders {
+			out = append(out, folderJSON(&folders[i]))
+		}
+		return c.JSON(fiber.Map{"folders": out})
+	}
+}
+
+// CreateFolderHandler adds a folder
+func CreateFolderHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req services.FolderInput
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		folder, err := services.CreateFolder(repo, userID, req)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create folder",
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(folderJSON(folder))
+	}
+}
+
+// UpdateFolderHandler renames a folder or moves it under another parent
+func UpdateFolderHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req services.FolderInput
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		folder, err := services.UpdateFolder(repo, userID, c.Params("id"), req)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update folder",
+			})
+		}
+
+		return c.JSON(folderJSON(folder))
+	}
+}
+
+// DeleteFolderHandler deletes a folder; its entries and subfolders move to its parent
+func DeleteFolderHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		err := services.DeleteFolder(repo, userID, c.Params("id"))
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to delete folder",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Folder deleted successfully",
+		})
+	}
+}
+
+// MoveEntriesHandler moves several entries into a folder at once
+func MoveEntriesHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req MoveEntriesRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		moved, err := services.MoveServicesToFolder(repo, userID, req.ServiceIDs, req.FolderID)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to move entries",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Entries moved successfully",
+			"moved":   moved,
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/passgen"
+)
+
+// GenerateRequest selects a password ("password", the default) or a diceware
+// passphrase ("passphrase") and its options. Omitted character classes are
+// enabled; omitted lengths use the defaults below.
+type GenerateRequest struct {
+	Type             string  `json:"type"`
+	Length           int     `json:"length"`
+	Lowercase        *bool   `json:"lowercase"`
+	Uppercase        *bool   `json:"uppercase"`
+	Digits           *bool   `json:"digits"`
+	Symbols          *bool   `json:"symbols"`
+	ExcludeAmbiguous bool    `json:"exclude_ambiguous"`
+	MinLowercase     int     `json:"min_lowercase"`
+	MinUppercase     int     `json:"min_uppercase"`
+	MinDigits        int     `json:"min_digits"`
+	MinSymbols       int     `json:"min_symbols"`
+	Words            int     `json:"words"`
+	Separator        *string `json:"separator"`
+	Capitalize       string  `json:"capitalize"`
+	IncludeNumber    bool    `json:"include_number"`
+}
+
+const (
+	defaultGeneratedLength = 20
+	defaultPassphraseWords = 6
+)
+
+func enabled(option *bool) bool {
+	return option == nil || *option
+}
+
+// GenerateHandler generates a password or passphrase with crypto/rand and
+// reports its entropy in bits. Nothing is stored.
+func GenerateHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req GenerateRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+			}
+		}
+
+		var generated *passgen.Generated
+		var err error
+		switch req.Type {
+		case "", "password":
+			req.Type = "password"
+			if req.Length == 0 {
+				req.Length = defaultGeneratedLength
+			}
+			generated, err = passgen.GeneratePassword(passgen.PasswordOptions{
+				Length:           req.Length,
+				Lowercase:        enabled(req.Lowercase),
+				Uppercase:        enabled(req.Uppercase),
+				Digits:           enabled(req.Digits),
+				Symbols:          enabled(req.Symbols),
+				ExcludeAmbiguous: req.ExcludeAmbiguous,
+				MinLowercase:     req.MinLowercase,
+				MinUppercase:     req.MinUppercase,
+				MinDigits:        req.MinDigits,
+				MinSymbols:       req.MinSymbols,
+			})
+		case "passphrase":
+			var list passgen.Wordlist
+			if list, err = passgen.DefaultWordlist(); err != nil {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "Passphrase generation is not available",
+				})
+			}
+			if req.Words == 0 {
+				req.Words = defaultPassphraseWords
+			}
+			separator := "-"
+			if req.Separator != nil {
+				separator = *req.Separator
+			}
+			generated, err = passgen.GeneratePassphrase(list, passgen.PassphraseOptions{
+				Words:         req.Words,
+				Separator:     separator,
+				Capitalize:    req.Capitalize,
+				IncludeNumber: req.IncludeNumber,
+			})
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be password or passphrase"})
+		}
+
+		if errors.Is(err, passgen.ErrInvalidOptions) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate",
+			})
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(fiber.Map{
+			"type":         req.Type,
+			"value":        generated.Value,
+			"entropy_bits": generated.EntropyBits,
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+func keyRotationJSON(job *models.KeyRotationJob) fiber.Map {
+	return fiber.Map{
+		"id":            job.ID,
+		"target_key_id": job.TargetKeyID,
+		"status":        job.Status,
+		"total":         job.Total,
+		"processed":     job.Processed,
+		"failed":        job.Failed,
+		"error":         job.Error,
+		"started_at":    job.StartedAt,
+		"updated_at":    job.UpdatedAt,
+		"finished_at":   job.FinishedAt,
+	}
+}
+
+// StartKeyRotationHandler starts (or resumes) re-wrapping vault keys to the active master key
+func StartKeyRotationHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := services.StartKeyRotation(repo)
+		if errors.Is(err, services.ErrKeyRotationInProgress) {
+			body := fiber.Map{"error": err.Error()}
+			// The running job may not be saved yet when the lock was just taken
+			if job != nil {
+				body["job"] = keyRotationJSON(job)
+			}
+			return c.Status(fiber.StatusConflict).JSON(body)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(keyRotationJSON(job))
+	}
+}
+
+// GetKeyRotationHandler reports progress of the most recent rotation job
+func GetKeyRotationHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := services.LatestKeyRotation(repo)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No key rotation has been run",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load key rotation status",
+			})
+		}
+
+		return c.JSON(keyRotationJSON(job))
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// otpErrorStatus maps entry one-time password errors to a status code, or 0 if unknown
+func otpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidOTP):
+		return fiber.StatusBadRequest
+	case errors.Is(err, services.ErrNoOTP), errors.Is(err, gorm.ErrRecordNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, services.ErrOTPCounterBased):
+		return fiber.StatusConflict
+	case errors.Is(err, crypto.ErrSealed):
+		return fiber.StatusServiceUnavailable
+	}
+	return vaultErrorStatus(err)
+}
+
+// SetServiceOTPHandler stores a TOTP/HOTP seed on an entry from an otpauth://
+// URI or an otpauth-migration:// QR payload
+func SetServiceOTPHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req services.OTPInput
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		summary, err := services.SetServiceOTP(repo, userID, c.Params("id"), req)
+		if status := otpErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to save one-time password",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "One-time password saved successfully",
+			"otp":     summary,
+		})
+	}
+}
+
+// RemoveServiceOTPHandler deletes the TOTP/HOTP seed of an entry
+func RemoveServiceOTPHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		err := services.RemoveServiceOTP(repo, userID, c.Params("id"))
+		if status := otpErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to remove one-time password",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "One-time password removed successfully",
+		})
+	}
+}
+
+// ServiceOTPCodeHandler returns the entry's current TOTP code and how long it stays valid
+func ServiceOTPCodeHandler(repo storage.Repository) fiber.Handler {
+	return otpCodeHandler(repo, services.ServiceOTPCode)
+}
+
+// NextServiceOTPCodeHandler issues the entry's next HOTP code, advancing its counter
+// (or returns the current TOTP code)
+func NextServiceOTPCodeHandler(repo storage.Repository) fiber.Handler {
+	return otpCodeHandler(repo, services.NextServiceOTPCode)
+}
+
+func otpCodeHandler(repo storage.Repository, generate func(storage.Repository, string, string, time.Time) (*services.OTPCode, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		code, err := generate(repo, userID, c.Params("id"), time.Now())
+		if status := otpErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate code",
+			})
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(code)
+	}
+}
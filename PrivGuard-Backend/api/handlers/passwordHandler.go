@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"log"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
-	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
 )
 
 // GetPasswordDetailHandler returns decrypted password for a single service entry
 func GetPasswordDetailHandler(repo storage.Repository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+		}
+
 		// Step 1: Extract entry ID from URL
 		entryIDStr := c.Params("id")
 		entryID, err := uuid.Parse(entryIDStr)
@@ -18,32 +26,126 @@ func GetPasswordDetailHandler(repo storage.Repository) fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Invalid entry ID")
 		}
 
-		// Step 2: Fetch service entry from DB
+		// Step 2: Fetch the user's vault and the service entry inside it
+		var vault models.Vault
+		if err := repo.DB.Where("user_id = ?", userID).First(&vault).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Password entry not found")
+		}
+
 		var service models.Service
-		err = repo.DB.First(&service, "id = ?", entryID).Error
+		err = repo.DB.Preload("URIs").Preload("Fields").Preload("Tags").
+			First(&service, "id = ? AND vault_id = ?", entryID, vault.ID).Error
 		if err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Password entry not found")
 		}
 
-		// Step 3: Load AES key and decrypt
-		key, err := crypto.LoadAESKey()
+		// Zero-knowledge entries are returned as the client's own envelopes
+		if service.ClientEncrypted {
+			recordUse(repo, &service)
+			password, _ := services.DecodeClientEnvelope(service.EncryptedPassword)
+			notes, _ := services.DecodeClientEnvelope(service.EncryptedNotes)
+			data, _ := services.DecodeClientEnvelope(service.EncryptedData)
+			otpSeed, _ := services.DecodeClientEnvelope(service.EncryptedOTP)
+			details := services.ClientEntryDetails(&service)
+			return c.JSON(fiber.Map{
+				"id":                 service.ID,
+				"type":               service.ItemType,
+				"service":            service.ServiceName,
+				"domain":             service.ServiceDomain,
+				"logo":               service.LogoURL,
+				"zero_knowledge":     true,
+				"encrypted_password": password,
+				"strength":           service.StrengthScore,
+				"encrypted_notes":    notes,
+				"encrypted_data":     data,
+				"encrypted_otp":      otpSeed,
+				"encrypted_username": details.EncryptedUsername,
+				"uris":               details.URIs,
+				"fields":             details.Fields,
+				"folder_id":          service.FolderID,
+				"tags":               tagSummaries(service.Tags),
+				"favorite":           service.Favorite,
+				"last_used_at":       service.LastUsedAt,
+				"reveal_count":       service.RevealCount,
+				"kdf":                services.VaultKDFParams(&vault),
+			})
+		}
+
+		// Step 3: Load the vault key and decrypt
+		key, err := services.VaultDataKey(repo, &vault)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Encryption key error")
 		}
 
-		decrypted, err := crypto.DecryptAES(service.EncryptedPassword, service.IV, key)
+		decrypted, err := services.DecryptServicePassword(key, &service)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Decryption failed")
+		}
+
+		notes, err := services.DecryptServiceNotes(key, &service)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Decryption failed")
+		}
+
+		details, err := services.DecryptEntryDetails(key, &service)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Decryption failed")
 		}
 
-		// Step 4: Return decrypted data
+		data, err := services.DecryptItemData(key, &service)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Decryption failed")
+		}
+
+		// The seed itself stays on the server; codes come from the OTP endpoint
+		var otpSummary *services.OTPSummary
+		otpConfig, err := services.DecryptOTPConfig(key, &service)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Decryption failed")
+		}
+		if otpConfig != nil {
+			otpSummary = otpConfig.Summary()
+		}
+
+		feedback, err := services.DecryptStrengthFeedback(key, &service)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Decryption failed")
+		}
+
+		// Step 4: Record the reveal and return decrypted data
+		recordUse(repo, &service)
 		return c.JSON(fiber.Map{
-			"id":       service.ID,
-			"service":  service.ServiceName,
-			"domain":   service.ServiceDomain,
-			"logo":     service.LogoURL,
-			"notes":    service.Notes,
-			"password": decrypted,
+			"id":           service.ID,
+			"type":         service.ItemType,
+			"service":      service.ServiceName,
+			"domain":       service.ServiceDomain,
+			"logo":         service.LogoURL,
+			"username":     details.Username,
+			"uris":         details.URIs,
+			"fields":       details.Fields,
+			"folder_id":    service.FolderID,
+			"tags":         tagSummaries(service.Tags),
+			"favorite":     service.Favorite,
+			"last_used_at": service.LastUsedAt,
+			"reveal_count": service.RevealCount,
+			"notes":        notes,
+			"password":     decrypted,
+			"strength":     service.StrengthScore,
+			"feedback":     feedback,
+			"data":         data,
+			"otp":          otpSummary,
 		})
 	}
 }
+
+// recordUse bumps an entry's usage stats; the response shows the updated values.
+// A failure is only logged, it shouldn't block access to the entry.
+func recordUse(repo storage.Repository, service *models.Service) {
+	if err := services.RecordServiceUse(repo, service.ID); err != nil {
+		log.Printf(" %v\n", err)
+		return
+	}
+	now := time.Now()
+	service.LastUsedAt = &now
+	service.RevealCount++
+}
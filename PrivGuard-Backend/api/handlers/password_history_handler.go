@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// historyErrorStatus maps password history errors to a status code, or 0 if unknown
+func historyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrHistoryNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, crypto.ErrSealed):
+		return fiber.StatusServiceUnavailable
+	}
+	return vaultErrorStatus(err)
+}
+
+// ListPasswordHistoryHandler lists the archived passwords of an entry without revealing them
+func ListPasswordHistoryHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		history, err := services.ListPasswordHistory(repo, userID, c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load password history",
+			})
+		}
+
+		versions := make([]fiber.Map, 0, len(history))
+		for _, h := range history {
+			versions = append(versions, fiber.Map{
+				"id":          h.ID,
+				"archived_at": h.CreatedAt,
+				"strength":    h.StrengthScore,
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"history": versions,
+			"depth":   services.PasswordHistoryDepth(),
+		})
+	}
+}
+
+// RevealPasswordHistoryHandler returns one archived password of an entry
+func RevealPasswordHistoryHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		version, err := services.RevealPasswordHistory(repo, userID, c.Params("id"), c.Params("historyId"))
+		if status := historyErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to reveal password",
+			})
+		}
+
+		return c.JSON(version)
+	}
+}
+
+// RestorePasswordHistoryHandler makes an archived password the entry's current one
+func RestorePasswordHistoryHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		err := services.RestorePasswordHistory(repo, userID, c.Params("id"), c.Params("historyId"))
+		if status := historyErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to restore password",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Password restored successfully",
+		})
+	}
+}
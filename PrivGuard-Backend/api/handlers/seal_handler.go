@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+)
+
+// GetSealStatusHandler reports whether the server is sealed and the unseal progress
+func GetSealStatusHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(crypto.CurrentSealStatus())
+	}
+}
+
+// UnsealHandler accepts one operator's Shamir share
+func UnsealHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Share string `json:"share"`
+		}
+		if err := c.BodyParser(&req); err != nil || req.Share == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Share is required"})
+		}
+
+		status, err := crypto.SubmitUnsealShare(req.Share)
+		if errors.Is(err, crypto.ErrSealNotConfigured) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":  err.Error(),
+				"status": status,
+			})
+		}
+
+		return c.JSON(status)
+	}
+}
+
+// SealHandler wipes the master keys from memory on demand
+func SealHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := crypto.Seal(); err != nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(crypto.CurrentSealStatus())
+	}
+}
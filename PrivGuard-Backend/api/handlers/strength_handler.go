@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// RecomputeStrengthHandler re-scores passwords last scored by an older estimator
+// version or by the client. It runs synchronously and reports how many entries changed.
+func RecomputeStrengthHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rescored, err := services.RecomputeStrengthScores(repo)
+		if errors.Is(err, crypto.ErrSealed) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":    err.Error(),
+				"rescored": rescored,
+			})
+		}
+		return c.JSON(fiber.Map{"rescored": rescored})
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// TagRequest is the body for creating or renaming a tag
+type TagRequest struct {
+	Name string `json:"name"`
+}
+
+// SetTagsRequest replaces the tags of an entry
+type SetTagsRequest struct {
+	TagIDs []uuid.UUID `json:"tag_ids"`
+}
+
+func tagSummaries(tags []models.Tag) []fiber.Map {
+	out := make([]fiber.Map, 0, len(tags))
+	for _, tag := range tags {
+		out = append(out, fiber.Map{"id": tag.ID, "name": tag.Name})
+	}
+	return out
+}
+
+// ListTagsHandler returns the vault's tags with how many entries carry each
+func ListTagsHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		tags, counts, err := services.ListTags(repo, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load tags",
+			})
+		}
+
+		out := tagSummaries(tags)
+		for i, tag := range tags {
+			out[i]["entries"] = counts[tag.ID]
+		}
+		return c.JSON(fiber.Map{"tags": out})
+	}
+}
+
+// CreateTagHandler adds a tag
+func CreateTagHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req TagRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		tag, err := services.CreateTag(repo, userID, req.Name)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create tag",
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": tag.ID, "name": tag.Name})
+	}
+}
+
+// RenameTagHandler changes the name of a tag
+func RenameTagHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req TagRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		tag, err := services.RenameTag(repo, userID, c.Params("id"), req.Name)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to rename tag",
+			})
+		}
+
+		return c.JSON(fiber.Map{"id": tag.ID, "name": tag.Name})
+	}
+}
+
+// DeleteTagHandler deletes a tag and removes it from all entries
+func DeleteTagHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		err := services.DeleteTag(repo, userID, c.Params("id"))
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to delete tag",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Tag deleted successfully",
+		})
+	}
+}
+
+// SetServiceTagsHandler replaces the tags of an entry
+func SetServiceTagsHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req SetTagsRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		tags, err := services.SetServiceTags(repo, userID, c.Params("id"), req.TagIDs)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update tags",
+			})
+		}
+
+		return c.JSON(fiber.Map{"tags": tagSummaries(tags)})
+	}
+}
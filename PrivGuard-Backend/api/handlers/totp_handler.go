@@ -2,10 +2,9 @@ package handlers
 
 import (
 	"errors"
-	"fmt"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
@@ -24,36 +23,21 @@ func GetTOTPSetupHandler(repo storage.Repository) fiber.Handler {
 			return c.Status(500).JSON(fiber.Map{"error": "DB error"})
 		}
 
-		// If not found, generate a new secret and save it
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			secret, uri, err := services.GenerateTOTP(userID)
+		// If not found or not yet confirmed, issue a fresh secret. The stored secret is
+		// encrypted and never rebuilt into a QR code, so an unconfirmed one is replaced.
+		if errors.Is(err, gorm.ErrRecordNotFound) || !ts.IsConfirmed {
+			uri, err := services.IssueTOTPSecret(repo, userID)
+			if errors.Is(err, crypto.ErrSealed) {
+				return c.Status(503).JSON(fiber.Map{"error": "Server is sealed"})
+			}
 			if err != nil {
 				return c.Status(500).JSON(fiber.Map{"error": "TOTP generation failed"})
 			}
 
-			// Create new TOTPSecret record
-			ts = models.TOTPSecret{
-				UserID:      userID,
-				Secret:      secret,
-				IsConfirmed: false,
-				Enabled:     false,
-				CreatedAt:   time.Now(),
-			}
-
-			if err := repo.DB.Create(&ts).Error; err != nil {
-				return c.Status(500).JSON(fiber.Map{"error": "DB save failed"})
-			}
-
 			// Return the provisioning URI (QR code)
 			return c.JSON(fiber.Map{"provisioning_uri": uri})
 		}
 
-		// If exists but not yet confirmed, re-issue the same QR code
-		if !ts.IsConfirmed {
-			uri := fmt.Sprintf("otpauth://totp/PrivGuard:%s?secret=%s&issuer=PrivGuard", userID, ts.Secret)
-			return c.JSON(fiber.Map{"provisioning_uri": uri})
-		}
-
 		// Already confirmed — notify client they don’t need QR code
 		return c.JSON(fiber.Map{"message": "TOTP already configured"})
 	}
@@ -80,7 +64,14 @@ func VerifyTOTPHandler(repo storage.Repository) fiber.Handler {
 		}
 
 		// Verify the provided code
-		if !services.VerifyTOTP(ts.Secret, body.Code) {
+		valid, err := services.VerifyTOTP(&ts, body.Code)
+		if errors.Is(err, crypto.ErrSealed) {
+			return c.Status(503).JSON(fiber.Map{"error": "Server is sealed"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "TOTP verification failed"})
+		}
+		if !valid {
 			return c.Status(400).JSON(fiber.Map{"error": "invalid code"})
 		}
 
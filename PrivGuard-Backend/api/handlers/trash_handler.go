@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// TrashHandler lists the user's deleted entries and when they will be purged
+func TrashHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		trashed, err := services.ListTrash(repo, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load trash",
+			})
+		}
+
+		retention := services.TrashRetention()
+		entries := make([]fiber.Map, 0, len(trashed))
+		for _, s := range trashed {
+			entry := fiber.Map{
+				"id":         s.ID,
+				"service":    s.ServiceName,
+				"domain":     s.ServiceDomain,
+				"logo":       s.LogoURL,
+				"deleted_at": s.DeletedAt.Time,
+			}
+			if retention > 0 {
+				entry["purge_at"] = s.DeletedAt.Time.Add(retention)
+			}
+			entries = append(entries, entry)
+		}
+
+		return c.JSON(fiber.Map{"trash": entries})
+	}
+}
+
+// RestoreTrashHandler moves a deleted entry back into the vault
+func RestoreTrashHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		err := services.RestoreFromTrash(repo, userID, c.Params("id"))
+		if errors.Is(err, services.ErrNotInTrash) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, services.ErrIntegrityMismatch) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, crypto.ErrSealed) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is sealed"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to restore entry",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Entry restored successfully",
+		})
+	}
+}
+
+// EmptyTrashHandler permanently deletes everything in the user's trash
+func EmptyTrashHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		deleted, err := services.EmptyTrash(repo, userID)
+		if errors.Is(err, services.ErrIntegrityMismatch) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, crypto.ErrSealed) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is sealed"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to empty trash",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Trash emptied",
+			"deleted": deleted,
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
@@ -25,31 +30,112 @@ func VaultHandler(repo storage.Repository) fiber.Handler {
 			})
 		}
 
-		// Step 2: Load full vault with services
+		// Step 2: Parse the filters, sort and page
+		filter, page, err := parseEntryQuery(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		// Step 3: Load the vault and its matching services
 		var vault models.Vault
-		err = repo.DB.Preload("Services").Where("id = ?", vaultID).First(&vault).Error
+		if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load vault",
+			})
+		}
+		list, err := services.ListVaultEntries(repo, vaultID, filter, page)
+		if errors.Is(err, services.ErrInvalidQuery) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to load vault services",
 			})
 		}
 
-		// Step 3: Return service metadata
-		services := make([]fiber.Map, 0, len(vault.Services))
-		for _, s := range vault.Services {
-			services = append(services, fiber.Map{
-				"id":        s.ID,
-				"service":   s.ServiceName,
-				"domain":    s.ServiceDomain,
-				"logo":      s.LogoURL,
-				"notes":     s.Notes,
-				"encrypted": true,
-			})
+		// Step 4: Return service metadata
+		services := make([]fiber.Map, 0, len(list.Entries))
+		for _, s := range list.Entries {
+			tagIDs := make([]uuid.UUID, 0, len(s.Tags))
+			for _, tag := range s.Tags {
+				tagIDs = append(tagIDs, tag.ID)
+			}
+			// Notes are only decrypted by the detail endpoint
+			entry := fiber.Map{
+				"id":           s.ID,
+				"type":         s.ItemType,
+				"service":      s.ServiceName,
+				"domain":       s.ServiceDomain,
+				"logo":         s.LogoURL,
+				"has_notes":    s.EncryptedNotes != "" || s.Notes != "",
+				"has_otp":      s.EncryptedOTP != "",
+				"encrypted":    true,
+				"folder_id":    s.FolderID,
+				"tags":         tagIDs,
+				"favorite":     s.Favorite,
+				"last_used_at": s.LastUsedAt,
+			}
+			if s.ClientEncrypted {
+				entry["client_encrypted"] = true
+			}
+			services = append(services, entry)
 		}
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Vault ready",
-			"vault":   services,
+			"message":        "Vault ready",
+			"vault":          services,
+			"total":          list.Total,
+			"next_cursor":    list.NextCursor,
+			"zero_knowledge": vault.ZeroKnowledge,
+			// Clients pin these and pass them to /vault/integrity to detect rollback
+			"integrity": fiber.Map{
+				"counter": vault.IntegrityCounter,
+				"root":    vault.IntegrityRoot,
+			},
 		})
 	}
 }
+
+// parseEntryQuery reads the listing's query parameters: ?folder=<id>|none,
+// ?recursive=true, ?tag=<id>,<id>, ?favorites=true, ?type=<item type>, ?q=<search>,
+// ?sort=name|created|updated|strength|used, ?order=asc|desc, ?favorites_first=true,
+// ?cursor= and ?limit= (without either, every entry is returned)
+func parseEntryQuery(c *fiber.Ctx) (services.EntryFilter, services.EntryPage, error) {
+	filter := services.EntryFilter{
+		Search:    c.Query("q"),
+		Favorites: c.QueryBool("favorites"),
+		Type:      c.Query("type"),
+	}
+	page := services.EntryPage{
+		Sort:           c.Query("sort"),
+		Order:          c.Query("order"),
+		FavoritesFirst: c.QueryBool("favorites_first"),
+		Cursor:         c.Query("cursor"),
+		Limit:          c.QueryInt("limit", 0),
+	}
+
+	switch folder := c.Query("folder"); folder {
+	case "":
+	case "none":
+		filter.Unfiled = true
+	default:
+		id, err := uuid.Parse(folder)
+		if err != nil {
+			return filter, page, errors.New("invalid folder ID")
+		}
+		filter.FolderID = &id
+		filter.Recursive = c.QueryBool("recursive")
+	}
+
+	if tags := c.Query("tag"); tags != "" {
+		for _, raw := range strings.Split(tags, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				return filter, page, errors.New("invalid tag ID")
+			}
+			filter.TagIDs = append(filter.TagIDs, id)
+		}
+	}
+
+	return filter, page, nil
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// VaultIntegrityHandler verifies the user's vault against its integrity root.
+// Clients pass the counter and root they last saw (?counter=&root=) to detect rollback.
+func VaultIntegrityHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		pinnedCounter := c.QueryInt("counter", 0)
+		if pinnedCounter < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid counter"})
+		}
+
+		report, err := services.VerifyUserVaultIntegrity(repo, userID, uint64(pinnedCounter), c.Query("root"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Vault not found"})
+		}
+		if errors.Is(err, crypto.ErrSealed) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is sealed"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify vault"})
+		}
+
+		return c.JSON(report)
+	}
+}
+
+// AdminVaultIntegrityHandler verifies one user's vault (?user_id=) or every vault
+func AdminVaultIntegrityHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if userID := c.Query("user_id"); userID != "" {
+			report, err := services.VerifyUserVaultIntegrity(repo, userID, 0, "")
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Vault not found"})
+			}
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.JSON(fiber.Map{
+				"ok":      report.Valid && report.FlaggedAt == nil,
+				"checked": 1,
+				"reports": []services.IntegrityReport{*report},
+			})
+		}
+
+		checked, failed, err := services.VerifyAllVaultIntegrity(repo)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if failed == nil {
+			failed = []services.IntegrityReport{}
+		}
+
+		return c.JSON(fiber.Map{
+			"ok":      len(failed) == 0,
+			"checked": checked,
+			"reports": failed,
+		})
+	}
+}
+
+// AcceptVaultIntegrityHandler signs a flagged vault's current entries (?user_id=)
+// as its new integrity baseline, once an admin has checked them
+func AcceptVaultIntegrityHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Query("user_id")
+		if userID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user_id is required"})
+		}
+
+		report, err := services.AcceptVaultIntegrity(repo, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Vault not found"})
+		}
+		if errors.Is(err, crypto.ErrSealed) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Server is sealed"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(report)
+	}
+}
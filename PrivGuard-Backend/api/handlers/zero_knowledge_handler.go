@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// vaultErrorStatus maps validation and zero-knowledge errors of vault writes to
+// HTTP statuses. It returns 0 for other errors.
+func vaultErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrZeroKnowledgeEnabled),
+		errors.Is(err, services.ErrZeroKnowledgeDisabled),
+		errors.Is(err, services.ErrIntegrityMismatch):
+		return fiber.StatusConflict
+	case errors.Is(err, services.ErrInvalidEnvelope),
+		errors.Is(err, services.ErrInvalidKDFParams),
+		errors.Is(err, services.ErrMigrationIncomplete),
+		errors.Is(err, services.ErrInvalidEntry),
+		errors.Is(err, services.ErrInvalidFolder),
+		errors.Is(err, services.ErrInvalidTag):
+		return fiber.StatusBadRequest
+	case errors.Is(err, services.ErrFolderNotFound), errors.Is(err, services.ErrTagNotFound):
+		return fiber.StatusNotFound
+	}
+	return 0
+}
+
+// GetZeroKnowledgeHandler returns whether the vault is in zero-knowledge mode and
+// the KDF parameters the client needs to derive its key
+func GetZeroKnowledgeHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		vault, err := services.GetUserVault(repo, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load vault"})
+		}
+
+		if !vault.ZeroKnowledge {
+			return c.JSON(fiber.Map{"enabled": false})
+		}
+
+		verifier, _ := services.DecodeClientEnvelope(vault.KDFVerifier)
+		return c.JSON(fiber.Map{
+			"enabled":  true,
+			"kdf":      services.VaultKDFParams(vault),
+			"verifier": verifier,
+		})
+	}
+}
+
+// EnableZeroKnowledgeHandler migrates the vault into zero-knowledge mode.
+// The client must submit every existing entry re-encrypted under its own key.
+func EnableZeroKnowledgeHandler(repo storage.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		var req struct {
+			KDF      services.KDFParams            `json:"kdf"`
+			Verifier services.ClientEnvelope       `json:"verifier"`
+			Entries  []services.ZeroKnowledgeEntry `json:"entries"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		err := services.EnableZeroKnowledge(repo, userID, req.KDF, req.Verifier, req.Entries)
+		if status := vaultErrorStatus(err); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable zero-knowledge mode"})
+		}
+
+		return c.JSON(fiber.Map{
+			"message":  "Vault moved to zero-knowledge mode",
+			"migrated": len(req.Entries),
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"time"
@@ -14,6 +15,10 @@ import (
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/config"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/db/migrations"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/routes"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/services"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/blobstore"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/breach"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/webauthnutil"
 )
@@ -39,19 +44,69 @@ func main() {
 	db.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`)
 
 
-	// Conditional migration
-	if os.Getenv("RUN_MIGRATIONS") == "true" {
-		migrations.AutoMigrate(db)
+	// Attachment contents go to the configured blob store
+	blobs, err := blobstore.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid blob store config: %v", err)
+	}
+	log.Printf("📦 Attachments stored in %s blob store", blobs.Name())
+
+	// Compromised password checks use the range API or a local index
+	breaches, err := breach.FromEnv(redisClient)
+	if err != nil {
+		log.Fatalf("❌ Invalid breach check config: %v", err)
+	}
+	if breaches != nil {
+		log.Printf("🔎 Breach checks answered by %s", breaches.Source())
 	}
 
-	// Set up Repository with both DB and Redis
+	// Set up Repository with DB, Redis, the blob store and the breach checker
 	vaultRoutes := storage.Repository{
 		DB:          db,
 		RedisClient: redisClient,
+		Blobs:       blobs,
+		Breaches:    breaches,
+	}
+
+	// Reject an unknown cipher before anything is encrypted with it
+	if _, err := crypto.ConfiguredAlgorithm(); err != nil {
+		log.Fatalf("❌ Invalid cipher config: %v", err)
+	}
+
+	// Conditional migration
+	runMigrations := os.Getenv("RUN_MIGRATIONS") == "true"
+	if runMigrations {
+		migrations.AutoMigrate(db)
+	}
+
+	if os.Getenv("KEY_PROVIDER") == "shamir" {
+		// Start sealed: keys are only loaded once operators submit enough unseal shares
+		if err := crypto.InitSealed(os.Getenv("SEALED_KEYRING_FILE")); err != nil {
+			log.Fatalf("❌ Could not load sealed key ring: %v", err)
+		}
+		crypto.OnUnseal(func() { runKeyJobs(vaultRoutes, runMigrations) })
+		status := crypto.CurrentSealStatus()
+		log.Printf("🔒 Started sealed, %d of %d unseal shares required", status.Threshold, status.Shares)
+	} else {
+		// Load the master key ring from the configured provider so a bad key config fails fast
+		keyProvider, err := crypto.KeyProviderFromEnv()
+		if err != nil {
+			log.Fatalf("❌ Invalid key provider config: %v", err)
+		}
+		ring, err := crypto.InitKeyRing(context.Background(), keyProvider)
+		if err != nil {
+			log.Fatalf("❌ Could not load master keys: %v", err)
+		}
+		activeKeyID, _ := ring.Active()
+		log.Printf("🔑 Master key ring loaded from %s provider (active: %s, keys: %v)", keyProvider.Name(), activeKeyID, ring.IDs())
+
+		runKeyJobs(vaultRoutes, runMigrations)
 	}
 
 	// Set up Fiber app
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		BodyLimit: bodyLimit(),
+	})
 	app.Use(logger.New())
 
 	// Global Rate Limiting: 100 requests per minute per IP
@@ -82,3 +137,49 @@ func main() {
 	}
 	log.Fatal(app.Listen(":" + port))
 }
+
+// runKeyJobs runs the work that needs the master keys: ciphertext migrations,
+// picking up an interrupted key rotation (or starting one if asked to) and the
+// trash purge, which updates vault integrity roots
+func runKeyJobs(repo storage.Repository, runMigrations bool) {
+	if runMigrations {
+		if err := services.BindLegacyCiphertexts(repo); err != nil {
+			log.Fatalf("❌ Ciphertext AAD migration failed: %v", err)
+		}
+
+		if err := services.EncryptLegacyNotes(repo); err != nil {
+			log.Fatalf("❌ Notes encryption migration failed: %v", err)
+		}
+
+		if err := services.EncryptLegacyTOTPSecrets(repo); err != nil {
+			log.Fatalf("❌ TOTP secret encryption migration failed: %v", err)
+		}
+
+		if err := services.InitVaultIntegrity(repo); err != nil {
+			log.Fatalf("❌ Vault integrity initialization failed: %v", err)
+		}
+	}
+
+	if os.Getenv("KEY_ROTATION_ON_STARTUP") == "true" {
+		if _, err := services.StartKeyRotation(repo); err != nil {
+			log.Printf("Key rotation not started: %v", err)
+		}
+	} else {
+		services.ResumeKeyRotation(repo)
+	}
+
+	services.StartTrashPurge(repo)
+	services.StartStrengthRecompute(repo)
+	services.StartFingerprintBackfill(repo)
+	services.StartBreachChecks(repo)
+}
+
+// bodyLimit leaves room for the largest attachment plus multipart overhead,
+// and never goes below Fiber's default of 4 MB
+func bodyLimit() int {
+	limit := int(services.AttachmentMaxSize()) + 1<<20
+	if limit < fiber.DefaultBodyLimit {
+		limit = fiber.DefaultBodyLimit
+	}
+	return limit
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/breach"
+)
+
+const breachIndexUsage = `usage: privguardctl breach-index <build|bench> [flags]
+
+  build  turn a Pwned Passwords hash dump into a bloom filter for BREACH_INDEX_FILE
+  bench  measure load time, memory and lookup latency of an index
+`
+
+func breachIndex(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, breachIndexUsage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "build":
+		return buildBreachIndex(args[1:])
+	case "bench":
+		return benchBreachIndex(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, breachIndexUsage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+func buildBreachIndex(args []string) error {
+	fs := flag.NewFlagSet("breach-index build", flag.ExitOnError)
+	in := fs.String("in", "", "hash dump with one SHA1[:COUNT] per line")
+	out := fs.String("out", "breach.bloom", "where to write the bloom filter")
+	rate := fs.Float64("fpr", 0.001, "target false positive rate")
+	items := fs.Uint64("n", 0, "number of hashes in the dump (counted in a first pass if 0)")
+	fs.Parse(args)
+
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	start := time.Now()
+	if *items == 0 {
+		n, err := countLines(*in)
+		if err != nil {
+			return err
+		}
+		*items = n
+		fmt.Printf("Counted %d hashes in %s\n", n, time.Since(start).Round(time.Millisecond))
+	}
+
+	dump, err := os.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	filter, err := breach.BuildBloomFilter(bufio.NewReaderSize(dump, 1<<20), *items, *rate)
+	if err != nil {
+		return err
+	}
+
+	// Write next to the target and rename, so a server never loads half a filter
+	tmp := *out + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+	if _, err := filter.WriteTo(w); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write filter: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write filter: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, *out); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s: %d hashes, %.1f MiB, expected false positive rate %.4f%%, built in %s\n",
+		*out, filter.Items(), float64(filter.SizeBytes())/(1<<20), filter.FalsePositiveRate()*100,
+		time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func countLines(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines uint64
+	buf := make([]byte, 1<<20)
+	for {
+		n, err := f.Read(buf)
+		lines += uint64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func benchBreachIndex(args []string) error {
+	fs := flag.NewFlagSet("breach-index bench", flag.ExitOnError)
+	path := fs.String("index", "", "sorted hash file or bloom filter to measure")
+	lookups := fs.Int("lookups", 100000, "number of random lookups")
+	fs.Parse(args)
+
+	if *path == "" {
+		return fmt.Errorf("-index is required")
+	}
+	if *lookups < 1 {
+		return fmt.Errorf("-lookups must be at least 1")
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	index, err := breach.OpenIndex(*path)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+	loaded := time.Since(start)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	// Random hashes are almost all misses; for a bloom filter the hits are its
+	// measured false positive rate
+	hashes := make([][20]byte, *lookups)
+	for i := range hashes {
+		rand.Read(hashes[i][:])
+	}
+	latencies := make([]time.Duration, len(hashes))
+	hits := 0
+	for i, hash := range hashes {
+		t := time.Now()
+		count, err := index.Lookup(hash)
+		latencies[i] = time.Since(t)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			hits++
+		}
+	}
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration { return latencies[int(float64(len(latencies)-1)*p)] }
+
+	fmt.Printf("Index:   %s (%s)\n", *path, index.Format())
+	fmt.Printf("Load:    %s, heap +%.1f MiB\n", loaded.Round(time.Millisecond), float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/(1<<20))
+	fmt.Printf("Lookups: %d, mean %s, p50 %s, p99 %s, max %s\n",
+		len(latencies), total/time.Duration(len(latencies)), percentile(0.5), percentile(0.99), latencies[len(latencies)-1])
+	fmt.Printf("Hits:    %d (%.4f%%)\n", hits, float64(hits)/float64(len(latencies))*100)
+	return nil
+}
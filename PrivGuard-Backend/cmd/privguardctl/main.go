@@ -0,0 +1,214 @@
+// privguardctl is the operator CLI for PrivGuard: it creates Shamir-sealed key
+// rings and offline breach indexes, and drives the admin API (seal/unseal, vault
+// integrity, password re-scoring) of a running server.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+)
+
+const usage = `usage: privguardctl <command> [flags]
+
+commands:
+  shamir-init   encrypt a key file and split its unseal key into shares
+  unseal        submit one unseal share to a running server
+  seal          wipe the master keys from a running server's memory
+  seal-status   show whether a running server is sealed
+  verify-vault  check vaults for tampering or rollback (-accept re-baselines a flagged vault)
+  rescore       re-score stored passwords with the current strength estimator
+  breach-index  build or benchmark an offline compromised-password index
+`
+
+func main() {
+	// .env is optional for the CLI
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "shamir-init":
+		err = shamirInit(args)
+	case "unseal":
+		err = unseal(args)
+	case "seal":
+		_, err = adminCall(http.MethodPost, "/api/admin/seal", nil)
+	case "seal-status":
+		_, err = adminCall(http.MethodGet, "/api/admin/seal-status", nil)
+	case "verify-vault":
+		err = verifyVault(args)
+	case "rescore":
+		_, err = adminCall(http.MethodPost, "/api/admin/strength-recompute", nil)
+	case "breach-index":
+		err = breachIndex(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func shamirInit(args []string) error {
+	fs := flag.NewFlagSet("shamir-init", flag.ExitOnError)
+	keyFile := fs.String("keyfile", "", "existing JSON key file to seal (a fresh v1 key is generated if empty)")
+	shares := fs.Int("shares", 5, "number of unseal shares to create")
+	threshold := fs.Int("threshold", 3, "number of shares needed to unseal")
+	out := fs.String("out", "sealed-keyring.json", "where to write the sealed key ring")
+	fs.Parse(args)
+
+	var keyFileJSON []byte
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read key file: %w", err)
+		}
+		keyFileJSON = data
+	} else {
+		key, err := crypto.GenerateDataKey()
+		if err != nil {
+			return err
+		}
+		keyFileJSON, _ = json.Marshal(map[string]interface{}{
+			"active_key_id": crypto.LegacyKeyID,
+			"keys":          map[string]string{crypto.LegacyKeyID: base64.StdEncoding.EncodeToString(key)},
+		})
+	}
+
+	sealed, parts, err := crypto.CreateSealedKeyRing(keyFileJSON, *shares, *threshold)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write sealed key ring: %w", err)
+	}
+
+	fmt.Printf("Sealed key ring written to %s (%d of %d shares unseal it)\n\n", *out, *threshold, *shares)
+	for i, part := range parts {
+		fmt.Printf("Unseal share %d: %s\n", i+1, base64.StdEncoding.EncodeToString(part))
+	}
+	fmt.Println("\nHand each share to a different operator. They are not stored anywhere else.")
+	return nil
+}
+
+func unseal(args []string) error {
+	fs := flag.NewFlagSet("unseal", flag.ExitOnError)
+	share := fs.String("share", "", "base64 unseal share (read from stdin if empty)")
+	fs.Parse(args)
+
+	if *share == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		*share = strings.TrimSpace(string(data))
+	}
+
+	_, err := adminCall(http.MethodPost, "/api/admin/unseal", map[string]string{"share": *share})
+	return err
+}
+
+func verifyVault(args []string) error {
+	fs := flag.NewFlagSet("verify-vault", flag.ExitOnError)
+	userID := fs.String("user", "", "only verify this user's vault (all vaults if empty)")
+	accept := fs.Bool("accept", false, "accept the user's current entries as the vault's new integrity baseline")
+	fs.Parse(args)
+
+	if *accept {
+		if *userID == "" {
+			return fmt.Errorf("-accept needs -user")
+		}
+		_, err := adminCall(http.MethodPost, "/api/admin/vault-integrity/accept?user_id="+url.QueryEscape(*userID), nil)
+		return err
+	}
+
+	path := "/api/admin/vault-integrity"
+	if *userID != "" {
+		path += "?user_id=" + url.QueryEscape(*userID)
+	}
+
+	body, err := adminCall(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		OK bool `json:"ok"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("unexpected response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("vault integrity check failed")
+	}
+	return nil
+}
+
+// adminCall sends a request to the admin API at PRIVGUARD_ADDR, prints the response and returns it
+func adminCall(method, path string, body interface{}) ([]byte, error) {
+	addr := os.Getenv("PRIVGUARD_ADDR")
+	if addr == "" {
+		addr = "http://localhost:8080"
+	}
+	token := os.Getenv("ADMIN_API_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("ADMIN_API_TOKEN must be set")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(addr, "/")+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Admin-Token", token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	fmt.Println(strings.TrimSpace(string(respBody)))
+	if resp.StatusCode >= 300 {
+		return respBody, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return respBody, nil
+}
@@ -13,13 +13,42 @@ func AutoMigrate(db *gorm.DB) {
 		&models.User{},
 		&models.Vault{},
 		&models.Service{},
+		&models.ServiceURI{},
+		&models.ServiceField{},
+		&models.PasswordHistory{},
+		&models.Folder{},
+		&models.Tag{},
+		&models.Attachment{},
 		&models.WebAuthnCredential{}, 
 		&models.TOTPSecret{},
+		&models.KeyRotationJob{},
 	)
 
 	if err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
 
+	// Indexes behind the vault listing that struct tags can't express. Search
+	// falls back to a scan if pg_trgm isn't available, so failures only warn.
+	for _, stmt := range listingIndexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Printf("⚠️ Could not create index: %v", err)
+		}
+	}
+
 	log.Println("✅ Database migrated successfully")
 }
+
+// listingIndexes back keyset pagination for each sort key of the vault listing
+// (trashed entries excluded) and ILIKE search over service name and domain
+var listingIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_services_vault_name ON services (vault_id, service_name, id) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_services_vault_created ON services (vault_id, created_at, id) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_services_vault_updated ON services (vault_id, updated_at, id) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_services_vault_strength ON services (vault_id, strength_score, id) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_services_vault_used ON services (vault_id, COALESCE(last_used_at, 'epoch'::timestamptz), id) WHERE deleted_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_services_vault_favorite_name ON services (vault_id, favorite DESC, service_name, id) WHERE deleted_at IS NULL`,
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE INDEX IF NOT EXISTS idx_services_name_trgm ON services USING gin (service_name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_services_domain_trgm ON services USING gin (service_domain gin_trgm_ops)`,
+}
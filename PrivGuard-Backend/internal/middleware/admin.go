@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminMiddleware guards operator endpoints with the ADMIN_API_TOKEN shared secret.
+// Admin routes are disabled entirely when the token is not configured.
+func AdminMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		expected := os.Getenv("ADMIN_API_TOKEN")
+		if expected == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Admin API disabled",
+			})
+		}
+
+		token := c.Get("X-Admin-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid admin token",
+			})
+		}
+
+		return c.Next()
+	}
+}
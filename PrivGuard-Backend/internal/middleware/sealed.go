@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+)
+
+// RequireUnsealed rejects requests while the master keys are sealed
+func RequireUnsealed() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if crypto.IsSealed() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":  "Vault is sealed",
+				"sealed": true,
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file attached to a vault entry. Its content lives encrypted
+// in the blob store under BlobKey; the row keeps the metadata and file key.
+type Attachment struct {
+	ID              uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ServiceID       uuid.UUID `gorm:"not null;index"`
+	VaultID         uuid.UUID `gorm:"not null;index"`
+	FileName        string    `gorm:"not null"`
+	ContentType     string    `gorm:"not null"`
+	Size            int64     `gorm:"not null;comment:Size of the uploaded file in bytes"`
+	BlobKey         string    `gorm:"not null;uniqueIndex"`
+	WrappedKey      string    // file key sealed with the vault data key, empty for client-encrypted files
+	ClientEncrypted bool      `gorm:"not null;default:false;comment:Content is an opaque client-side ciphertext"`
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+}
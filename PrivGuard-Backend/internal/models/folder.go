@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Folder groups vault entries. Folders nest through ParentID; top-level folders have none.
+type Folder struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	VaultID   uuid.UUID  `gorm:"not null;index"`
+	ParentID  *uuid.UUID `gorm:"type:uuid;index"`
+	Name      string     `gorm:"not null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
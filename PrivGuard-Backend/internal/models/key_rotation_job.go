@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KeyRotationRunning   = "running"
+	KeyRotationCompleted = "completed"
+	KeyRotationFailed    = "failed"
+	KeyRotationCancelled = "cancelled" // superseded by a job for a newer key
+)
+
+// KeyRotationJob tracks re-wrapping of vault keys to a new master key.
+// LastVaultID is the resume cursor; vaults are processed in ID order.
+type KeyRotationJob struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	TargetKeyID string    `gorm:"not null;index"`
+	Status      string    `gorm:"not null;index"`
+	Total       int64
+	Processed   int64
+	Failed      int64
+	LastVaultID *uuid.UUID `gorm:"type:uuid"`
+	Error       string
+	StartedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+	FinishedAt  *time.Time
+}
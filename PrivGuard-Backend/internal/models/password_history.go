@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordHistory is a previous password of a vault entry, kept as the
+// ciphertext it was stored with (bound to the entry's row like the live one)
+type PasswordHistory struct {
+	ID                uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ServiceID         uuid.UUID `gorm:"not null;index"`
+	VaultID           uuid.UUID `gorm:"not null;index"`
+	EncryptedPassword string    `gorm:"not null"`
+	IV                string    // legacy IV column, empty for envelopes
+	AADVersion        int8      `gorm:"not null;default:0"`
+	ClientEncrypted   bool      `gorm:"not null;default:false"`
+	StrengthScore     int8
+	CreatedAt         time.Time `gorm:"autoCreateTime;index;comment:When the password was replaced"`
+}
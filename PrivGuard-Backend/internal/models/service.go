@@ -4,21 +4,47 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Service struct {
-	ID                uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	VaultID           uuid.UUID `gorm:"not null;index"`
-	ServiceName       string    `gorm:"not null;index"`
-	ServiceDomain     string
-	LogoURL           string
-	EncryptedPassword string `gorm:"not null"`
-	IV                string `gorm:"not null"`
-	Notes             string
-	StrengthScore 	  int8	 `gorm:"not null;comment:Password strength score (0-100)"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID                  uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	VaultID             uuid.UUID  `gorm:"not null;index"`
+	FolderID            *uuid.UUID `gorm:"type:uuid;index"` // nil for entries outside any folder
+	ItemType            string     `gorm:"not null;default:'login';index;comment:login, note, card, identity, api_key or wifi"`
+	ServiceName         string     `gorm:"not null;index"`
+	ServiceDomain       string
+	LogoURL             string
+	EncryptedUsername   string // ciphertext envelope, or client envelope in zero-knowledge vaults
+	EncryptedPassword   string `gorm:"not null"` // ciphertext envelope (or legacy base64 ciphertext), empty for non-login items
+	EncryptedData       string // fields of non-login items: envelope of a JSON object, or client envelope
+	EncryptedOTP        string // TOTP/HOTP seed: envelope of a JSON object, or client envelope
+	IV                  string `gorm:"not null"` // legacy IV column, empty for envelopes
+	AADVersion          int8   `gorm:"not null;default:0;comment:0 = legacy ciphertext without associated data"`
+	ClientEncrypted     bool   `gorm:"not null;default:false;comment:Password and notes are opaque client-side envelopes"`
+	Notes               string // legacy plaintext notes, emptied by the notes encryption migration
+	EncryptedNotes      string
+	NotesIV             string         // legacy IV column, empty for envelopes
+	StrengthScore       int8           `gorm:"not null;comment:Password strength score (0-100)"`
+	StrengthFeedback    string         // envelope of the estimator's warning and suggestions, empty when there are none
+	StrengthVersion     int            `gorm:"not null;default:0;comment:Estimator version that computed the score, 0 = reported by the client"`
+	BreachCount         int            `gorm:"not null;default:0;comment:Times the password appears in known breaches"`
+	BreachCheckedAt     *time.Time     `gorm:"index;comment:Last breach check, nil until checked or after the password changed"`
+	PasswordFingerprint string         `gorm:"not null;default:'';index;comment:Keyed HMAC of the password for reuse detection, empty in zero-knowledge vaults"`
+	Favorite            bool           `gorm:"not null;default:false"`
+	LastUsedAt          *time.Time     `gorm:"comment:Last time the entry was revealed"`
+	RevealCount         int64          `gorm:"not null;default:0"`
+	CreatedAt           time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt           time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt           gorm.DeletedAt `gorm:"index"` // set while the entry is in the trash
 
 	// Relations
-	Vault Vault `gorm:"foreignKey:VaultID"`
+	Vault   Vault             `gorm:"foreignKey:VaultID"`
+	URIs    []ServiceURI      `gorm:"foreignKey:ServiceID;constraint:OnDelete:CASCADE"`
+	Fields  []ServiceField    `gorm:"foreignKey:ServiceID;constraint:OnDelete:CASCADE"`
+	History []PasswordHistory `gorm:"foreignKey:ServiceID;constraint:OnDelete:CASCADE"`
+	Folder  *Folder           `gorm:"foreignKey:FolderID;constraint:OnDelete:SET NULL"`
+	Tags    []Tag             `gorm:"many2many:service_tags;constraint:OnDelete:CASCADE"`
+
+	Attachments []Attachment `gorm:"foreignKey:ServiceID;constraint:OnDelete:CASCADE"`
 }
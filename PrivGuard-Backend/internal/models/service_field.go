@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ServiceField is a user-defined field on a vault entry. Text and boolean values
+// are stored as-is; hidden values are encrypted like the password.
+type ServiceField struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ServiceID      uuid.UUID `gorm:"not null;index"`
+	Name           string    `gorm:"not null"`
+	Type           string    `gorm:"not null;comment:text, hidden or boolean"`
+	Value          string    // text and boolean fields
+	EncryptedValue string    // hidden fields: server envelope, or client envelope in zero-knowledge vaults
+	Position       int       `gorm:"not null;default:0"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ServiceURI is one of the URIs a vault entry can be filled on
+type ServiceURI struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ServiceID uuid.UUID `gorm:"not null;index"`
+	URI       string    `gorm:"not null"`
+	Match     string    `gorm:"not null;default:'domain';comment:domain, host, starts_with, exact or never"`
+	Position  int       `gorm:"not null;default:0"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tag labels vault entries; an entry can have many tags
+type Tag struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	VaultID   uuid.UUID `gorm:"not null;uniqueIndex:idx_tags_vault_name"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_tags_vault_name"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -1,16 +1,17 @@
 package models
 
-import(
+import (
 	"time"
 )
 
-
 // internal/models/totp_secret.go
 
 type TOTPSecret struct {
-    UserID      string    `gorm:"primaryKey"`
-    Secret      string    // base32‑encoded
-    Enabled     bool      // “enforced” flag, can stay false for now
-    IsConfirmed bool      // ← New: true only after a successful Verify
-    CreatedAt   time.Time
+	UserID          string `gorm:"primaryKey"`
+	Secret          string // legacy base32 plaintext, emptied by the TOTP encryption migration
+	EncryptedSecret string // base32 secret sealed with the master key ring
+	SecretKeyID     string `gorm:"index"` // per-user subkey that sealed it ("hkdf:<master key id>")
+	Enabled         bool   // “enforced” flag, can stay false for now
+	IsConfirmed     bool   // ← New: true only after a successful Verify
+	CreatedAt       time.Time
 }
@@ -12,6 +12,27 @@ type Vault struct {
 	CreatedAt time.Time `gorm:"default:now()"`
 	UpdatedAt time.Time
 
+	// Per-vault data-encryption key, wrapped with the master key
+	WrappedKey    string `json:"-"`                           // ciphertext envelope
+	WrappedKeyIV  string `json:"-"`                           // legacy IV column, empty once the key is stored as an envelope
+	MasterKeyID   string `gorm:"index" json:"-"`              // key that wrapped it: "hkdf:<master key id>" for per-user subkeys
+	KeyAADVersion int8   `gorm:"not null;default:0" json:"-"` // 0 = wrapped without associated data
+
+	// Zero-knowledge mode: entries are encrypted on the client with a key
+	// derived from these Argon2id parameters; the server never sees plaintext
+	ZeroKnowledge  bool `gorm:"not null;default:false"`
+	KDFAlgorithm   string
+	KDFSalt        string
+	KDFMemory      uint32 // KiB
+	KDFIterations  uint32
+	KDFParallelism uint8
+	KDFVerifier    string // client-encrypted known value to check the derived key
+
+	// Tamper evidence: keyed Merkle root over the entries, bumped on every change
+	IntegrityCounter   uint64     `gorm:"not null;default:0" json:"-"`
+	IntegrityRoot      string     `json:"-"`
+	IntegrityFlaggedAt *time.Time `json:"-"` // a write was refused because the entries didn't match the root
+
 	User     User      `gorm:"foreignKey:UserID"`
 	Services []Service `gorm:"foreignKey:VaultID"`
 }
@@ -21,4 +21,6 @@ func SetupRoutes(app *fiber.App, repo storage.Repository, wa *webauthn.WebAuthn)
 
     AssesmentRoutes(api, repo)
 
+    AdminRoutes(api, repo)
+
 }
\ No newline at end of file
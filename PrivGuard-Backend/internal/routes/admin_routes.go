@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/api/handlers"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/middleware"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+func AdminRoutes(router fiber.Router, repo storage.Repository) {
+	admin := router.Group("/admin", middleware.AdminMiddleware())
+
+	// Master key rotation
+	admin.Post("/key-rotation", handlers.StartKeyRotationHandler(repo))
+	admin.Get("/key-rotation", handlers.GetKeyRotationHandler(repo))
+
+	// Vault tamper / rollback checks
+	admin.Get("/vault-integrity", handlers.AdminVaultIntegrityHandler(repo))
+	admin.Post("/vault-integrity/accept", handlers.AcceptVaultIntegrityHandler(repo))
+
+	// Re-score stored passwords with the current strength estimator
+	admin.Post("/strength-recompute", handlers.RecomputeStrengthHandler(repo))
+
+	// Shamir seal / unseal
+	admin.Get("/seal-status", handlers.GetSealStatusHandler())
+	admin.Post("/unseal", handlers.UnsealHandler())
+	admin.Post("/seal", handlers.SealHandler())
+}
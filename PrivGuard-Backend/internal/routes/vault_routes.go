@@ -11,7 +11,7 @@ import (
 )
 
 func VaultRoutes(router fiber.Router, repo storage.Repository) {
-	protected := router.Group("/protected", middleware.AuthMiddleware(repo))
+	protected := router.Group("/protected", middleware.AuthMiddleware(repo), middleware.RequireUnsealed())
 
 	// Vault routes group
 	vault := protected.Group("/vault")
@@ -28,13 +28,127 @@ func VaultRoutes(router fiber.Router, repo storage.Repository) {
 		handlers.AddPasswordHandler(repo),
 	)
 
+	// Route: GET /vault/item-types (schemas of the typed items)
+	vault.Get("/item-types",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_item_types"),
+		handlers.ItemTypesHandler(),
+	)
+
+	// Route: POST /vault/items (add a secure note, card, identity, API key or Wi-Fi item)
+	vault.Post("/items",
+		middleware.UserRateLimit(repo, 30, 10*time.Minute, "vault_add"),
+		handlers.AddItemHandler(repo),
+	)
+
+	// Route: POST /vault/generate (random password or passphrase with its entropy)
+	vault.Post("/generate",
+		middleware.UserRateLimit(repo, 300, 10*time.Minute, "vault_generate"),
+		handlers.GenerateHandler(),
+	)
+
+	// Route: GET /vault/breach-range/:prefix (k-anonymity breach lookup by SHA-1 prefix)
+	vault.Get("/breach-range/:prefix",
+		middleware.UserRateLimit(repo, 300, 10*time.Minute, "vault_breach_range"),
+		handlers.BreachRangeHandler(repo),
+	)
+
+	// Route: GET /vault/zero-knowledge (mode and KDF parameters)
+	vault.Get("/zero-knowledge",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_zk_status"),
+		handlers.GetZeroKnowledgeHandler(repo),
+	)
+
+	// Route: POST /vault/zero-knowledge/migrate (move vault to client-side encryption)
+	vault.Post("/zero-knowledge/migrate",
+		middleware.UserRateLimit(repo, 5, 10*time.Minute, "vault_zk_migrate"),
+		handlers.EnableZeroKnowledgeHandler(repo),
+	)
+
+	// Route: GET /vault/integrity (verify entries against the vault's integrity root)
+	vault.Get("/integrity",
+		middleware.UserRateLimit(repo, 30, 10*time.Minute, "vault_integrity"),
+		handlers.VaultIntegrityHandler(repo),
+	)
+
+	// Route: GET /vault/trash (deleted entries)
+	vault.Get("/trash",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_trash"),
+		handlers.TrashHandler(repo),
+	)
+
+	// Route: DELETE /vault/trash (permanently delete everything in the trash)
+	vault.Delete("/trash",
+		middleware.UserRateLimit(repo, 10, 10*time.Minute, "vault_trash_empty"),
+		handlers.EmptyTrashHandler(repo),
+	)
+
+	// Route: POST /vault/trash/:id/restore
+	vault.Post("/trash/:id/restore",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_trash_restore"),
+		handlers.RestoreTrashHandler(repo),
+	)
+
+	// Route: GET /vault/folders (all folders, clients build the tree from parent_id)
+	vault.Get("/folders",
+		middleware.UserRateLimit(repo, 200, 10*time.Minute, "vault_folders"),
+		handlers.ListFoldersHandler(repo),
+	)
+
+	// Route: POST /vault/folders (create folder)
+	vault.Post("/folders",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_folder_create"),
+		handlers.CreateFolderHandler(repo),
+	)
+
+	// Route: POST /vault/folders/:id/update (rename or move folder)
+	vault.Post("/folders/:id/update",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_folder_update"),
+		handlers.UpdateFolderHandler(repo),
+	)
+
+	// Route: DELETE /vault/folders/:id (entries and subfolders move to the parent)
+	vault.Delete("/folders/:id",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_folder_delete"),
+		handlers.DeleteFolderHandler(repo),
+	)
+
+	// Route: POST /vault/move (move entries into a folder in bulk)
+	vault.Post("/move",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_move"),
+		handlers.MoveEntriesHandler(repo),
+	)
+
+	// Route: GET /vault/tags (all tags with entry counts)
+	vault.Get("/tags",
+		middleware.UserRateLimit(repo, 200, 10*time.Minute, "vault_tags"),
+		handlers.ListTagsHandler(repo),
+	)
+
+	// Route: POST /vault/tags (create tag)
+	vault.Post("/tags",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_tag_create"),
+		handlers.CreateTagHandler(repo),
+	)
+
+	// Route: POST /vault/tags/:id/update (rename tag)
+	vault.Post("/tags/:id/update",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_tag_update"),
+		handlers.RenameTagHandler(repo),
+	)
+
+	// Route: DELETE /vault/tags/:id (delete tag and untag its entries)
+	vault.Delete("/tags/:id",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_tag_delete"),
+		handlers.DeleteTagHandler(repo),
+	)
+
 	// Route: GET /vault/:id (fetch one entry)
 	vault.Get("/:id", 
 		middleware.UserRateLimit(repo, 200, 10*time.Minute, "vault_detail"),
 		handlers.GetPasswordDetailHandler(repo),
 	)
 
-	// Route: DELETE /vault/:id (delete entry)
+	// Route: DELETE /vault/:id (move entry to the trash)
 	vault.Delete("/:id", 
 		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_delete"),
 		handlers.DeletePasswordHandler(repo),
@@ -51,5 +165,95 @@ func VaultRoutes(router fiber.Router, repo storage.Repository) {
 		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_update_password"),
 		handlers.UpdateServicePasswordHandler(repo),
 	)
+
+	// Route: POST /vault/:id/update-item (name and fields of a typed item)
+	vault.Post("/:id/update-item",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_update_item"),
+		handlers.UpdateItemHandler(repo),
+	)
+
+	// Route: POST /vault/:id/update-details (username, URIs and custom fields)
+	vault.Post("/:id/update-details",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_update_details"),
+		handlers.UpdateServiceDetailsHandler(repo),
+	)
+
+	// Route: POST /vault/:id/favorite (mark or unmark as favorite)
+	vault.Post("/:id/favorite",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_favorite"),
+		handlers.SetFavoriteHandler(repo),
+	)
+
+	// Route: POST /vault/:id/tags (replace the entry's tags)
+	vault.Post("/:id/tags",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_entry_tags"),
+		handlers.SetServiceTagsHandler(repo),
+	)
+
+	// Route: GET /vault/:id/history (previous passwords, without secrets)
+	vault.Get("/:id/history",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_history"),
+		handlers.ListPasswordHistoryHandler(repo),
+	)
+
+	// Route: GET /vault/:id/history/:historyId (reveal one previous password)
+	vault.Get("/:id/history/:historyId",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_history_reveal"),
+		handlers.RevealPasswordHistoryHandler(repo),
+	)
+
+	// Route: POST /vault/:id/history/:historyId/restore
+	vault.Post("/:id/history/:historyId/restore",
+		middleware.UserRateLimit(repo, 20, 10*time.Minute, "vault_history_restore"),
+		handlers.RestorePasswordHistoryHandler(repo),
+	)
+
+	// Route: POST /vault/:id/otp (store a TOTP/HOTP seed from an otpauth URI or QR payload)
+	vault.Post("/:id/otp",
+		middleware.UserRateLimit(repo, 30, 10*time.Minute, "vault_otp_set"),
+		handlers.SetServiceOTPHandler(repo),
+	)
+
+	// Route: DELETE /vault/:id/otp
+	vault.Delete("/:id/otp",
+		middleware.UserRateLimit(repo, 30, 10*time.Minute, "vault_otp_remove"),
+		handlers.RemoveServiceOTPHandler(repo),
+	)
+
+	// Route: GET /vault/:id/otp/code (current TOTP code and its remaining validity)
+	vault.Get("/:id/otp/code",
+		middleware.UserRateLimit(repo, 300, 10*time.Minute, "vault_otp_code"),
+		handlers.ServiceOTPCodeHandler(repo),
+	)
+
+	// Route: POST /vault/:id/otp/code (next HOTP code; advances the counter)
+	vault.Post("/:id/otp/code",
+		middleware.UserRateLimit(repo, 60, 10*time.Minute, "vault_otp_next"),
+		handlers.NextServiceOTPCodeHandler(repo),
+	)
+
+	// Route: GET /vault/:id/attachments (list attached files and the vault's usage)
+	vault.Get("/:id/attachments",
+		middleware.UserRateLimit(repo, 100, 10*time.Minute, "vault_attachments"),
+		handlers.ListAttachmentsHandler(repo),
+	)
+
+	// Route: POST /vault/:id/attachments (multipart upload, field "file")
+	vault.Post("/:id/attachments",
+		middleware.UserRateLimit(repo, 20, 10*time.Minute, "vault_attachment_upload"),
+		handlers.UploadAttachmentHandler(repo),
+	)
+
+	// Route: GET /vault/:id/attachments/:attachmentId (download the file)
+	vault.Get("/:id/attachments/:attachmentId",
+		middleware.UserRateLimit(repo, 50, 10*time.Minute, "vault_attachment_download"),
+		handlers.DownloadAttachmentHandler(repo),
+	)
+
+	// Route: DELETE /vault/:id/attachments/:attachmentId
+	vault.Delete("/:id/attachments/:attachmentId",
+		middleware.UserRateLimit(repo, 30, 10*time.Minute, "vault_attachment_delete"),
+		handlers.DeleteAttachmentHandler(repo),
+	)
 }
 
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// BindLegacyCiphertexts re-encrypts rows written without associated data so they
+// are bound to their vault, row and field. Legacy rows stay readable until then,
+// or until LEGACY_CIPHERTEXTS=reject switches legacy reads off.
+func BindLegacyCiphertexts(repo storage.Repository) error {
+	if legacyReadsRejected() {
+		// Every row was bound before the switch; any legacy row now was marked
+		// legacy after the fact and must not be decrypted to migrate it
+		var vaults, entries int64
+		repo.DB.Model(&models.Vault{}).
+			Where("wrapped_key <> '' AND key_aad_version = ?", AADVersionLegacy).Count(&vaults)
+		repo.DB.Unscoped().Model(&models.Service{}).
+			Where("aad_version = ? AND client_encrypted = ?", AADVersionLegacy, false).Count(&entries)
+		if vaults > 0 || entries > 0 {
+			log.Printf("⚠️ %d vault keys and %d entries are marked as unbound but LEGACY_CIPHERTEXTS=reject; they will not be readable\n", vaults, entries)
+		}
+		return nil
+	}
+
+	ring, err := crypto.LoadKeyRing()
+	if err != nil {
+		return fmt.Errorf("failed to load encryption key: %w", err)
+	}
+
+	// Step 1: Re-wrap vault keys that were wrapped without AAD
+	var vaultIDs []uuid.UUID
+	if err := repo.DB.Model(&models.Vault{}).
+		Where("wrapped_key <> '' AND key_aad_version = ?", AADVersionLegacy).
+		Pluck("id", &vaultIDs).Error; err != nil {
+		return fmt.Errorf("failed to find legacy vault keys: %w", err)
+	}
+	for _, id := range vaultIDs {
+		if err := rewrapVaultKey(repo.DB, ring, id); err != nil {
+			return fmt.Errorf("failed to bind key of vault %s: %w", id, err)
+		}
+	}
+
+	// Step 2: Re-encrypt entries vault by vault
+	var entryVaultIDs []uuid.UUID
+	if err := repo.DB.Unscoped().Model(&models.Service{}).
+		Where("aad_version = ? AND client_encrypted = ?", AADVersionLegacy, false).
+		Distinct("vault_id").Pluck("vault_id", &entryVaultIDs).Error; err != nil {
+		return fmt.Errorf("failed to find legacy entries: %w", err)
+	}
+
+	var migrated int
+	for _, vaultID := range entryVaultIDs {
+		n, err := bindVaultEntries(repo, vaultID)
+		if errors.Is(err, ErrIntegrityMismatch) {
+			// Flagged; bound once an admin has accepted the vault's state
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to bind entries of vault %s: %w", vaultID, err)
+		}
+		migrated += n
+	}
+
+	if len(vaultIDs) > 0 || migrated > 0 {
+		log.Printf("✅ Bound %d vault keys and %d entries to their rows\n", len(vaultIDs), migrated)
+	}
+	log.Println("All ciphertexts are bound to their rows; set LEGACY_CIPHERTEXTS=reject to refuse unbound reads")
+	return nil
+}
+
+func bindVaultEntries(repo storage.Repository, vaultID uuid.UUID) (int, error) {
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return 0, err
+	}
+
+	// Vaults without a data key are migrated (and bound) by VaultDataKey itself
+	key, err := VaultDataKey(repo, &vault)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = updateVaultEntries(repo, vaultID, func(tx *gorm.DB) error {
+		var legacy []models.Service
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("vault_id = ? AND aad_version = ? AND client_encrypted = ?", vaultID, AADVersionLegacy, false).
+			Find(&legacy).Error; err != nil {
+			return err
+		}
+
+		for _, svc := range legacy {
+			plain, err := DecryptServicePassword(key, &svc)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt entry %s: %w", svc.ID, err)
+			}
+			notes, err := DecryptServiceNotes(key, &svc)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt notes of entry %s: %w", svc.ID, err)
+			}
+
+			svc.AADVersion = AADVersionBound
+			encrypted, err := sealServiceField(key, svc.VaultID, svc.ID, FieldPassword, []byte(plain))
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt entry %s: %w", svc.ID, err)
+			}
+			encryptedNotes, err := sealNotes(key, &svc, notes)
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt notes of entry %s: %w", svc.ID, err)
+			}
+
+			if err := tx.Unscoped().Model(&models.Service{}).Where("id = ?", svc.ID).
+				Updates(map[string]interface{}{
+					"encrypted_password": encrypted,
+					"iv":                 "",
+					"notes":              "",
+					"encrypted_notes":    encryptedNotes,
+					"notes_iv":           "",
+					"aad_version":        AADVersionBound,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		count = len(legacy)
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/blobstore"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// Fields of an attachment row its ciphertexts are bound to
+const (
+	FieldAttachmentKey     = "attachment_key"
+	FieldAttachmentContent = "attachment"
+)
+
+const (
+	defaultAttachmentMaxMB   = 10
+	defaultAttachmentQuotaMB = 100
+	maxAttachmentsPerEntry   = 20
+	maxAttachmentNameLength  = 255
+	maxContentTypeLength     = 127
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrAttachmentTooLarge  = errors.New("attachment is too large")
+	ErrAttachmentQuota     = errors.New("attachment storage quota exceeded")
+	ErrInvalidAttachment   = errors.New("invalid attachment")
+	ErrAttachmentsDisabled = errors.New("attachment storage is not configured")
+)
+
+// AttachmentUpload is a file being attached to an entry. In zero-knowledge
+// vaults Body is already encrypted by the client and is stored as-is.
+type AttachmentUpload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Body        io.Reader
+}
+
+// AttachmentMaxSize is the largest file that can be attached (ATTACHMENT_MAX_MB, default 10)
+func AttachmentMaxSize() int64 {
+	return envMegabytes("ATTACHMENT_MAX_MB", defaultAttachmentMaxMB)
+}
+
+// AttachmentQuota is how many bytes of attachments a vault may hold
+// (ATTACHMENT_QUOTA_MB, default 100). Files in the trash still count.
+func AttachmentQuota() int64 {
+	return envMegabytes("ATTACHMENT_QUOTA_MB", defaultAttachmentQuotaMB)
+}
+
+func envMegabytes(name string, defaultMB int) int64 {
+	mb, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || mb <= 0 {
+		mb = defaultMB
+	}
+	return int64(mb) << 20
+}
+
+// ListAttachments returns the attachments of an entry, oldest first
+func ListAttachments(repo storage.Repository, userID, serviceID string) ([]models.Attachment, error) {
+	_, service, err := findAttachmentParent(repo.DB, userID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []models.Attachment
+	if err := repo.DB.Where("service_id = ?", service.ID).
+		Order("created_at ASC").Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// AttachmentUsage returns how many bytes of attachments the user's vault holds
+func AttachmentUsage(repo storage.Repository, userID string) (int64, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return 0, err
+	}
+	return vaultAttachmentBytes(repo.DB, vault.ID)
+}
+
+// AddAttachment encrypts an upload and stores it in the blob store. Every file
+// gets its own random key, sealed with the vault data key and bound to the
+// attachment's row. The content is encrypted as a chunked stream, so no
+// ciphertext copy is held in memory; the upload itself is, as Fiber buffers the
+// whole multipart body (cmd/main.go sizes its BodyLimit for the largest file).
+// The blob is written before the row, so a row always points at a stored file;
+// a blob whose row can't be saved is deleted again.
+func AddAttachment(ctx context.Context, repo storage.Repository, userID, serviceID string, up AttachmentUpload) (*models.Attachment, error) {
+	if crypto.IsSealed() {
+		return nil, crypto.ErrSealed
+	}
+	if repo.Blobs == nil {
+		return nil, ErrAttachmentsDisabled
+	}
+
+	name, err := cleanAttachmentName(up.FileName)
+	if err != nil {
+		return nil, err
+	}
+	contentType := strings.TrimSpace(up.ContentType)
+	if contentType == "" || len(contentType) > maxContentTypeLength || strings.ContainsAny(contentType, "\r\n") {
+		contentType = "application/octet-stream"
+	}
+	if up.Size < 0 {
+		return nil, fmt.Errorf("%w: unknown file size", ErrInvalidAttachment)
+	}
+	if up.Size > AttachmentMaxSize() {
+		return nil, fmt.Errorf("%w: files can be at most %d MB", ErrAttachmentTooLarge, AttachmentMaxSize()>>20)
+	}
+
+	vault, service, err := findAttachmentParent(repo.DB, userID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := models.Attachment{
+		ID:              uuid.New(),
+		ServiceID:       service.ID,
+		VaultID:         vault.ID,
+		FileName:        name,
+		ContentType:     contentType,
+		Size:            up.Size,
+		ClientEncrypted: vault.ZeroKnowledge,
+	}
+	attachment.BlobKey = "attachments/" + vault.ID.String() + "/" + attachment.ID.String()
+
+	var fileKey []byte
+	if !vault.ZeroKnowledge {
+		dataKey, err := VaultDataKey(repo, vault)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vault key: %w", err)
+		}
+		if fileKey, err = crypto.GenerateDataKey(); err != nil {
+			return nil, fmt.Errorf("failed to generate file key: %w", err)
+		}
+		attachment.WrappedKey, err = crypto.WrapKey(fileKey, crypto.DataKeyID, dataKey,
+			crypto.RowAAD(vault.ID.String(), attachment.ID.String(), FieldAttachmentKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap file key: %w", err)
+		}
+	}
+
+	// Turn away uploads that can't fit before storing anything
+	if err := checkAttachmentQuota(repo.DB, vault.ID, service.ID, up.Size); err != nil {
+		return nil, err
+	}
+
+	if err := storeAttachmentBlob(ctx, repo.Blobs, &attachment, fileKey, up.Body); err != nil {
+		deleteAttachmentBlob(ctx, repo, &attachment)
+		return nil, err
+	}
+
+	// Check again under the vault lock so concurrent uploads can't overrun the quota
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", vault.ID).First(&models.Vault{}).Error; err != nil {
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+		if err := checkAttachmentQuota(tx, vault.ID, service.ID, up.Size); err != nil {
+			return err
+		}
+		if err := tx.Create(&attachment).Error; err != nil {
+			return fmt.Errorf("failed to save attachment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		deleteAttachmentBlob(ctx, repo, &attachment)
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// checkAttachmentQuota reports whether the entry can take another attachment
+// and the vault another size bytes
+func checkAttachmentQuota(db *gorm.DB, vaultID, serviceID uuid.UUID, size int64) error {
+	var count int64
+	if err := db.Model(&models.Attachment{}).Where("service_id = ?", serviceID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count attachments: %w", err)
+	}
+	if count >= maxAttachmentsPerEntry {
+		return fmt.Errorf("%w: an entry can have at most %d attachments", ErrAttachmentQuota, maxAttachmentsPerEntry)
+	}
+
+	used, err := vaultAttachmentBytes(db, vaultID)
+	if err != nil {
+		return err
+	}
+	if used+size > AttachmentQuota() {
+		return fmt.Errorf("%w: %d of %d MB used", ErrAttachmentQuota, used>>20, AttachmentQuota()>>20)
+	}
+	return nil
+}
+
+// deleteAttachmentBlob removes the blob of an upload that wasn't saved. It runs
+// even if the request was cancelled, since that is often why the upload failed.
+func deleteAttachmentBlob(ctx context.Context, repo storage.Repository, attachment *models.Attachment) {
+	if err := repo.Blobs.Delete(context.WithoutCancel(ctx), attachment.BlobKey); err != nil {
+		log.Printf(" Failed to remove blob of unsaved attachment %s: %v\n", attachment.ID, err)
+	}
+}
+
+// storeAttachmentBlob streams the upload, encrypted unless it is a client ciphertext, into the blob store
+func storeAttachmentBlob(ctx context.Context, blobs blobstore.Store, attachment *models.Attachment, fileKey []byte, body io.Reader) error {
+	if attachment.ClientEncrypted {
+		if err := blobs.Put(ctx, attachment.BlobKey, io.LimitReader(body, attachment.Size), attachment.Size); err != nil {
+			return fmt.Errorf("failed to store attachment: %w", err)
+		}
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		aad := crypto.RowAAD(attachment.VaultID.String(), attachment.ID.String(), FieldAttachmentContent)
+		n, err := crypto.EncryptStream(pw, io.LimitReader(body, attachment.Size), fileKey, aad)
+		if err == nil && n != attachment.Size {
+			err = fmt.Errorf("%w: upload ended after %d of %d bytes", ErrInvalidAttachment, n, attachment.Size)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	err := blobs.Put(ctx, attachment.BlobKey, pr, crypto.StreamCiphertextSize(attachment.Size))
+	// Unblock the encrypting goroutine if the store gave up early
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("failed to store attachment: %w", err)
+	}
+	return nil
+}
+
+// OpenAttachment returns an attachment and a reader of its content. Server-encrypted
+// files are decrypted while they are read; a tampered blob fails the read with
+// crypto.ErrStreamCorrupt. Client-encrypted files are returned as stored.
+func OpenAttachment(ctx context.Context, repo storage.Repository, userID, serviceID, attachmentID string) (*models.Attachment, io.ReadCloser, error) {
+	if repo.Blobs == nil {
+		return nil, nil, ErrAttachmentsDisabled
+	}
+
+	vault, attachment, err := findAttachment(repo.DB, userID, serviceID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fileKey []byte
+	if !attachment.ClientEncrypted {
+		if crypto.IsSealed() {
+			return nil, nil, crypto.ErrSealed
+		}
+		dataKey, err := VaultDataKey(repo, vault)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load vault key: %w", err)
+		}
+		fileKey, err = crypto.UnwrapKey(attachment.WrappedKey, crypto.SingleKey(crypto.DataKeyID, dataKey),
+			crypto.RowAAD(vault.ID.String(), attachment.ID.String(), FieldAttachmentKey))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to unwrap file key: %w", err)
+		}
+	}
+
+	blob, err := repo.Blobs.Get(ctx, attachment.BlobKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	if attachment.ClientEncrypted {
+		return attachment, blob, nil
+	}
+
+	plain, err := crypto.NewDecryptReader(blob, fileKey,
+		crypto.RowAAD(vault.ID.String(), attachment.ID.String(), FieldAttachmentContent))
+	if err != nil {
+		blob.Close()
+		return nil, nil, fmt.Errorf("failed to decrypt attachment: %w", err)
+	}
+	return attachment, readCloser{plain, blob}, nil
+}
+
+// DeleteAttachment removes an attachment and its blob
+func DeleteAttachment(ctx context.Context, repo storage.Repository, userID, serviceID, attachmentID string) error {
+	_, attachment, err := findAttachment(repo.DB, userID, serviceID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.DB.Delete(&models.Attachment{}, "id = ?", attachment.ID).Error; err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	deleteAttachmentBlobs(ctx, repo, []string{attachment.BlobKey})
+	return nil
+}
+
+// trashedAttachmentKeys returns the blob keys of attachments on a vault's
+// trashed entries matching the condition, before they are deleted for good
+func trashedAttachmentKeys(db *gorm.DB, vaultID uuid.UUID, condition string, args ...interface{}) ([]string, error) {
+	var keys []string
+	trashed := db.Unscoped().Model(&models.Service{}).Select("id").
+		Where("vault_id = ?", vaultID).Where(condition, args...)
+	if err := db.Model(&models.Attachment{}).
+		Where("service_id IN (?)", trashed).Pluck("blob_key", &keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to find attachments: %w", err)
+	}
+	return keys, nil
+}
+
+// deleteAttachmentBlobs removes blobs whose rows are already gone. Failures
+// only leave unreachable ciphertext behind, so they are logged, not returned.
+func deleteAttachmentBlobs(ctx context.Context, repo storage.Repository, keys []string) {
+	if repo.Blobs == nil {
+		if len(keys) > 0 {
+			log.Printf(" Blob store not configured, leaving %d attachment blobs behind\n", len(keys))
+		}
+		return
+	}
+	for _, key := range keys {
+		if err := repo.Blobs.Delete(ctx, key); err != nil {
+			log.Printf(" Failed to delete attachment blob %s: %v\n", key, err)
+		}
+	}
+}
+
+func vaultAttachmentBytes(db *gorm.DB, vaultID uuid.UUID) (int64, error) {
+	var used int64
+	if err := db.Model(&models.Attachment{}).Where("vault_id = ?", vaultID).
+		Select("COALESCE(SUM(size), 0)").Scan(&used).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum attachment sizes: %w", err)
+	}
+	return used, nil
+}
+
+// findAttachmentParent returns the user's vault and one of its entries outside the trash
+func findAttachmentParent(db *gorm.DB, userID, serviceID string) (*models.Vault, *models.Service, error) {
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(db, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var service models.Service
+	if err := db.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).First(&service).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to find service: %w", err)
+	}
+	return vault, &service, nil
+}
+
+func findAttachment(db *gorm.DB, userID, serviceID, attachmentID string) (*models.Vault, *models.Attachment, error) {
+	parsedAttachmentID, err := uuid.Parse(attachmentID)
+	if err != nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	vault, service, err := findAttachmentParent(db, userID, serviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var attachment models.Attachment
+	err = db.Where("id = ? AND service_id = ?", parsedAttachmentID, service.ID).First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find attachment: %w", err)
+	}
+	return vault, &attachment, nil
+}
+
+// cleanAttachmentName keeps the base name of an uploaded file and rejects
+// names that are empty, too long or carry control characters
+func cleanAttachmentName(name string) (string, error) {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("%w: file name is required", ErrInvalidAttachment)
+	}
+	if len(name) > maxAttachmentNameLength {
+		return "", fmt.Errorf("%w: file name is longer than %d bytes", ErrInvalidAttachment, maxAttachmentNameLength)
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%w: file name contains control characters", ErrInvalidAttachment)
+	}
+	return name, nil
+}
+
+// readCloser reads from a decrypting reader and closes the blob underneath it
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+const (
+	defaultBreachRecheckDays = 7
+	breachCheckInterval      = time.Hour
+	breachCheckBatchSize     = 500
+	breachCheckLockKey       = "lock:breach_check"
+	breachCheckLockTTL       = breachCheckInterval
+)
+
+var breachCheckOnce sync.Once
+
+// BreachRecheckAge is how long a breach check stays current before the entry
+// is checked again (BREACH_RECHECK_DAYS, default 7), since breach corpora grow
+func BreachRecheckAge() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("BREACH_RECHECK_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultBreachRecheckDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// CheckBreachedPasswords checks a batch of server-encrypted logins that were
+// never checked, changed password since, or were last checked too long ago,
+// and records how often each password appears in known breaches. Zero-knowledge
+// entries are skipped since the server can't read them.
+func CheckBreachedPasswords(repo storage.Repository) (checked, breached int, err error) {
+	if repo.Breaches == nil {
+		return 0, 0, nil
+	}
+	if crypto.IsSealed() {
+		return 0, 0, crypto.ErrSealed
+	}
+
+	var due []models.Service
+	if err := repo.DB.
+		Where("item_type = ? AND client_encrypted = ? AND encrypted_password <> ''", ItemLogin, false).
+		Where("breach_checked_at IS NULL OR breach_checked_at < ?", time.Now().Add(-BreachRecheckAge())).
+		Order("breach_checked_at ASC NULLS FIRST").Limit(breachCheckBatchSize).
+		Find(&due).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to find entries to check: %w", err)
+	}
+
+	// A vault or entry that can't be decrypted is logged and skipped, so it
+	// doesn't hold up every other entry
+	keys := make(map[uuid.UUID][]byte)
+	ctx := context.Background()
+	for _, svc := range due {
+		key, ok := keys[svc.VaultID]
+		if !ok {
+			key, err = breachCheckKey(repo, svc.VaultID)
+			if err != nil {
+				log.Printf(" Breach check skipped vault %s: %v\n", svc.VaultID, err)
+			}
+			keys[svc.VaultID] = key
+		}
+		if key == nil {
+			continue
+		}
+
+		password, err := DecryptServicePassword(key, &svc)
+		if err != nil {
+			log.Printf(" Breach check skipped entry %s: %v\n", svc.ID, err)
+			continue
+		}
+		// An unavailable API ends the run; the rest is picked up next time
+		count, err := repo.Breaches.Count(ctx, password)
+		if err != nil {
+			return checked, breached, err
+		}
+
+		// Only record the result if the password wasn't changed meanwhile
+		if err := repo.DB.Model(&models.Service{}).
+			Where("id = ? AND encrypted_password = ?", svc.ID, svc.EncryptedPassword).
+			UpdateColumns(map[string]interface{}{
+				"breach_count":      count,
+				"breach_checked_at": time.Now(),
+			}).Error; err != nil {
+			return checked, breached, fmt.Errorf("failed to save breach check of entry %s: %w", svc.ID, err)
+		}
+		checked++
+		if count > 0 {
+			breached++
+		}
+	}
+	return checked, breached, nil
+}
+
+// localBreachCheck checks a password while it is being saved when lookups are
+// answered by a local index. With the range API, or if the lookup fails, the
+// entry is left unchecked (nil time) for the background job.
+func localBreachCheck(repo storage.Repository, password string) (int, *time.Time) {
+	if repo.Breaches == nil || !repo.Breaches.Offline() || password == "" {
+		return 0, nil
+	}
+	count, err := repo.Breaches.Count(context.Background(), password)
+	if err != nil {
+		log.Printf(" Local breach check failed: %v\n", err)
+		return 0, nil
+	}
+	now := time.Now()
+	return count, &now
+}
+
+func breachCheckKey(repo storage.Repository, vaultID uuid.UUID) ([]byte, error) {
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return nil, fmt.Errorf("failed to load vault: %w", err)
+	}
+	return VaultDataKey(repo, &vault)
+}
+
+// StartBreachChecks checks due entries now and then every hour, one batch per
+// run. Runs are skipped while the server is sealed or another instance is
+// checking; calling it again is a no-op.
+func StartBreachChecks(repo storage.Repository) {
+	if repo.Breaches == nil {
+		return
+	}
+	breachCheckOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(breachCheckInterval)
+			defer ticker.Stop()
+
+			for {
+				if !crypto.IsSealed() {
+					runBreachChecks(repo)
+				}
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+func runBreachChecks(repo storage.Repository) {
+	ctx := context.Background()
+	lockValue := uuid.NewString()
+	ok, err := acquireJobLock(ctx, repo.RedisClient, breachCheckLockKey, lockValue, breachCheckLockTTL)
+	if err != nil {
+		log.Printf(" Failed to acquire breach check lock: %v\n", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := releaseJobLock(ctx, repo.RedisClient, breachCheckLockKey, lockValue); err != nil {
+			log.Printf(" Failed to release breach check lock: %v\n", err)
+		}
+	}()
+
+	checked, breached, err := CheckBreachedPasswords(repo)
+	if err != nil {
+		log.Printf(" Breach check failed: %v\n", err)
+	} else if checked > 0 {
+		log.Printf("🔎 Checked %d passwords against known breaches, %d found\n", checked, breached)
+	}
+}
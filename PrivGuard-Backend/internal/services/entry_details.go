@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+)
+
+var ErrInvalidEntry = errors.New("invalid vault entry")
+
+// Custom field types
+const (
+	CustomFieldText    = "text"
+	CustomFieldHidden  = "hidden"
+	CustomFieldBoolean = "boolean"
+)
+
+// How a URI is matched against the page being filled
+var uriMatchTypes = map[string]bool{
+	"domain":      true,
+	"host":        true,
+	"starts_with": true,
+	"exact":       true,
+	"never":       true,
+}
+
+// Limits for entry details
+const (
+	maxEntryURIs        = 20
+	maxURILength        = 2048
+	maxUsernameLength   = 512
+	maxCustomFields     = 50
+	maxFieldNameLength  = 100
+	maxFieldValueLength = 10000
+)
+
+// EntryURI is one URI of a vault entry
+type EntryURI struct {
+	URI   string `json:"uri"`
+	Match string `json:"match,omitempty"`
+}
+
+// CustomField is a user-defined field. Hidden values are sent in Value and
+// encrypted by the server, or sent as EncryptedValue by zero-knowledge clients.
+type CustomField struct {
+	ID             uuid.UUID       `json:"id"`
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	Value          string          `json:"value,omitempty"`
+	EncryptedValue *ClientEnvelope `json:"encrypted_value,omitempty"`
+}
+
+// EntryDetails are the username, URIs and custom fields of a vault entry
+type EntryDetails struct {
+	Username          string          `json:"username"`
+	EncryptedUsername *ClientEnvelope `json:"encrypted_username,omitempty"`
+	URIs              []EntryURI      `json:"uris"`
+	Fields            []CustomField   `json:"fields"`
+}
+
+func (d *EntryDetails) validate(zeroKnowledge bool) error {
+	if zeroKnowledge && d.Username != "" {
+		return ErrZeroKnowledgeEnabled
+	}
+	if !zeroKnowledge && d.EncryptedUsername != nil {
+		return ErrZeroKnowledgeDisabled
+	}
+	if len(d.Username) > maxUsernameLength {
+		return fmt.Errorf("%w: username is too long", ErrInvalidEntry)
+	}
+
+	if len(d.URIs) > maxEntryURIs {
+		return fmt.Errorf("%w: at most %d URIs are allowed", ErrInvalidEntry, maxEntryURIs)
+	}
+	for i := range d.URIs {
+		uri := &d.URIs[i]
+		uri.URI = strings.TrimSpace(uri.URI)
+		if uri.URI == "" || len(uri.URI) > maxURILength {
+			return fmt.Errorf("%w: URI %d is empty or too long", ErrInvalidEntry, i+1)
+		}
+		if uri.Match == "" {
+			uri.Match = "domain"
+		}
+		if !uriMatchTypes[uri.Match] {
+			return fmt.Errorf("%w: unknown URI match type %q", ErrInvalidEntry, uri.Match)
+		}
+	}
+
+	if len(d.Fields) > maxCustomFields {
+		return fmt.Errorf("%w: at most %d custom fields are allowed", ErrInvalidEntry, maxCustomFields)
+	}
+	for i := range d.Fields {
+		field := &d.Fields[i]
+		field.Name = strings.TrimSpace(field.Name)
+		if field.Name == "" || len(field.Name) > maxFieldNameLength {
+			return fmt.Errorf("%w: custom field %d needs a name of at most %d characters", ErrInvalidEntry, i+1, maxFieldNameLength)
+		}
+		if len(field.Value) > maxFieldValueLength {
+			return fmt.Errorf("%w: value of field %q is too long", ErrInvalidEntry, field.Name)
+		}
+
+		switch field.Type {
+		case CustomFieldText:
+		case CustomFieldBoolean:
+			if field.Value != "true" && field.Value != "false" {
+				return fmt.Errorf("%w: boolean field %q must be \"true\" or \"false\"", ErrInvalidEntry, field.Name)
+			}
+		case CustomFieldHidden:
+			if zeroKnowledge && (field.EncryptedValue == nil || field.Value != "") {
+				return ErrZeroKnowledgeEnabled
+			}
+			if !zeroKnowledge && field.EncryptedValue != nil {
+				return ErrZeroKnowledgeDisabled
+			}
+			continue
+		default:
+			return fmt.Errorf("%w: unknown custom field type %q", ErrInvalidEntry, field.Type)
+		}
+		if field.EncryptedValue != nil {
+			return fmt.Errorf("%w: only hidden fields can be encrypted", ErrInvalidEntry)
+		}
+	}
+
+	return nil
+}
+
+// buildEntryDetails turns validated details into the stored username and rows.
+// Server-side vaults encrypt the username and hidden values with the vault key
+// (always bound to their row); zero-knowledge vaults store the client envelopes.
+func buildEntryDetails(key []byte, vaultID, serviceID uuid.UUID, d *EntryDetails, clientEncrypted bool) (string, []models.ServiceURI, []models.ServiceField, error) {
+	var username string
+	var err error
+	if clientEncrypted {
+		username, err = encodeOptionalEnvelope(d.EncryptedUsername)
+	} else if d.Username != "" {
+		username, err = sealServiceField(key, vaultID, serviceID, FieldUsername, []byte(d.Username))
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	uris := make([]models.ServiceURI, 0, len(d.URIs))
+	for i, uri := range d.URIs {
+		uris = append(uris, models.ServiceURI{
+			ID:        uuid.New(),
+			ServiceID: serviceID,
+			URI:       uri.URI,
+			Match:     uri.Match,
+			Position:  i,
+		})
+	}
+
+	fields := make([]models.ServiceField, 0, len(d.Fields))
+	for i, field := range d.Fields {
+		row := models.ServiceField{
+			ID:        uuid.New(),
+			ServiceID: serviceID,
+			Name:      field.Name,
+			Type:      field.Type,
+			Position:  i,
+		}
+		switch {
+		case field.Type != CustomFieldHidden:
+			row.Value = field.Value
+		case clientEncrypted:
+			row.EncryptedValue, err = field.EncryptedValue.encode()
+		case field.Value != "":
+			row.EncryptedValue, err = sealServiceField(key, vaultID, row.ID, FieldCustomValue, []byte(field.Value))
+		}
+		if err != nil {
+			return "", nil, nil, err
+		}
+		fields = append(fields, row)
+	}
+
+	return username, uris, fields, nil
+}
+
+// replaceEntryDetails swaps an entry's URIs and custom fields for new ones
+func replaceEntryDetails(tx *gorm.DB, serviceID uuid.UUID, uris []models.ServiceURI, fields []models.ServiceField) error {
+	if err := tx.Where("service_id = ?", serviceID).Delete(&models.ServiceURI{}).Error; err != nil {
+		return fmt.Errorf("failed to remove URIs: %w", err)
+	}
+	if err := tx.Where("service_id = ?", serviceID).Delete(&models.ServiceField{}).Error; err != nil {
+		return fmt.Errorf("failed to remove custom fields: %w", err)
+	}
+	if len(uris) > 0 {
+		if err := tx.Create(&uris).Error; err != nil {
+			return fmt.Errorf("failed to save URIs: %w", err)
+		}
+	}
+	if len(fields) > 0 {
+		if err := tx.Create(&fields).Error; err != nil {
+			return fmt.Errorf("failed to save custom fields: %w", err)
+		}
+	}
+	return nil
+}
+
+// sortedDetails returns an entry's URIs and fields in the order the user gave them
+func sortedDetails(svc *models.Service) ([]models.ServiceURI, []models.ServiceField) {
+	uris := append([]models.ServiceURI(nil), svc.URIs...)
+	sort.Slice(uris, func(i, j int) bool { return uris[i].Position < uris[j].Position })
+	fields := append([]models.ServiceField(nil), svc.Fields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Position < fields[j].Position })
+	return uris, fields
+}
+
+// decryptServiceUsername returns the username of a server-encrypted entry, if any
+func decryptServiceUsername(key []byte, svc *models.Service) (string, error) {
+	if svc.EncryptedUsername == "" {
+		return "", nil
+	}
+	username, err := crypto.Decrypt(svc.EncryptedUsername, crypto.SingleKey(crypto.DataKeyID, key),
+		crypto.RowAAD(svc.VaultID.String(), svc.ID.String(), FieldUsername))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt username: %w", err)
+	}
+	return string(username), nil
+}
+
+// DecryptEntryDetails returns the details of a server-encrypted entry in plaintext.
+// The entry must be loaded with its URIs and Fields.
+func DecryptEntryDetails(key []byte, svc *models.Service) (*EntryDetails, error) {
+	details := &EntryDetails{URIs: []EntryURI{}, Fields: []CustomField{}}
+
+	username, err := decryptServiceUsername(key, svc)
+	if err != nil {
+		return nil, err
+	}
+	details.Username = username
+
+	uris, fields := sortedDetails(svc)
+	for _, uri := range uris {
+		details.URIs = append(details.URIs, EntryURI{URI: uri.URI, Match: uri.Match})
+	}
+	for _, field := range fields {
+		value := field.Value
+		if field.Type == CustomFieldHidden && field.EncryptedValue != "" {
+			plain, err := crypto.Decrypt(field.EncryptedValue, crypto.SingleKey(crypto.DataKeyID, key),
+				crypto.RowAAD(svc.VaultID.String(), field.ID.String(), FieldCustomValue))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt field %q: %w", field.Name, err)
+			}
+			value = string(plain)
+		}
+		details.Fields = append(details.Fields, CustomField{ID: field.ID, Name: field.Name, Type: field.Type, Value: value})
+	}
+
+	return details, nil
+}
+
+// ClientEntryDetails returns the details of a zero-knowledge entry with the
+// username and hidden values as the client's own envelopes
+func ClientEntryDetails(svc *models.Service) *EntryDetails {
+	details := &EntryDetails{URIs: []EntryURI{}, Fields: []CustomField{}}
+	details.EncryptedUsername, _ = DecodeClientEnvelope(svc.EncryptedUsername)
+
+	uris, fields := sortedDetails(svc)
+	for _, uri := range uris {
+		details.URIs = append(details.URIs, EntryURI{URI: uri.URI, Match: uri.Match})
+	}
+	for _, field := range fields {
+		out := CustomField{ID: field.ID, Name: field.Name, Type: field.Type, Value: field.Value}
+		if field.Type == CustomFieldHidden {
+			out.EncryptedValue, _ = DecodeClientEnvelope(field.EncryptedValue)
+		}
+		details.Fields = append(details.Fields, out)
+	}
+
+	return details
+}
@@ -0,0 +1,469 @@
+package services
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// FieldOTP labels an entry's one-time password seed in its associated data
+const FieldOTP = "otp"
+
+// One-time password types
+const (
+	OTPTypeTOTP = "totp"
+	OTPTypeHOTP = "hotp"
+)
+
+const (
+	defaultOTPDigits = 6
+	defaultOTPPeriod = 30
+	maxOTPPeriod     = 300
+	maxOTPLabel      = 200
+)
+
+var (
+	ErrInvalidOTP = errors.New("invalid one-time password configuration")
+	ErrNoOTP      = errors.New("entry has no one-time password")
+
+	// ErrOTPCounterBased is returned when reading an HOTP code without advancing
+	// the counter; HOTP codes are issued by NextServiceOTPCode only
+	ErrOTPCounterBased = errors.New("HOTP codes advance the counter; request the next code instead")
+)
+
+var otpAlgorithms = map[string]otp.Algorithm{
+	"SHA1":   otp.AlgorithmSHA1,
+	"SHA256": otp.AlgorithmSHA256,
+	"SHA512": otp.AlgorithmSHA512,
+}
+
+// OTPConfig is the one-time password seed of an entry as stored, sealed, in
+// Service.EncryptedOTP
+type OTPConfig struct {
+	Type      string `json:"type"`
+	Secret    string `json:"secret"` // base32 without padding
+	Issuer    string `json:"issuer,omitempty"`
+	Account   string `json:"account,omitempty"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    uint   `json:"period,omitempty"`  // TOTP only
+	Counter   uint64 `json:"counter,omitempty"` // HOTP only: counter of the next code
+}
+
+// OTPSummary describes an entry's one-time password without its secret
+type OTPSummary struct {
+	Type      string `json:"type"`
+	Issuer    string `json:"issuer,omitempty"`
+	Account   string `json:"account,omitempty"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    uint   `json:"period,omitempty"`
+	Counter   uint64 `json:"counter,omitempty"`
+}
+
+// OTPCode is a generated one-time password. TOTP codes carry their validity
+// window; HOTP codes the counter they were generated for.
+type OTPCode struct {
+	Code      string     `json:"code"`
+	Type      string     `json:"type"`
+	Digits    int        `json:"digits"`
+	Period    uint       `json:"period,omitempty"`
+	Remaining int        `json:"remaining,omitempty"` // seconds the code stays valid
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Counter   *uint64    `json:"counter,omitempty"`
+}
+
+// OTPInput sets an entry's one-time password: URI is an otpauth:// URI or the
+// otpauth-migration:// payload of an authenticator export QR code. Zero-knowledge
+// vaults send the seed encrypted by the client instead.
+type OTPInput struct {
+	URI          string          `json:"uri"`
+	EncryptedOTP *ClientEnvelope `json:"encrypted_otp"`
+}
+
+func (c *OTPConfig) Summary() *OTPSummary {
+	return &OTPSummary{
+		Type:      c.Type,
+		Issuer:    c.Issuer,
+		Account:   c.Account,
+		Algorithm: c.Algorithm,
+		Digits:    c.Digits,
+		Period:    c.Period,
+		Counter:   c.Counter,
+	}
+}
+
+// ParseOTPURI reads a one-time password seed from an otpauth:// URI or a
+// single-account otpauth-migration:// payload
+func ParseOTPURI(raw string) (*OTPConfig, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(strings.ToLower(raw), "otpauth-migration:") {
+		configs, err := ParseOTPMigration(raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(configs) != 1 {
+			return nil, fmt.Errorf("%w: the payload holds %d accounts, only one can be added to an entry", ErrInvalidOTP, len(configs))
+		}
+		return configs[0], nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || !strings.EqualFold(u.Scheme, "otpauth") {
+		return nil, fmt.Errorf("%w: expected an otpauth:// URI", ErrInvalidOTP)
+	}
+	q := u.Query()
+
+	cfg := &OTPConfig{
+		Type:      strings.ToLower(u.Host),
+		Secret:    q.Get("secret"),
+		Issuer:    q.Get("issuer"),
+		Algorithm: strings.ToUpper(q.Get("algorithm")),
+	}
+
+	// The label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		if cfg.Issuer == "" {
+			cfg.Issuer = strings.TrimSpace(issuer)
+		}
+		label = account
+	}
+	cfg.Account = strings.TrimSpace(label)
+
+	if digits := q.Get("digits"); digits != "" {
+		if cfg.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("%w: digits must be a number", ErrInvalidOTP)
+		}
+	}
+	if period := q.Get("period"); period != "" {
+		p, err := strconv.ParseUint(period, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: period must be a number", ErrInvalidOTP)
+		}
+		cfg.Period = uint(p)
+	}
+	if counter := q.Get("counter"); counter != "" {
+		if cfg.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: counter must be a number", ErrInvalidOTP)
+		}
+	}
+
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// normalize fills in defaults and checks the configuration can generate codes
+func (c *OTPConfig) normalize() error {
+	switch c.Type {
+	case OTPTypeTOTP:
+		if c.Period == 0 {
+			c.Period = defaultOTPPeriod
+		}
+		if c.Period > maxOTPPeriod {
+			return fmt.Errorf("%w: period must be at most %d seconds", ErrInvalidOTP, maxOTPPeriod)
+		}
+		c.Counter = 0
+	case OTPTypeHOTP:
+		c.Period = 0
+	default:
+		return fmt.Errorf("%w: type must be totp or hotp", ErrInvalidOTP)
+	}
+
+	if c.Algorithm == "" {
+		c.Algorithm = "SHA1"
+	}
+	if _, ok := otpAlgorithms[c.Algorithm]; !ok {
+		return fmt.Errorf("%w: algorithm must be SHA1, SHA256 or SHA512", ErrInvalidOTP)
+	}
+
+	if c.Digits == 0 {
+		c.Digits = defaultOTPDigits
+	}
+	if c.Digits < 6 || c.Digits > 8 {
+		return fmt.Errorf("%w: digits must be between 6 and 8", ErrInvalidOTP)
+	}
+
+	secret := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(c.Secret))
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil || len(decoded) == 0 {
+		return fmt.Errorf("%w: secret must be base32", ErrInvalidOTP)
+	}
+	c.Secret = secret
+
+	if len(c.Issuer) > maxOTPLabel || len(c.Account) > maxOTPLabel {
+		return fmt.Errorf("%w: issuer and account must be at most %d characters", ErrInvalidOTP, maxOTPLabel)
+	}
+	return nil
+}
+
+// generate returns the code for a time (TOTP) or the configured counter (HOTP)
+func (c *OTPConfig) generate(now time.Time) (*OTPCode, error) {
+	algorithm := otpAlgorithms[c.Algorithm]
+	digits := otp.Digits(c.Digits)
+
+	if c.Type == OTPTypeHOTP {
+		code, err := hotp.GenerateCodeCustom(c.Secret, c.Counter, hotp.ValidateOpts{Digits: digits, Algorithm: algorithm})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate code: %w", err)
+		}
+		counter := c.Counter
+		return &OTPCode{Code: code, Type: c.Type, Digits: c.Digits, Counter: &counter}, nil
+	}
+
+	code, err := totp.GenerateCodeCustom(c.Secret, now, totp.ValidateOpts{
+		Period:    c.Period,
+		Digits:    digits,
+		Algorithm: algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate code: %w", err)
+	}
+	period := int64(c.Period)
+	expiresAt := time.Unix((now.Unix()/period+1)*period, 0).UTC()
+	return &OTPCode{
+		Code:      code,
+		Type:      c.Type,
+		Digits:    c.Digits,
+		Period:    c.Period,
+		Remaining: int((expiresAt.Sub(now) + time.Second - 1) / time.Second),
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+func sealOTPConfig(key []byte, svc *models.Service, cfg *OTPConfig) (string, error) {
+	plain, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return sealServiceField(key, svc.VaultID, svc.ID, FieldOTP, plain)
+}
+
+// DecryptOTPConfig returns the one-time password seed of a server-encrypted
+// entry, or nil if it has none
+func DecryptOTPConfig(key []byte, svc *models.Service) (*OTPConfig, error) {
+	if svc.EncryptedOTP == "" {
+		return nil, nil
+	}
+	plain, err := crypto.Decrypt(svc.EncryptedOTP, crypto.SingleKey(crypto.DataKeyID, key),
+		crypto.RowAAD(svc.VaultID.String(), svc.ID.String(), FieldOTP))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt one-time password seed: %w", err)
+	}
+	var cfg OTPConfig
+	if err := json.Unmarshal(plain, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode one-time password seed: %w", err)
+	}
+	return &cfg, nil
+}
+
+// SetServiceOTP stores a one-time password seed on an entry, replacing any
+// previous one. It returns the seed's summary, or nil in zero-knowledge vaults.
+func SetServiceOTP(repo storage.Repository, userID, serviceID string, in OTPInput) (*OTPSummary, error) {
+	if crypto.IsSealed() {
+		return nil, crypto.ErrSealed
+	}
+
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *OTPConfig
+	var key []byte
+	if vault.ZeroKnowledge {
+		if in.URI != "" || in.EncryptedOTP == nil {
+			return nil, ErrZeroKnowledgeEnabled
+		}
+	} else {
+		if in.EncryptedOTP != nil {
+			return nil, ErrZeroKnowledgeDisabled
+		}
+		if cfg, err = ParseOTPURI(in.URI); err != nil {
+			return nil, err
+		}
+		if key, err = VaultDataKey(repo, vault); err != nil {
+			return nil, fmt.Errorf("failed to load vault key: %w", err)
+		}
+	}
+
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		svc, err := lockUserService(tx, vault.ID, parsedServiceID)
+		if err != nil {
+			return err
+		}
+		var sealed string
+		if vault.ZeroKnowledge {
+			sealed, err = in.EncryptedOTP.encode()
+		} else {
+			sealed, err = sealOTPConfig(key, svc, cfg)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Service{}).Where("id = ?", svc.ID).
+			Updates(map[string]interface{}{
+				"encrypted_otp": sealed,
+				"updated_at":    time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to save one-time password: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	invalidateVaultCache(repo, userID)
+
+	if cfg == nil {
+		return nil, nil
+	}
+	return cfg.Summary(), nil
+}
+
+// RemoveServiceOTP deletes the one-time password seed of an entry
+func RemoveServiceOTP(repo storage.Repository, userID, serviceID string) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		result := tx.Model(&models.Service{}).
+			Where("id = ? AND vault_id = ? AND encrypted_otp <> ''", parsedServiceID, vault.ID).
+			Updates(map[string]interface{}{
+				"encrypted_otp": "",
+				"updated_at":    time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to remove one-time password: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoOTP
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	invalidateVaultCache(repo, userID)
+	return nil
+}
+
+// ServiceOTPCode generates the current code of an entry's TOTP. It never writes,
+// so HOTP entries return ErrOTPCounterBased. Zero-knowledge clients generate
+// codes themselves.
+func ServiceOTPCode(repo storage.Repository, userID, serviceID string, now time.Time) (*OTPCode, error) {
+	return serviceOTPCode(repo, userID, serviceID, now, false)
+}
+
+// NextServiceOTPCode issues the next code of an entry's HOTP and advances its
+// counter, so every call returns a new code. TOTP entries get their current code.
+func NextServiceOTPCode(repo storage.Repository, userID, serviceID string, now time.Time) (*OTPCode, error) {
+	return serviceOTPCode(repo, userID, serviceID, now, true)
+}
+
+func serviceOTPCode(repo storage.Repository, userID, serviceID string, now time.Time, advance bool) (*OTPCode, error) {
+	if crypto.IsSealed() {
+		return nil, crypto.ErrSealed
+	}
+
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if vault.ZeroKnowledge {
+		return nil, ErrZeroKnowledgeEnabled
+	}
+	key, err := VaultDataKey(repo, vault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vault key: %w", err)
+	}
+
+	// TOTP codes are read without a write; only HOTP has a counter to advance
+	var current models.Service
+	if err := repo.DB.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).
+		First(&current).Error; err != nil {
+		return nil, fmt.Errorf("failed to find service: %w", err)
+	}
+	cfg, err := DecryptOTPConfig(key, &current)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, ErrNoOTP
+	}
+	if cfg.Type != OTPTypeHOTP {
+		return cfg.generate(now)
+	}
+	if !advance {
+		return nil, ErrOTPCounterBased
+	}
+
+	var code *OTPCode
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		svc, err := lockUserService(tx, vault.ID, parsedServiceID)
+		if err != nil {
+			return err
+		}
+		cfg, err := DecryptOTPConfig(key, svc)
+		if err != nil {
+			return err
+		}
+		if cfg == nil || cfg.Type != OTPTypeHOTP {
+			return ErrNoOTP
+		}
+
+		if code, err = cfg.generate(now); err != nil {
+			return err
+		}
+		cfg.Counter++
+		sealed, err := sealOTPConfig(key, svc, cfg)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Service{}).Where("id = ?", svc.ID).
+			UpdateColumn("encrypted_otp", sealed).Error; err != nil {
+			return fmt.Errorf("failed to advance HOTP counter: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return code, nil
+}
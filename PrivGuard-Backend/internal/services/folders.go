@@ -0,0 +1,253 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+var (
+	ErrFolderNotFound = errors.New("folder not found")
+	ErrInvalidFolder  = errors.New("invalid folder")
+)
+
+// Limits for folders
+const (
+	maxFolderNameLength = 100
+	maxFolderDepth      = 10
+	maxBulkMoveEntries  = 500
+)
+
+// FolderInput is the name and parent of a folder; a nil ParentID makes it top-level
+type FolderInput struct {
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+// ListFolders returns every folder of the user's vault, ordered by name
+func ListFolders(repo storage.Repository, userID string) ([]models.Folder, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	return vaultFolders(repo.DB, vault.ID)
+}
+
+// CreateFolder adds a folder to the user's vault
+func CreateFolder(repo storage.Repository, userID string, input FolderInput) (*models.Folder, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := models.Folder{ID: uuid.New(), VaultID: vault.ID}
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyFolderInput(tx, &folder, input); err != nil {
+			return err
+		}
+		return tx.Create(&folder).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// UpdateFolder renames a folder and/or moves it under another parent
+func UpdateFolder(repo storage.Repository, userID, folderID string, input FolderInput) (*models.Folder, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var folder models.Folder
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		found, err := findVaultFolder(tx, vault.ID, folderID)
+		if err != nil {
+			return err
+		}
+		folder = *found
+		if err := applyFolderInput(tx, &folder, input); err != nil {
+			return err
+		}
+		return tx.Model(&models.Folder{}).Where("id = ?", folder.ID).
+			Updates(map[string]interface{}{"name": folder.Name, "parent_id": folder.ParentID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// DeleteFolder removes a folder. Its entries and subfolders move up to its parent,
+// so deleting a folder never deletes entries.
+func DeleteFolder(repo storage.Repository, userID, folderID string) error {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		folder, err := findVaultFolder(tx, vault.ID, folderID)
+		if err != nil {
+			return err
+		}
+
+		// Trashed entries move too, so restoring them doesn't point at a missing folder
+		if err := tx.Unscoped().Model(&models.Service{}).Where("folder_id = ?", folder.ID).
+			Update("folder_id", folder.ParentID).Error; err != nil {
+			return fmt.Errorf("failed to move folder entries: %w", err)
+		}
+		if err := tx.Model(&models.Folder{}).Where("parent_id = ?", folder.ID).
+			Update("parent_id", folder.ParentID).Error; err != nil {
+			return fmt.Errorf("failed to move subfolders: %w", err)
+		}
+		if err := tx.Delete(&models.Folder{}, "id = ?", folder.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete folder: %w", err)
+		}
+		return nil
+	})
+}
+
+// MoveServicesToFolder moves entries of the user's vault into a folder, or out of
+// any folder when folderID is nil. It returns how many entries were moved.
+func MoveServicesToFolder(repo storage.Repository, userID string, serviceIDs []uuid.UUID, folderID *uuid.UUID) (int64, error) {
+	if len(serviceIDs) == 0 || len(serviceIDs) > maxBulkMoveEntries {
+		return 0, fmt.Errorf("%w: between 1 and %d entries can be moved at once", ErrInvalidFolder, maxBulkMoveEntries)
+	}
+
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		if folderID != nil {
+			if _, err := findVaultFolder(tx, vault.ID, folderID.String()); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&models.Service{}).
+			Where("vault_id = ? AND id IN ?", vault.ID, serviceIDs).
+			Update("folder_id", folderID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to move entries: %w", result.Error)
+		}
+		moved = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+func vaultFolders(db *gorm.DB, vaultID uuid.UUID) ([]models.Folder, error) {
+	var folders []models.Folder
+	if err := db.Where("vault_id = ?", vaultID).Order("name").Find(&folders).Error; err != nil {
+		return nil, fmt.Errorf("failed to load folders: %w", err)
+	}
+	return folders, nil
+}
+
+func findVaultFolder(db *gorm.DB, vaultID uuid.UUID, folderID string) (*models.Folder, error) {
+	parsedFolderID, err := uuid.Parse(folderID)
+	if err != nil {
+		return nil, ErrFolderNotFound
+	}
+
+	var folder models.Folder
+	err = db.Where("id = ? AND vault_id = ?", parsedFolderID, vaultID).First(&folder).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load folder: %w", err)
+	}
+	return &folder, nil
+}
+
+// applyFolderInput validates a name and parent and sets them on the folder. The
+// parent must be in the same vault, must not be the folder or one of its
+// descendants, and the result must not nest deeper than maxFolderDepth.
+func applyFolderInput(tx *gorm.DB, folder *models.Folder, input FolderInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxFolderNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidFolder, maxFolderNameLength)
+	}
+
+	folders, err := vaultFolders(tx, folder.VaultID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]models.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+
+	depth := 1
+	if input.ParentID != nil {
+		if _, ok := byID[*input.ParentID]; !ok {
+			return ErrFolderNotFound
+		}
+		for id := input.ParentID; id != nil; id = byID[*id].ParentID {
+			if *id == folder.ID {
+				return fmt.Errorf("%w: a folder can't be moved into itself", ErrInvalidFolder)
+			}
+			depth++
+		}
+	}
+	if depth+subtreeHeight(folders, folder.ID)-1 > maxFolderDepth {
+		return fmt.Errorf("%w: folders can be nested at most %d levels deep", ErrInvalidFolder, maxFolderDepth)
+	}
+
+	for _, f := range folders {
+		if f.ID != folder.ID && f.Name == name && sameParent(f.ParentID, input.ParentID) {
+			return fmt.Errorf("%w: a folder named %q already exists here", ErrInvalidFolder, name)
+		}
+	}
+
+	folder.Name = name
+	folder.ParentID = input.ParentID
+	return nil
+}
+
+// subtreeHeight is the number of levels in the subtree rooted at a folder
+// (1 for a folder without subfolders)
+func subtreeHeight(folders []models.Folder, rootID uuid.UUID) int {
+	height := 1
+	for _, f := range folders {
+		if f.ParentID != nil && *f.ParentID == rootID {
+			if h := subtreeHeight(folders, f.ID) + 1; h > height {
+				height = h
+			}
+		}
+	}
+	return height
+}
+
+// folderSubtree returns a folder's ID with the IDs of all folders below it
+func folderSubtree(folders []models.Folder, rootID uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{rootID}
+	for _, f := range folders {
+		if f.ParentID != nil && *f.ParentID == rootID {
+			ids = append(ids, folderSubtree(folders, f.ID)...)
+		}
+	}
+	return ids
+}
+
+func sameParent(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Background jobs take a Redis lock so only one instance runs each of them. The
+// lock holds a value unique to the run, and it is only refreshed or released
+// while it still holds that value: a run that outlived its TTL must not extend
+// or free a lock another instance has taken since.
+var (
+	releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	refreshLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// acquireJobLock takes the lock under key with the run's value, reporting
+// whether it was free
+func acquireJobLock(ctx context.Context, rdb *redis.Client, key, value string, ttl time.Duration) (bool, error) {
+	return rdb.SetNX(ctx, key, value, ttl).Result()
+}
+
+// refreshJobLock extends the lock's TTL if the run still holds it
+func refreshJobLock(ctx context.Context, rdb *redis.Client, key, value string, ttl time.Duration) (bool, error) {
+	n, err := refreshLockScript.Run(ctx, rdb, []string{key}, value, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// releaseJobLock deletes the lock if the run still holds it
+func releaseJobLock(ctx context.Context, rdb *redis.Client, key, value string) error {
+	return releaseLockScript.Run(ctx, rdb, []string{key}, value).Err()
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+const (
+	keyRotationBatchSize = 100
+	keyRotationLockKey   = "lock:key_rotation"
+	keyRotationLockTTL   = 2 * time.Minute
+)
+
+var ErrKeyRotationInProgress = errors.New("key rotation already in progress")
+
+// StartKeyRotation re-wraps every vault key that is not under a per-user subkey of the
+// active master key, which also moves keys wrapped with a master key directly.
+// A running job for the same target key is resumed instead of starting a new one;
+// running jobs for any other target are cancelled. The rotation lock is taken
+// before a job row is written, with the job's ID as its value, so an instance
+// that loses the race leaves nothing behind. When another rotation holds the
+// lock, its job is returned with ErrKeyRotationInProgress if it can be loaded.
+func StartKeyRotation(repo storage.Repository) (*models.KeyRotationJob, error) {
+	ring, err := crypto.LoadKeyRing()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	activeID, _ := ring.Active()
+
+	var job models.KeyRotationJob
+	err = repo.DB.Where("status = ? AND target_key_id = ?", models.KeyRotationRunning, activeID).
+		Order("started_at DESC").First(&job).Error
+	resume := err == nil
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		job = models.KeyRotationJob{
+			ID:          uuid.New(),
+			TargetKeyID: activeID,
+			Status:      models.KeyRotationRunning,
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load rotation job: %w", err)
+	}
+
+	ctx := context.Background()
+	ok, err := acquireJobLock(ctx, repo.RedisClient, keyRotationLockKey, job.ID.String(), keyRotationLockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire rotation lock: %w", err)
+	}
+	if !ok {
+		return lockedKeyRotation(repo), ErrKeyRotationInProgress
+	}
+
+	if !resume {
+		if err := createKeyRotationJob(repo.DB, &job); err != nil {
+			if err := releaseJobLock(ctx, repo.RedisClient, keyRotationLockKey, job.ID.String()); err != nil {
+				log.Printf(" Failed to release rotation lock: %v\n", err)
+			}
+			return nil, fmt.Errorf("failed to create rotation job: %w", err)
+		}
+	}
+
+	go runKeyRotation(repo, ring, job)
+
+	return &job, nil
+}
+
+// createKeyRotationJob saves a new job. It re-wraps everything an older
+// target's job had left, so those jobs are closed rather than resumed.
+func createKeyRotationJob(db *gorm.DB, job *models.KeyRotationJob) error {
+	if err := staleVaultsQuery(db, job.TargetKeyID).Count(&job.Total).Error; err != nil {
+		return fmt.Errorf("failed to count vaults: %w", err)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.KeyRotationJob{}).
+			Where("status = ? AND target_key_id <> ?", models.KeyRotationRunning, job.TargetKeyID).
+			Updates(map[string]interface{}{
+				"status":      models.KeyRotationCancelled,
+				"error":       "superseded by rotation to " + job.TargetKeyID,
+				"finished_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(job).Error
+	})
+}
+
+// lockedKeyRotation loads the job named by the rotation lock, or returns nil if
+// the lock is gone or its job isn't saved yet
+func lockedKeyRotation(repo storage.Repository) *models.KeyRotationJob {
+	holder, err := repo.RedisClient.Get(context.Background(), keyRotationLockKey).Result()
+	if err != nil {
+		return nil
+	}
+	id, err := uuid.Parse(holder)
+	if err != nil {
+		return nil
+	}
+	var job models.KeyRotationJob
+	if err := repo.DB.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil
+	}
+	return &job
+}
+
+// ResumeKeyRotation restarts an interrupted rotation job, if there is one
+func ResumeKeyRotation(repo storage.Repository) {
+	var count int64
+	if err := repo.DB.Model(&models.KeyRotationJob{}).
+		Where("status = ?", models.KeyRotationRunning).Count(&count).Error; err != nil || count == 0 {
+		return
+	}
+
+	job, err := StartKeyRotation(repo)
+	if errors.Is(err, ErrKeyRotationInProgress) {
+		// Another instance holds the lock and carries on with its job
+		return
+	}
+	if err != nil {
+		log.Printf(" Failed to resume key rotation: %v\n", err)
+		return
+	}
+	log.Printf(" Resuming key rotation job %s (%d/%d)\n", job.ID, job.Processed, job.Total)
+}
+
+// LatestKeyRotation returns the most recently started rotation job
+func LatestKeyRotation(repo storage.Repository) (*models.KeyRotationJob, error) {
+	var job models.KeyRotationJob
+	if err := repo.DB.Order("started_at DESC").First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// staleVaultsQuery selects vault keys not wrapped with a per-user subkey of the active master key
+func staleVaultsQuery(db *gorm.DB, activeID string) *gorm.DB {
+	return db.Model(&models.Vault{}).
+		Where("wrapped_key <> '' AND COALESCE(master_key_id, '') <> ?", crypto.DerivedKeyID(activeID))
+}
+
+func runKeyRotation(repo storage.Repository, ring *crypto.KeyRing, job models.KeyRotationJob) {
+	ctx := context.Background()
+	lockValue := job.ID.String()
+	defer func() {
+		if err := releaseJobLock(ctx, repo.RedisClient, keyRotationLockKey, lockValue); err != nil {
+			log.Printf(" Failed to release rotation lock: %v\n", err)
+		}
+	}()
+
+	activeID, _ := ring.Active()
+	log.Printf(" Key rotation job %s started (target %s)\n", job.ID, activeID)
+
+	for {
+		query := staleVaultsQuery(repo.DB, activeID).Order("id").Limit(keyRotationBatchSize)
+		if job.LastVaultID != nil {
+			query = query.Where("id > ?", *job.LastVaultID)
+		}
+
+		var batch []models.Vault
+		if err := query.Find(&batch).Error; err != nil {
+			finishKeyRotation(repo, &job, fmt.Errorf("failed to load vaults: %w", err))
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, vault := range batch {
+			if err := rewrapVaultKey(repo.DB, ring, vault.ID); err != nil {
+				log.Printf(" Failed to rotate key of vault %s: %v\n", vault.ID, err)
+				job.Failed++
+			} else {
+				job.Processed++
+			}
+		}
+
+		lastID := batch[len(batch)-1].ID
+		job.LastVaultID = &lastID
+		if err := repo.DB.Model(&models.KeyRotationJob{}).Where("id = ?", job.ID).
+			Updates(map[string]interface{}{
+				"processed":     job.Processed,
+				"failed":        job.Failed,
+				"last_vault_id": lastID,
+			}).Error; err != nil {
+			log.Printf(" Failed to save rotation progress: %v\n", err)
+		}
+
+		if held, err := refreshJobLock(ctx, repo.RedisClient, keyRotationLockKey, lockValue, keyRotationLockTTL); err == nil && !held {
+			// The lock expired: take it back unless another instance took over the job
+			if ok, _ := acquireJobLock(ctx, repo.RedisClient, keyRotationLockKey, lockValue, keyRotationLockTTL); !ok {
+				log.Printf(" Key rotation job %s lost its lock, stopping\n", job.ID)
+				return
+			}
+		}
+		log.Printf(" Key rotation job %s: %d/%d vaults re-wrapped\n", job.ID, job.Processed, job.Total)
+	}
+
+	// TOTP secrets are sealed with the master key directly
+	if n, err := rotateTOTPSecrets(repo.DB, ring); err != nil {
+		finishKeyRotation(repo, &job, fmt.Errorf("failed to rotate TOTP secrets: %w", err))
+		return
+	} else if n > 0 {
+		log.Printf(" Key rotation job %s: %d TOTP secrets re-encrypted\n", job.ID, n)
+	}
+
+	if job.Failed > 0 {
+		finishKeyRotation(repo, &job, fmt.Errorf("%d vaults could not be re-wrapped", job.Failed))
+		return
+	}
+	finishKeyRotation(repo, &job, nil)
+}
+
+// rewrapVaultKey moves one vault key from its current master key to the active one,
+// binding it to the vault as associated data if it was wrapped without
+func rewrapVaultKey(db *gorm.DB, ring *crypto.KeyRing, vaultID uuid.UUID) error {
+	activeID, _ := ring.Active()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var vault models.Vault
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", vaultID).First(&vault).Error; err != nil {
+			return err
+		}
+		if vault.WrappedKey == "" || (vault.MasterKeyID == crypto.DerivedKeyID(activeID) && vault.KeyAADVersion == AADVersionBound) {
+			return nil
+		}
+
+		dataKey, err := unwrapVaultKey(ring, &vault)
+		if err != nil {
+			return err
+		}
+		vault.KeyAADVersion = AADVersionBound
+		wrapKeyID, wrapKey, err := ring.KeyFor(vault.UserID.String(), crypto.PurposeVaultKey)
+		if err != nil {
+			return err
+		}
+		wrapped, err := crypto.WrapKey(dataKey, wrapKeyID, wrapKey, vaultKeyAAD(&vault))
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Vault{}).Where("id = ?", vault.ID).
+			Updates(map[string]interface{}{
+				"wrapped_key":     wrapped,
+				"wrapped_key_iv":  "",
+				"master_key_id":   wrapKeyID,
+				"key_aad_version": AADVersionBound,
+			}).Error
+	})
+}
+
+func finishKeyRotation(repo storage.Repository, job *models.KeyRotationJob, jobErr error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.KeyRotationCompleted,
+		"finished_at": now,
+	}
+	if jobErr != nil {
+		updates["status"] = models.KeyRotationFailed
+		updates["error"] = jobErr.Error()
+		log.Printf(" Key rotation job %s failed: %v\n", job.ID, jobErr)
+	} else {
+		log.Printf(" Key rotation job %s completed: %d vaults re-wrapped\n", job.ID, job.Processed)
+	}
+
+	// A job cancelled while it ran stays cancelled
+	if err := repo.DB.Model(&models.KeyRotationJob{}).
+		Where("id = ? AND status = ?", job.ID, models.KeyRotationRunning).Updates(updates).Error; err != nil {
+		log.Printf(" Failed to save rotation job status: %v\n", err)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// EncryptLegacyNotes encrypts notes still stored as plaintext with their vault key.
+// It is safe to run repeatedly; rows are only touched while they have plaintext notes.
+func EncryptLegacyNotes(repo storage.Repository) error {
+	// Zero-knowledge envelopes used to live in the plaintext column; move them over as-is
+	var clientVaultIDs []uuid.UUID
+	if err := repo.DB.Unscoped().Model(&models.Service{}).
+		Where("client_encrypted = ? AND notes <> ''", true).
+		Distinct("vault_id").Pluck("vault_id", &clientVaultIDs).Error; err != nil {
+		return fmt.Errorf("failed to find client-encrypted notes: %w", err)
+	}
+	var moved int64
+	for _, vaultID := range clientVaultIDs {
+		err := updateVaultEntries(repo, vaultID, func(tx *gorm.DB) error {
+			result := tx.Unscoped().Model(&models.Service{}).
+				Where("vault_id = ? AND client_encrypted = ? AND notes <> ''", vaultID, true).
+				Updates(map[string]interface{}{
+					"encrypted_notes": gorm.Expr("notes"),
+					"notes_iv":        "",
+					"notes":           "",
+				})
+			moved += result.RowsAffected
+			return result.Error
+		})
+		if errors.Is(err, ErrIntegrityMismatch) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to move client-encrypted notes of vault %s: %w", vaultID, err)
+		}
+	}
+
+	var vaultIDs []uuid.UUID
+	if err := repo.DB.Unscoped().Model(&models.Service{}).
+		Where("client_encrypted = ? AND notes <> ''", false).
+		Distinct("vault_id").Pluck("vault_id", &vaultIDs).Error; err != nil {
+		return fmt.Errorf("failed to find plaintext notes: %w", err)
+	}
+
+	var encrypted int
+	for _, vaultID := range vaultIDs {
+		n, err := encryptVaultNotes(repo, vaultID)
+		if errors.Is(err, ErrIntegrityMismatch) {
+			// Flagged; encrypted once an admin has accepted the vault's state
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encrypt notes of vault %s: %w", vaultID, err)
+		}
+		encrypted += n
+	}
+
+	if moved > 0 || encrypted > 0 {
+		log.Printf("✅ Encrypted %d plaintext notes (%d client envelopes moved)\n", encrypted, moved)
+	}
+	return nil
+}
+
+func encryptVaultNotes(repo storage.Repository, vaultID uuid.UUID) (int, error) {
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return 0, err
+	}
+
+	key, err := VaultDataKey(repo, &vault)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = updateVaultEntries(repo, vaultID, func(tx *gorm.DB) error {
+		var plain []models.Service
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("vault_id = ? AND client_encrypted = ? AND notes <> ''", vaultID, false).
+			Find(&plain).Error; err != nil {
+			return err
+		}
+
+		for _, svc := range plain {
+			encryptedNotes, err := sealNotes(key, &svc, svc.Notes)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt notes of entry %s: %w", svc.ID, err)
+			}
+			if err := tx.Unscoped().Model(&models.Service{}).Where("id = ?", svc.ID).
+				Updates(map[string]interface{}{
+					"notes":           "",
+					"encrypted_notes": encryptedNotes,
+					"notes_iv":        "",
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		count = len(plain)
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Authenticator apps export accounts as QR codes holding
+//
+//	otpauth-migration://offline?data=<base64 protobuf MigrationPayload>
+//
+// The payload is small and fixed, so it is decoded by hand rather than through
+// generated protobuf code. Field numbers follow the app's migration.proto:
+//
+//	MigrationPayload { repeated OtpParameters otp_parameters = 1; ... }
+//	OtpParameters    { bytes secret = 1; string name = 2; string issuer = 3;
+//	                   Algorithm algorithm = 4; DigitCount digits = 5;
+//	                   OtpType type = 6; int64 counter = 7; }
+const maxMigrationAccounts = 100
+
+var errTruncatedProtobuf = errors.New("truncated protobuf")
+
+// ParseOTPMigration reads every account of an otpauth-migration:// payload
+func ParseOTPMigration(raw string) ([]*OTPConfig, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || !strings.EqualFold(u.Scheme, "otpauth-migration") {
+		return nil, fmt.Errorf("%w: expected an otpauth-migration:// URI", ErrInvalidOTP)
+	}
+
+	// Scanners often leave '+' unescaped, which the query parser reads as a space
+	data := strings.ReplaceAll(u.Query().Get("data"), " ", "+")
+	payload, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		payload, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+	if err != nil || len(payload) == 0 {
+		return nil, fmt.Errorf("%w: migration data is not base64", ErrInvalidOTP)
+	}
+
+	var configs []*OTPConfig
+	err = walkProtobuf(payload, func(field int, value []byte, _ uint64) error {
+		if field != 1 {
+			return nil
+		}
+		if len(configs) == maxMigrationAccounts {
+			return fmt.Errorf("more than %d accounts", maxMigrationAccounts)
+		}
+		cfg, err := parseMigrationAccount(value)
+		if err != nil {
+			return err
+		}
+		configs = append(configs, cfg)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed migration payload: %v", ErrInvalidOTP, err)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%w: migration payload holds no accounts", ErrInvalidOTP)
+	}
+	return configs, nil
+}
+
+func parseMigrationAccount(msg []byte) (*OTPConfig, error) {
+	cfg := &OTPConfig{}
+	var secret []byte
+	var name string
+	err := walkProtobuf(msg, func(field int, value []byte, n uint64) error {
+		switch field {
+		case 1:
+			secret = value
+		case 2:
+			name = string(value)
+		case 3:
+			cfg.Issuer = string(value)
+		case 4:
+			switch n {
+			case 0, 1:
+				cfg.Algorithm = "SHA1"
+			case 2:
+				cfg.Algorithm = "SHA256"
+			case 3:
+				cfg.Algorithm = "SHA512"
+			default:
+				return errors.New("unsupported algorithm")
+			}
+		case 5:
+			switch n {
+			case 0, 1:
+				cfg.Digits = 6
+			case 2:
+				cfg.Digits = 8
+			default:
+				return errors.New("unsupported digit count")
+			}
+		case 6:
+			switch n {
+			case 1:
+				cfg.Type = OTPTypeHOTP
+			case 0, 2:
+				cfg.Type = OTPTypeTOTP
+			default:
+				return errors.New("unsupported OTP type")
+			}
+		case 7:
+			cfg.Counter = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Type == "" {
+		cfg.Type = OTPTypeTOTP
+	}
+
+	// The name is the otpauth label: "issuer:account" or just "account"
+	if issuer, account, ok := strings.Cut(name, ":"); ok {
+		if cfg.Issuer == "" {
+			cfg.Issuer = strings.TrimSpace(issuer)
+		}
+		name = account
+	}
+	cfg.Account = strings.TrimSpace(name)
+	cfg.Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// walkProtobuf calls fn for each field of a protobuf message with the bytes of
+// length-delimited fields or the value of varint fields. Fixed-width fields
+// are skipped since the migration payload doesn't use them.
+func walkProtobuf(msg []byte, fn func(field int, value []byte, n uint64) error) error {
+	for len(msg) > 0 {
+		tag, rest, err := readVarint(msg)
+		if err != nil {
+			return err
+		}
+		msg = rest
+		field, wireType := int(tag>>3), tag&7
+
+		switch wireType {
+		case 0: // varint
+			n, rest, err := readVarint(msg)
+			if err != nil {
+				return err
+			}
+			msg = rest
+			if err := fn(field, nil, n); err != nil {
+				return err
+			}
+		case 1: // fixed64
+			if len(msg) < 8 {
+				return errTruncatedProtobuf
+			}
+			msg = msg[8:]
+		case 2: // length-delimited
+			length, rest, err := readVarint(msg)
+			if err != nil {
+				return err
+			}
+			if length > uint64(len(rest)) {
+				return errTruncatedProtobuf
+			}
+			msg = rest[length:]
+			if err := fn(field, rest[:length], 0); err != nil {
+				return err
+			}
+		case 5: // fixed32
+			if len(msg) < 4 {
+				return errTruncatedProtobuf
+			}
+			msg = msg[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", wireType)
+		}
+	}
+	return nil
+}
+
+func readVarint(b []byte) (uint64, []byte, error) {
+	var n uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		n |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i] < 0x80 {
+			return n, b[i+1:], nil
+		}
+	}
+	return 0, nil, errTruncatedProtobuf
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// fingerprintKeyLabel derives a vault's password fingerprint key from its data
+// key. The data key is wrapped by the master key, which never touches the
+// database, so it acts as the pepper: a database dump alone can't be used to
+// test guesses against fingerprints. Keys differ per vault, so equal passwords
+// of different users don't share a fingerprint either.
+const fingerprintKeyLabel = "password-fingerprint"
+
+var fingerprintBackfillOnce sync.Once
+
+// passwordFingerprint returns the keyed HMAC of a password that lets reused
+// passwords within a vault be found without decrypting them, or "" for no password
+func passwordFingerprint(key []byte, password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	fingerprintKey, err := crypto.DeriveKey(key, fingerprintKeyLabel)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive fingerprint key: %w", err)
+	}
+	mac := hmac.New(sha256.New, fingerprintKey)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ReusedPasswordGroups groups entries that share a password fingerprint. Groups
+// and the entries in them keep the order of entries; entries without a
+// fingerprint (zero-knowledge or not yet backfilled) are never grouped.
+func ReusedPasswordGroups(entries []models.Service) [][]models.Service {
+	byFingerprint := make(map[string][]models.Service)
+	var order []string
+	for _, svc := range entries {
+		if svc.PasswordFingerprint == "" {
+			continue
+		}
+		if _, ok := byFingerprint[svc.PasswordFingerprint]; !ok {
+			order = append(order, svc.PasswordFingerprint)
+		}
+		byFingerprint[svc.PasswordFingerprint] = append(byFingerprint[svc.PasswordFingerprint], svc)
+	}
+
+	var groups [][]models.Service
+	for _, fingerprint := range order {
+		if group := byFingerprint[fingerprint]; len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	// Most reused first
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i]) > len(groups[j]) })
+	return groups
+}
+
+// BackfillPasswordFingerprints fingerprints the passwords of server-encrypted
+// logins saved before fingerprints existed. A vault that can't be fingerprinted
+// is logged and skipped, and picked up again by the next backfill.
+func BackfillPasswordFingerprints(repo storage.Repository) (int, error) {
+	if crypto.IsSealed() {
+		return 0, crypto.ErrSealed
+	}
+
+	var vaultIDs []uuid.UUID
+	if err := repo.DB.Unscoped().Model(&models.Service{}).
+		Where("password_fingerprint = '' AND encrypted_password <> '' AND client_encrypted = ?", false).
+		Distinct("vault_id").Pluck("vault_id", &vaultIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find entries to fingerprint: %w", err)
+	}
+
+	var filled int
+	for _, vaultID := range vaultIDs {
+		n, err := backfillVaultFingerprints(repo, vaultID)
+		if errors.Is(err, crypto.ErrSealed) {
+			return filled, err
+		}
+		if err != nil {
+			log.Printf(" Fingerprint backfill skipped vault %s: %v\n", vaultID, err)
+			continue
+		}
+		filled += n
+	}
+	return filled, nil
+}
+
+func backfillVaultFingerprints(repo storage.Repository, vaultID uuid.UUID) (int, error) {
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return 0, err
+	}
+	if vault.ZeroKnowledge {
+		return 0, nil
+	}
+	key, err := VaultDataKey(repo, &vault)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		var missing []models.Service
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("vault_id = ? AND password_fingerprint = '' AND encrypted_password <> '' AND client_encrypted = ?", vaultID, false).
+			Find(&missing).Error; err != nil {
+			return err
+		}
+
+		for _, svc := range missing {
+			password, err := DecryptServicePassword(key, &svc)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt entry %s: %w", svc.ID, err)
+			}
+			fingerprint, err := passwordFingerprint(key, password)
+			if err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&models.Service{}).Where("id = ?", svc.ID).
+				UpdateColumn("password_fingerprint", fingerprint).Error; err != nil {
+				return err
+			}
+		}
+
+		count = len(missing)
+		return nil
+	})
+	return count, err
+}
+
+// StartFingerprintBackfill fingerprints existing passwords once in the
+// background. Calling it again is a no-op.
+func StartFingerprintBackfill(repo storage.Repository) {
+	fingerprintBackfillOnce.Do(func() {
+		go func() {
+			filled, err := BackfillPasswordFingerprints(repo)
+			if err != nil {
+				log.Printf(" Password fingerprint backfill failed: %v\n", err)
+			} else if filled > 0 {
+				log.Printf("✅ Fingerprinted the passwords of %d entries\n", filled)
+			}
+		}()
+	})
+}
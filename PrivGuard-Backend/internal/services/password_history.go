@@ -0,0 +1,274 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+const defaultPasswordHistoryDepth = 10
+
+var ErrHistoryNotFound = errors.New("password history version not found")
+
+// PasswordHistoryVersion is one revealed history entry. Server-encrypted versions
+// carry the plaintext Password; zero-knowledge ones the client's envelope.
+type PasswordHistoryVersion struct {
+	ID                uuid.UUID       `json:"id"`
+	ArchivedAt        time.Time       `json:"archived_at"`
+	Strength          int8            `json:"strength"`
+	Password          string          `json:"password,omitempty"`
+	EncryptedPassword *ClientEnvelope `json:"encrypted_password,omitempty"`
+}
+
+// PasswordHistoryDepth is how many previous passwords are kept per entry
+// (PASSWORD_HISTORY_DEPTH, default 10; 0 disables history)
+func PasswordHistoryDepth() int {
+	depth, err := strconv.Atoi(os.Getenv("PASSWORD_HISTORY_DEPTH"))
+	if err != nil || depth < 0 {
+		return defaultPasswordHistoryDepth
+	}
+	return depth
+}
+
+// archivePassword stores the current password of a (locked) entry in its history
+// and drops versions beyond the configured depth
+func archivePassword(tx *gorm.DB, svc *models.Service) error {
+	depth := PasswordHistoryDepth()
+	if depth == 0 || svc.EncryptedPassword == "" {
+		return nil
+	}
+
+	if err := tx.Create(&models.PasswordHistory{
+		ID:                uuid.New(),
+		ServiceID:         svc.ID,
+		VaultID:           svc.VaultID,
+		EncryptedPassword: svc.EncryptedPassword,
+		IV:                svc.IV,
+		AADVersion:        svc.AADVersion,
+		ClientEncrypted:   svc.ClientEncrypted,
+		StrengthScore:     svc.StrengthScore,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to archive password: %w", err)
+	}
+
+	var expired []uuid.UUID
+	if err := tx.Model(&models.PasswordHistory{}).Where("service_id = ?", svc.ID).
+		Order("created_at DESC").Offset(depth).Pluck("id", &expired).Error; err != nil {
+		return fmt.Errorf("failed to trim password history: %w", err)
+	}
+	if len(expired) > 0 {
+		if err := tx.Where("id IN ?", expired).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return fmt.Errorf("failed to trim password history: %w", err)
+		}
+	}
+	return nil
+}
+
+// lockUserService loads and locks an entry of the given vault inside a transaction
+func lockUserService(tx *gorm.DB, vaultID, serviceID uuid.UUID) (*models.Service, error) {
+	var svc models.Service
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND vault_id = ?", serviceID, vaultID).First(&svc).Error; err != nil {
+		return nil, fmt.Errorf("failed to find service: %w", err)
+	}
+	return &svc, nil
+}
+
+// ListPasswordHistory returns the archived versions of an entry, newest first, without secrets
+func ListPasswordHistory(repo storage.Repository, userID, serviceID string) ([]models.PasswordHistory, error) {
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []models.PasswordHistory
+	if err := repo.DB.Select("id", "service_id", "client_encrypted", "strength_score", "created_at").
+		Where("service_id = ? AND vault_id = ?", parsedServiceID, vault.ID).
+		Order("created_at DESC").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to load password history: %w", err)
+	}
+	return history, nil
+}
+
+// RevealPasswordHistory returns one archived password of an entry
+func RevealPasswordHistory(repo storage.Repository, userID, serviceID, historyID string) (*PasswordHistoryVersion, error) {
+	if crypto.IsSealed() {
+		return nil, crypto.ErrSealed
+	}
+
+	vault, version, err := findHistoryVersion(repo.DB, userID, serviceID, historyID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &PasswordHistoryVersion{ID: version.ID, ArchivedAt: version.CreatedAt, Strength: version.StrengthScore}
+	if version.ClientEncrypted {
+		out.EncryptedPassword, _ = DecodeClientEnvelope(version.EncryptedPassword)
+		return out, nil
+	}
+
+	key, err := VaultDataKey(repo, vault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vault key: %w", err)
+	}
+	out.Password, err = decryptHistoryPassword(key, version)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestorePasswordHistory makes an archived password current again. The password
+// it replaces is archived, so a restore can itself be undone.
+func RestorePasswordHistory(repo storage.Repository, userID, serviceID, historyID string) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	vault, version, err := findHistoryVersion(repo.DB, userID, serviceID, historyID)
+	if err != nil {
+		return err
+	}
+	if version.ClientEncrypted != vault.ZeroKnowledge {
+		return fmt.Errorf("%w: version was encrypted in a different vault mode", ErrHistoryNotFound)
+	}
+
+	updates := map[string]interface{}{
+		"encrypted_password":   version.EncryptedPassword,
+		"iv":                   "",
+		"strength_score":       version.StrengthScore,
+		"strength_feedback":    "",
+		"strength_version":     0,
+		"password_fingerprint": "",
+		"breach_count":         0,
+		"breach_checked_at":    nil,
+		"updated_at":           time.Now(),
+	}
+	var key []byte
+	var plain string
+	if !version.ClientEncrypted {
+		// Re-encrypt rather than copy, so the restored password is bound to the
+		// row even if the archived ciphertext predates AAD binding
+		key, err = VaultDataKey(repo, vault)
+		if err != nil {
+			return fmt.Errorf("failed to load vault key: %w", err)
+		}
+		plain, err = decryptHistoryPassword(key, version)
+		if err != nil {
+			return err
+		}
+		encrypted, err := sealServiceField(key, vault.ID, version.ServiceID, FieldPassword, []byte(plain))
+		if err != nil {
+			return fmt.Errorf("encryption failed: %w", err)
+		}
+		updates["encrypted_password"] = encrypted
+		fingerprint, err := passwordFingerprint(key, plain)
+		if err != nil {
+			return err
+		}
+		updates["password_fingerprint"] = fingerprint
+		updates["breach_count"], updates["breach_checked_at"] = localBreachCheck(repo, plain)
+	}
+
+	// Server-encrypted versions are re-scored (scores archived before the server
+	// estimated them came from the client); do it before the row is locked
+	var snapshot models.Service
+	var estimate *passwordStrength
+	if !version.ClientEncrypted {
+		if err := repo.DB.Where("id = ? AND vault_id = ?", version.ServiceID, vault.ID).First(&snapshot).Error; err != nil {
+			return fmt.Errorf("failed to find service: %w", err)
+		}
+		if estimate, err = estimateEntryPassword(key, &snapshot, plain); err != nil {
+			return err
+		}
+	}
+
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		current, err := lockUserService(tx, vault.ID, version.ServiceID)
+		if err != nil {
+			return err
+		}
+		if !version.ClientEncrypted {
+			if err := bindLegacyRowUpdates(key, current, updates); err != nil {
+				return err
+			}
+			if !sameStrengthInputs(current, &snapshot) {
+				if estimate, err = estimateEntryPassword(key, current, plain); err != nil {
+					return err
+				}
+			}
+			for column, value := range estimate.updates() {
+				updates[column] = value
+			}
+		}
+		if err := tx.Where("id = ?", version.ID).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return fmt.Errorf("failed to remove restored version: %w", err)
+		}
+		if err := archivePassword(tx, current); err != nil {
+			return err
+		}
+		return tx.Model(&models.Service{}).Where("id = ?", current.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateVaultCache(repo, userID)
+	return nil
+}
+
+func findHistoryVersion(db *gorm.DB, userID, serviceID, historyID string) (*models.Vault, *models.PasswordHistory, error) {
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid service ID: %w", err)
+	}
+	parsedHistoryID, err := uuid.Parse(historyID)
+	if err != nil {
+		return nil, nil, ErrHistoryNotFound
+	}
+
+	vault, err := findUserVault(db, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var version models.PasswordHistory
+	err = db.Where("id = ? AND service_id = ? AND vault_id = ?", parsedHistoryID, parsedServiceID, vault.ID).
+		First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrHistoryNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load password history: %w", err)
+	}
+	return vault, &version, nil
+}
+
+// decryptHistoryPassword opens an archived ciphertext with the binding of the row it came from
+func decryptHistoryPassword(key []byte, version *models.PasswordHistory) (string, error) {
+	plain, err := DecryptServicePassword(key, &models.Service{
+		ID:                version.ServiceID,
+		VaultID:           version.VaultID,
+		EncryptedPassword: version.EncryptedPassword,
+		IV:                version.IV,
+		AADVersion:        version.AADVersion,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt archived password: %w", err)
+	}
+	return plain, nil
+}
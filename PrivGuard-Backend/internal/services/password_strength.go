@@ -0,0 +1,201 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/strength"
+)
+
+// FieldStrengthFeedback labels an entry's sealed strength feedback in its
+// associated data. The hints describe the password ("this is a date"), so they
+// are encrypted like the password itself.
+const FieldStrengthFeedback = "strength_feedback"
+
+var strengthRecomputeOnce sync.Once
+
+// passwordStrength is the server's estimate for a password, ready to be stored
+type passwordStrength struct {
+	Score             int8
+	EncryptedFeedback string
+}
+
+// estimatePasswordStrength scores a password against the entry's own details,
+// which an attacker targeting the account would try first, and seals the feedback
+func estimatePasswordStrength(key []byte, vaultID, serviceID uuid.UUID, password string, userInputs ...string) (*passwordStrength, error) {
+	result := strength.Estimate(password, userInputs...)
+	out := &passwordStrength{Score: result.Percent}
+	if result.Feedback.Warning == "" && len(result.Feedback.Suggestions) == 0 {
+		return out, nil
+	}
+
+	raw, err := json.Marshal(result.Feedback)
+	if err != nil {
+		return nil, err
+	}
+	out.EncryptedFeedback, err = sealServiceField(key, vaultID, serviceID, FieldStrengthFeedback, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt strength feedback: %w", err)
+	}
+	return out, nil
+}
+
+// updates returns the column updates that store the estimate
+func (s *passwordStrength) updates() map[string]interface{} {
+	return map[string]interface{}{
+		"strength_score":    s.Score,
+		"strength_feedback": s.EncryptedFeedback,
+		"strength_version":  strength.Version,
+	}
+}
+
+// DecryptStrengthFeedback returns the stored hints for a server-encrypted entry,
+// or nil if its password got no feedback
+func DecryptStrengthFeedback(key []byte, svc *models.Service) (*strength.Feedback, error) {
+	if svc.StrengthFeedback == "" {
+		return nil, nil
+	}
+	raw, err := crypto.Decrypt(svc.StrengthFeedback, crypto.SingleKey(crypto.DataKeyID, key),
+		crypto.RowAAD(svc.VaultID.String(), svc.ID.String(), FieldStrengthFeedback))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt strength feedback: %w", err)
+	}
+	var feedback strength.Feedback
+	if err := json.Unmarshal(raw, &feedback); err != nil {
+		return nil, fmt.Errorf("malformed strength feedback: %w", err)
+	}
+	return &feedback, nil
+}
+
+// clampClientStrength keeps a client-reported score on the 0-100 scale. Zero-
+// knowledge vaults are the only place the server still takes the client's word.
+func clampClientStrength(score int) int8 {
+	switch {
+	case score < 0:
+		return 0
+	case score > 100:
+		return 100
+	}
+	return int8(score)
+}
+
+// strengthInputs returns the entry details a password is checked against
+func strengthInputs(svc *models.Service, username string) []string {
+	return []string{svc.ServiceName, svc.ServiceDomain, username}
+}
+
+// estimateEntryPassword estimates a password for an entry, with the entry's
+// details as user inputs. Estimating long passwords takes a while, so callers
+// run it before locking the row and redo it only if sameStrengthInputs fails.
+func estimateEntryPassword(key []byte, svc *models.Service, password string) (*passwordStrength, error) {
+	username, err := decryptServiceUsername(key, svc)
+	if err != nil {
+		return nil, err
+	}
+	return estimatePasswordStrength(key, svc.VaultID, svc.ID, password, strengthInputs(svc, username)...)
+}
+
+// sameStrengthInputs reports whether an estimate made for one copy of a row still holds for another
+func sameStrengthInputs(a, b *models.Service) bool {
+	return a.ServiceName == b.ServiceName && a.ServiceDomain == b.ServiceDomain && a.EncryptedUsername == b.EncryptedUsername
+}
+
+// RecomputeStrengthScores re-scores the passwords of server-encrypted logins
+// last scored by an older estimator version (or taken from the client before
+// the server scored them). Zero-knowledge entries are skipped since the server
+// can't read them. Rows keep their updated_at: nothing the user edited changed.
+func RecomputeStrengthScores(repo storage.Repository) (int, error) {
+	if crypto.IsSealed() {
+		return 0, crypto.ErrSealed
+	}
+
+	var vaultIDs []uuid.UUID
+	if err := repo.DB.Unscoped().Model(&models.Service{}).
+		Where("strength_version < ? AND client_encrypted = ? AND item_type = ?", strength.Version, false, ItemLogin).
+		Distinct("vault_id").Pluck("vault_id", &vaultIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find entries to re-score: %w", err)
+	}
+
+	var rescored int
+	for _, vaultID := range vaultIDs {
+		n, err := recomputeVaultStrength(repo, vaultID)
+		if err != nil {
+			return rescored, fmt.Errorf("failed to re-score entries of vault %s: %w", vaultID, err)
+		}
+		rescored += n
+	}
+	return rescored, nil
+}
+
+func recomputeVaultStrength(repo storage.Repository, vaultID uuid.UUID) (int, error) {
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return 0, err
+	}
+	if vault.ZeroKnowledge {
+		return 0, nil
+	}
+	key, err := VaultDataKey(repo, &vault)
+	if err != nil {
+		return 0, err
+	}
+
+	var stale []models.Service
+	if err := repo.DB.Unscoped().
+		Where("vault_id = ? AND strength_version < ? AND client_encrypted = ? AND item_type = ?",
+			vaultID, strength.Version, false, ItemLogin).
+		Find(&stale).Error; err != nil {
+		return 0, err
+	}
+
+	// Rows aren't locked while they are estimated; an update only lands if the
+	// password and inputs it was estimated from are still there. Anything changed
+	// in the meantime was scored by the write that changed it.
+	var count int
+	for _, svc := range stale {
+		password, err := DecryptServicePassword(key, &svc)
+		if err != nil {
+			return count, fmt.Errorf("failed to decrypt entry %s: %w", svc.ID, err)
+		}
+		estimate, err := estimateEntryPassword(key, &svc, password)
+		if err != nil {
+			return count, fmt.Errorf("entry %s: %w", svc.ID, err)
+		}
+
+		result := repo.DB.Unscoped().Model(&models.Service{}).
+			Where("id = ? AND encrypted_password = ? AND service_name = ? AND service_domain = ? AND encrypted_username = ? AND strength_version < ?",
+				svc.ID, svc.EncryptedPassword, svc.ServiceName, svc.ServiceDomain, svc.EncryptedUsername, strength.Version).
+			UpdateColumns(estimate.updates())
+		if result.Error != nil {
+			return count, result.Error
+		}
+		count += int(result.RowsAffected)
+	}
+
+	if err == nil && count > 0 {
+		invalidateVaultCache(repo, vault.UserID.String())
+	}
+	return count, err
+}
+
+// StartStrengthRecompute re-scores stale entries once in the background, so
+// startup isn't held up by large vaults. Calling it again is a no-op.
+func StartStrengthRecompute(repo storage.Repository) {
+	strengthRecomputeOnce.Do(func() {
+		go func() {
+			rescored, err := RecomputeStrengthScores(repo)
+			if err != nil {
+				log.Printf(" Strength recompute failed: %v\n", err)
+			} else if rescored > 0 {
+				log.Printf("✅ Re-scored the passwords of %d entries\n", rescored)
+			}
+		}()
+	})
+}
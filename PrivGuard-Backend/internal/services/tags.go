@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrInvalidTag  = errors.New("invalid tag")
+)
+
+// Limits for tags
+const (
+	maxTagNameLength = 50
+	maxTagsPerEntry  = 50
+)
+
+// ListTags returns every tag of the user's vault with how many entries carry it
+func ListTags(repo storage.Repository, userID string) ([]models.Tag, map[uuid.UUID]int64, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tags []models.Tag
+	if err := repo.DB.Where("vault_id = ?", vault.ID).Order("name").Find(&tags).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+
+	var rows []struct {
+		TagID uuid.UUID
+		Count int64
+	}
+	if err := repo.DB.Table("service_tags").
+		Select("service_tags.tag_id, COUNT(*) AS count").
+		Joins("JOIN services ON services.id = service_tags.service_id AND services.deleted_at IS NULL").
+		Where("services.vault_id = ?", vault.ID).
+		Group("service_tags.tag_id").Scan(&rows).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to count tagged entries: %w", err)
+	}
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+
+	return tags, counts, nil
+}
+
+// CreateTag adds a tag to the user's vault
+func CreateTag(repo storage.Repository, userID, name string) (*models.Tag, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := models.Tag{ID: uuid.New(), VaultID: vault.ID}
+	if tag.Name, err = validTagName(repo.DB, vault.ID, uuid.Nil, name); err != nil {
+		return nil, err
+	}
+	if err := repo.DB.Create(&tag).Error; err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// RenameTag changes the name of a tag
+func RenameTag(repo storage.Repository, userID, tagID, name string) (*models.Tag, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := findVaultTag(repo.DB, vault.ID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name, err = validTagName(repo.DB, vault.ID, tag.ID, name); err != nil {
+		return nil, err
+	}
+	if err := repo.DB.Model(&models.Tag{}).Where("id = ?", tag.ID).Update("name", tag.Name).Error; err != nil {
+		return nil, fmt.Errorf("failed to rename tag: %w", err)
+	}
+	return tag, nil
+}
+
+// DeleteTag removes a tag from the vault and from every entry that had it
+func DeleteTag(repo storage.Repository, userID, tagID string) error {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+
+	return repo.DB.Transaction(func(tx *gorm.DB) error {
+		tag, err := findVaultTag(tx, vault.ID, tagID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM service_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return fmt.Errorf("failed to untag entries: %w", err)
+		}
+		if err := tx.Delete(&models.Tag{}, "id = ?", tag.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete tag: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetServiceTags replaces the tags of an entry
+func SetServiceTags(repo storage.Repository, userID, serviceID string, tagIDs []uuid.UUID) ([]models.Tag, error) {
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service ID: %w", err)
+	}
+	if len(tagIDs) > maxTagsPerEntry {
+		return nil, fmt.Errorf("%w: an entry can have at most %d tags", ErrInvalidTag, maxTagsPerEntry)
+	}
+
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []models.Tag{}
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		var service models.Service
+		if err := tx.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).First(&service).Error; err != nil {
+			return fmt.Errorf("failed to find service: %w", err)
+		}
+
+		if len(tagIDs) > 0 {
+			if err := tx.Where("vault_id = ? AND id IN ?", vault.ID, tagIDs).Order("name").Find(&tags).Error; err != nil {
+				return fmt.Errorf("failed to load tags: %w", err)
+			}
+			if len(tags) != len(uniqueIDs(tagIDs)) {
+				return ErrTagNotFound
+			}
+		}
+
+		if err := tx.Model(&service).Association("Tags").Replace(tags); err != nil {
+			return fmt.Errorf("failed to save tags: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func findVaultTag(db *gorm.DB, vaultID uuid.UUID, tagID string) (*models.Tag, error) {
+	parsedTagID, err := uuid.Parse(tagID)
+	if err != nil {
+		return nil, ErrTagNotFound
+	}
+
+	var tag models.Tag
+	err = db.Where("id = ? AND vault_id = ?", parsedTagID, vaultID).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTagNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tag: %w", err)
+	}
+	return &tag, nil
+}
+
+// validTagName trims a tag name and checks it is unique in the vault (ignoring the tag itself)
+func validTagName(db *gorm.DB, vaultID, tagID uuid.UUID, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTagNameLength {
+		return "", fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidTag, maxTagNameLength)
+	}
+
+	var count int64
+	if err := db.Model(&models.Tag{}).
+		Where("vault_id = ? AND name = ? AND id <> ?", vaultID, name, tagID).Count(&count).Error; err != nil {
+		return "", fmt.Errorf("failed to check tag name: %w", err)
+	}
+	if count > 0 {
+		return "", fmt.Errorf("%w: a tag named %q already exists", ErrInvalidTag, name)
+	}
+	return name, nil
+}
+
+func uniqueIDs(ids []uuid.UUID) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -1,22 +1,175 @@
 package services
 
 import (
-    "github.com/pquerna/otp/totp"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
 )
 
+// FieldTOTPSecret labels TOTP secrets in their associated data
+const FieldTOTPSecret = "totp_secret"
+
 // Generate a new TOTP secret + provisioning URI
 func GenerateTOTP(userID string) (secret, uri string, err error) {
-    key, err := totp.Generate(totp.GenerateOpts{
-        Issuer:      "PrivGuard",
-        AccountName: userID,
-    })
-    if err != nil {
-        return "", "", err
-    }
-    return key.Secret(), key.URL(), nil
-}
-
-// Verify a user‑supplied code
-func VerifyTOTP(secret, code string) bool {
-    return totp.Validate(code, secret)
-}
\ No newline at end of file
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      "PrivGuard",
+		AccountName: userID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return key.Secret(), key.URL(), nil
+}
+
+// IssueTOTPSecret generates a fresh secret for a user who hasn't confirmed TOTP yet,
+// stores it encrypted and returns the provisioning URI. The plaintext secret only
+// leaves the server inside this URI.
+func IssueTOTPSecret(repo storage.Repository, userID string) (string, error) {
+	secret, uri, err := GenerateTOTP(userID)
+	if err != nil {
+		return "", fmt.Errorf("TOTP generation failed: %w", err)
+	}
+
+	encrypted, keyID, err := sealTOTPSecret(userID, secret)
+	if err != nil {
+		return "", err
+	}
+
+	ts := models.TOTPSecret{
+		UserID:          userID,
+		EncryptedSecret: encrypted,
+		SecretKeyID:     keyID,
+		IsConfirmed:     false,
+		Enabled:         false,
+		CreatedAt:       time.Now(),
+	}
+	if err := repo.DB.Save(&ts).Error; err != nil {
+		return "", fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+
+	return uri, nil
+}
+
+// Verify a user‑supplied code against the stored secret, which is only decrypted here
+func VerifyTOTP(ts *models.TOTPSecret, code string) (bool, error) {
+	secret, err := openTOTPSecret(ts)
+	if err != nil {
+		return false, err
+	}
+	return totp.Validate(code, secret), nil
+}
+
+// totpSecretAAD binds a TOTP secret to the user it belongs to
+func totpSecretAAD(userID string) []byte {
+	return []byte("privguard:v1|user=" + userID + "|field=" + FieldTOTPSecret)
+}
+
+// sealTOTPSecret encrypts a secret with the user's subkey of the active master key
+func sealTOTPSecret(userID, secret string) (string, string, error) {
+	keyID, key, err := crypto.KeyFor(userID, crypto.PurposeTOTPSecret)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load encryption key: %w", err)
+	}
+
+	encrypted, err := crypto.Encrypt([]byte(secret), keyID, key, totpSecretAAD(userID))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt TOTP secret: %w", err)
+	}
+	return encrypted, keyID, nil
+}
+
+func openTOTPSecret(ts *models.TOTPSecret) (string, error) {
+	if ts.EncryptedSecret == "" {
+		if ts.Secret == "" {
+			return "", errors.New("TOTP secret missing")
+		}
+		// Not migrated yet
+		return ts.Secret, nil
+	}
+
+	ring, err := crypto.LoadKeyRing()
+	if err != nil {
+		return "", fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	resolve := crypto.UserKeyResolver(ring, ts.UserID, crypto.PurposeTOTPSecret)
+	secret, err := crypto.Decrypt(ts.EncryptedSecret, resolve, totpSecretAAD(ts.UserID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+// EncryptLegacyTOTPSecrets encrypts TOTP secrets still stored as plaintext.
+// It is safe to run repeatedly; rows are only touched while they have a plaintext secret.
+func EncryptLegacyTOTPSecrets(repo storage.Repository) error {
+	var legacy []models.TOTPSecret
+	if err := repo.DB.Where("secret <> ''").Find(&legacy).Error; err != nil {
+		return fmt.Errorf("failed to find plaintext TOTP secrets: %w", err)
+	}
+
+	for _, ts := range legacy {
+		encrypted, keyID, err := sealTOTPSecret(ts.UserID, ts.Secret)
+		if err != nil {
+			return err
+		}
+		if err := repo.DB.Model(&models.TOTPSecret{}).
+			Where("user_id = ? AND secret = ?", ts.UserID, ts.Secret).
+			Updates(map[string]interface{}{
+				"secret":           "",
+				"encrypted_secret": encrypted,
+				"secret_key_id":    keyID,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to save TOTP secret of %s: %w", ts.UserID, err)
+		}
+	}
+
+	if len(legacy) > 0 {
+		log.Printf("✅ Encrypted %d plaintext TOTP secrets\n", len(legacy))
+	}
+	return nil
+}
+
+// rotateTOTPSecrets re-encrypts TOTP secrets that are not under the active master key
+func rotateTOTPSecrets(db *gorm.DB, ring *crypto.KeyRing) (int, error) {
+	activeID, _ := ring.Active()
+
+	var stale []models.TOTPSecret
+	if err := db.Where("encrypted_secret <> '' AND secret_key_id <> ?", crypto.DerivedKeyID(activeID)).
+		Find(&stale).Error; err != nil {
+		return 0, err
+	}
+
+	for _, ts := range stale {
+		resolve := crypto.UserKeyResolver(ring, ts.UserID, crypto.PurposeTOTPSecret)
+		secret, err := crypto.Decrypt(ts.EncryptedSecret, resolve, totpSecretAAD(ts.UserID))
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt TOTP secret of %s: %w", ts.UserID, err)
+		}
+		keyID, key, err := ring.KeyFor(ts.UserID, crypto.PurposeTOTPSecret)
+		if err != nil {
+			return 0, err
+		}
+		encrypted, err := crypto.Encrypt(secret, keyID, key, totpSecretAAD(ts.UserID))
+		if err != nil {
+			return 0, err
+		}
+		if err := db.Model(&models.TOTPSecret{}).
+			Where("user_id = ? AND encrypted_secret = ?", ts.UserID, ts.EncryptedSecret).
+			Updates(map[string]interface{}{
+				"encrypted_secret": encrypted,
+				"secret_key_id":    keyID,
+			}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return len(stale), nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = time.Hour
+	trashPurgeLockKey         = "lock:trash_purge"
+	trashPurgeLockTTL         = 10 * time.Minute
+)
+
+var ErrNotInTrash = errors.New("entry is not in the trash")
+
+var trashPurgeOnce sync.Once
+
+// TrashRetention is how long deleted entries stay in the trash before they are
+// purged (TRASH_RETENTION_DAYS, default 30; 0 keeps them until the trash is emptied)
+func TrashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ListTrash returns the user's deleted entries, most recently deleted first
+func ListTrash(repo storage.Repository, userID string) ([]models.Service, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var trashed []models.Service
+	if err := repo.DB.Unscoped().
+		Where("vault_id = ? AND deleted_at IS NOT NULL", vault.ID).
+		Order("deleted_at DESC").Find(&trashed).Error; err != nil {
+		return nil, fmt.Errorf("failed to load trash: %w", err)
+	}
+	return trashed, nil
+}
+
+// RestoreFromTrash moves a deleted entry back into the vault
+func RestoreFromTrash(repo storage.Repository, userID, serviceID string) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.Service{}).
+			Where("id = ? AND vault_id = ? AND deleted_at IS NOT NULL", parsedServiceID, vault.ID).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return fmt.Errorf("failed to restore entry: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotInTrash
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateVaultCache(repo, userID)
+	return nil
+}
+
+// EmptyTrash permanently deletes every entry in the user's trash along with
+// their attachments
+func EmptyTrash(repo storage.Repository, userID string) (int64, error) {
+	if crypto.IsSealed() {
+		return 0, crypto.ErrSealed
+	}
+
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	blobKeys, err := trashedAttachmentKeys(repo.DB, vault.ID, "deleted_at IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+
+	var emptied int64
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		result := tx.Unscoped().
+			Where("vault_id = ? AND deleted_at IS NOT NULL", vault.ID).
+			Delete(&models.Service{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to empty trash: %w", result.Error)
+		}
+		emptied = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	deleteAttachmentBlobs(context.Background(), repo, blobKeys)
+
+	invalidateVaultCache(repo, userID)
+	return emptied, nil
+}
+
+// PurgeExpiredTrash permanently deletes entries, and their attachments, that
+// have been in the trash longer than the retention period
+func PurgeExpiredTrash(repo storage.Repository) (int, error) {
+	retention := TrashRetention()
+	if retention == 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-retention)
+
+	var vaultIDs []uuid.UUID
+	if err := repo.DB.Unscoped().Model(&models.Service{}).
+		Where("deleted_at < ?", cutoff).
+		Distinct("vault_id").Pluck("vault_id", &vaultIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired trash: %w", err)
+	}
+
+	var purged int
+	for _, vaultID := range vaultIDs {
+		blobKeys, err := trashedAttachmentKeys(repo.DB, vaultID, "deleted_at < ?", cutoff)
+		if err != nil {
+			return purged, err
+		}
+
+		var deleted int64
+		err = updateVaultEntries(repo, vaultID, func(tx *gorm.DB) error {
+			result := tx.Unscoped().
+				Where("vault_id = ? AND deleted_at < ?", vaultID, cutoff).
+				Delete(&models.Service{})
+			deleted = result.RowsAffected
+			return result.Error
+		})
+		if errors.Is(err, ErrIntegrityMismatch) {
+			// Flagged; its trash stays until an admin has looked at it
+			continue
+		}
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge trash of vault %s: %w", vaultID, err)
+		}
+		deleteAttachmentBlobs(context.Background(), repo, blobKeys)
+		purged += int(deleted)
+	}
+
+	return purged, nil
+}
+
+// StartTrashPurge purges expired trash now and then every hour. Runs are
+// skipped while the server is sealed or another instance is purging; calling
+// it again is a no-op.
+func StartTrashPurge(repo storage.Repository) {
+	trashPurgeOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(trashPurgeInterval)
+			defer ticker.Stop()
+
+			for {
+				if !crypto.IsSealed() {
+					runTrashPurge(repo)
+				}
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+func runTrashPurge(repo storage.Repository) {
+	ctx := context.Background()
+	lockValue := uuid.NewString()
+	ok, err := acquireJobLock(ctx, repo.RedisClient, trashPurgeLockKey, lockValue, trashPurgeLockTTL)
+	if err != nil {
+		log.Printf(" Failed to acquire trash purge lock: %v\n", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := releaseJobLock(ctx, repo.RedisClient, trashPurgeLockKey, lockValue); err != nil {
+			log.Printf(" Failed to release trash purge lock: %v\n", err)
+		}
+	}()
+
+	purged, err := PurgeExpiredTrash(repo)
+	if err != nil {
+		log.Printf(" Trash purge failed: %v\n", err)
+	} else if purged > 0 {
+		log.Printf("🗑️ Purged %d expired trash entries\n", purged)
+	}
+}
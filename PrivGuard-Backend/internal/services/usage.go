@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+const defaultStaleEntryDays = 180
+
+// RecordServiceUse notes that an entry was revealed. It writes the columns
+// directly so updated_at keeps meaning "last edited".
+func RecordServiceUse(repo storage.Repository, serviceID uuid.UUID) error {
+	if err := repo.DB.Model(&models.Service{}).Where("id = ?", serviceID).
+		UpdateColumns(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"reveal_count": gorm.Expr("reveal_count + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to record entry use: %w", err)
+	}
+	return nil
+}
+
+// SetServiceFavorite marks or unmarks an entry as a favorite
+func SetServiceFavorite(repo storage.Repository, userID, serviceID string, favorite bool) error {
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+
+	result := repo.DB.Model(&models.Service{}).
+		Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).
+		UpdateColumn("favorite", favorite)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update favorite: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to find service: %w", gorm.ErrRecordNotFound)
+	}
+
+	invalidateVaultCache(repo, userID)
+	return nil
+}
+
+// StaleEntryAge is how long an entry can go unrevealed before it is suggested
+// for cleanup (STALE_ENTRY_DAYS, default 180)
+func StaleEntryAge() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("STALE_ENTRY_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultStaleEntryDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// IsStaleEntry reports whether an entry hasn't been revealed within StaleEntryAge.
+// Entries that were never revealed count from when they were created.
+func IsStaleEntry(svc *models.Service, now time.Time) bool {
+	lastUsed := svc.CreatedAt
+	if svc.LastUsedAt != nil {
+		lastUsed = *svc.LastUsedAt
+	}
+	return now.Sub(lastUsed) > StaleEntryAge()
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+var ErrInvalidQuery = errors.New("invalid vault query")
+
+// Page sizes and search limits for the vault listing
+const (
+	DefaultEntryPageSize = 50
+	MaxEntryPageSize     = 200
+	maxSearchLength      = 100
+)
+
+// Sort keys for the vault listing and the columns behind them. Never-used
+// entries sort as used at the epoch so "used" has no NULLs to page over.
+var entrySortColumns = map[string]string{
+	"name":     "service_name",
+	"created":  "created_at",
+	"updated":  "updated_at",
+	"strength": "strength_score",
+	"used":     "COALESCE(last_used_at, 'epoch'::timestamptz)",
+}
+
+// Sort keys that list newest first unless an order is given
+var entrySortDefaultDesc = map[string]bool{
+	"created": true,
+	"updated": true,
+	"used":    true,
+}
+
+// EntryFilter narrows the vault listing. The zero value lists every entry.
+type EntryFilter struct {
+	FolderID  *uuid.UUID  // only entries in this folder
+	Unfiled   bool        // only entries outside any folder
+	Recursive bool        // with FolderID, also entries in its subfolders
+	TagIDs    []uuid.UUID // only entries carrying all of these tags
+	Search    string      // case-insensitive substring of the service name or domain
+	Favorites bool        // only favorite entries
+	Type      string      // only items of this type
+}
+
+// EntryPage selects one page of the listing. Sort is a key of entrySortColumns
+// (default "name"), Order is "asc", "desc" or empty for the sort's default, and
+// Cursor is the NextCursor of the previous page. Without a Limit or Cursor every
+// matching entry is returned in one page, as the listing did before paging.
+type EntryPage struct {
+	Sort           string
+	Order          string
+	FavoritesFirst bool
+	Cursor         string
+	Limit          int
+}
+
+// EntryList is one page of vault entries with the number of entries matching the filter
+type EntryList struct {
+	Entries    []models.Service
+	Total      int64
+	NextCursor string
+}
+
+// entryCursor marks the last entry of a page. It carries the sort so a cursor
+// can't be replayed against a different ordering.
+type entryCursor struct {
+	Sort           string    `json:"s"`
+	Desc           bool      `json:"d"`
+	FavoritesFirst bool      `json:"ff,omitempty"`
+	Favorite       bool      `json:"f,omitempty"`
+	Value          string    `json:"v"`
+	ID             uuid.UUID `json:"id"`
+}
+
+// ListVaultEntries returns one page of the vault's entries (not in the trash)
+// matching the filter, with their tags loaded. Pages are keyset-paginated on
+// (sort column, id), so they stay stable while entries are added.
+func ListVaultEntries(repo storage.Repository, vaultID uuid.UUID, filter EntryFilter, page EntryPage) (*EntryList, error) {
+	if page.Sort == "" {
+		page.Sort = "name"
+	}
+	column, ok := entrySortColumns[page.Sort]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, page.Sort)
+	}
+	var desc bool
+	switch page.Order {
+	case "":
+		desc = entrySortDefaultDesc[page.Sort]
+	case "asc":
+	case "desc":
+		desc = true
+	default:
+		return nil, fmt.Errorf("%w: order must be asc or desc", ErrInvalidQuery)
+	}
+	paged := page.Limit > 0 || page.Cursor != ""
+	if page.Limit <= 0 {
+		page.Limit = DefaultEntryPageSize
+	}
+	if page.Limit > MaxEntryPageSize {
+		page.Limit = MaxEntryPageSize
+	}
+
+	query, err := filteredEntries(repo.DB, vaultID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &EntryList{}
+	if err := query.Session(&gorm.Session{}).Model(&models.Service{}).Count(&list.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count vault entries: %w", err)
+	}
+
+	if page.Cursor != "" {
+		cursor, err := decodeEntryCursor(page.Cursor, page.Sort, desc, page.FavoritesFirst)
+		if err != nil {
+			return nil, err
+		}
+		value, err := cursorValue(page.Sort, cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		after := fmt.Sprintf("(%s, id) %s (?, ?)", column, op)
+		if page.FavoritesFirst {
+			// Favorites come first, so past the cursor is either later within the
+			// cursor's group or anywhere in the non-favorite group after it
+			query = query.Where("((favorite = ? AND "+after+") OR favorite < ?)",
+				cursor.Favorite, value, cursor.ID, cursor.Favorite)
+		} else {
+			query = query.Where(after, value, cursor.ID)
+		}
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	order := fmt.Sprintf("%s %s, id %s", column, direction, direction)
+	if page.FavoritesFirst {
+		order = "favorite DESC, " + order
+	}
+	query = query.Preload("Tags").Order(order)
+	if paged {
+		// Fetch one extra row to know whether there is a next page
+		query = query.Limit(page.Limit + 1)
+	}
+	if err := query.Find(&list.Entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load vault entries: %w", err)
+	}
+
+	if paged && len(list.Entries) > page.Limit {
+		list.Entries = list.Entries[:page.Limit]
+		last := list.Entries[page.Limit-1]
+		list.NextCursor = encodeEntryCursor(entryCursor{
+			Sort:           page.Sort,
+			Desc:           desc,
+			FavoritesFirst: page.FavoritesFirst,
+			Favorite:       last.Favorite,
+			Value:          sortValue(page.Sort, &last),
+			ID:             last.ID,
+		})
+	}
+
+	return list, nil
+}
+
+// filteredEntries builds the query for the vault's entries matching the filter
+func filteredEntries(db *gorm.DB, vaultID uuid.UUID, filter EntryFilter) (*gorm.DB, error) {
+	query := db.Where("vault_id = ?", vaultID)
+
+	switch {
+	case filter.Unfiled:
+		query = query.Where("folder_id IS NULL")
+	case filter.FolderID != nil && filter.Recursive:
+		folders, err := vaultFolders(db, vaultID)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("folder_id IN ?", folderSubtree(folders, *filter.FolderID))
+	case filter.FolderID != nil:
+		query = query.Where("folder_id = ?", *filter.FolderID)
+	}
+
+	if filter.Type != "" {
+		if !IsItemType(filter.Type) {
+			return nil, fmt.Errorf("%w: unknown item type %q", ErrInvalidQuery, filter.Type)
+		}
+		query = query.Where("item_type = ?", filter.Type)
+	}
+
+	if filter.Favorites {
+		query = query.Where("favorite = ?", true)
+	}
+
+	if tagIDs := uniqueIDs(filter.TagIDs); len(tagIDs) > 0 {
+		ids := make([]uuid.UUID, 0, len(tagIDs))
+		for id := range tagIDs {
+			ids = append(ids, id)
+		}
+		query = query.Where("id IN (?)", db.Table("service_tags").Select("service_id").
+			Where("tag_id IN ?", ids).Group("service_id").Having("COUNT(DISTINCT tag_id) = ?", len(ids)))
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		if len(search) > maxSearchLength {
+			return nil, fmt.Errorf("%w: search is longer than %d characters", ErrInvalidQuery, maxSearchLength)
+		}
+		pattern := "%" + likeEscaper.Replace(search) + "%"
+		query = query.Where("(service_name ILIKE ? OR service_domain ILIKE ?)", pattern, pattern)
+	}
+
+	return query, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func sortValue(sort string, svc *models.Service) string {
+	switch sort {
+	case "created":
+		return svc.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated":
+		return svc.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "strength":
+		return strconv.Itoa(int(svc.StrengthScore))
+	case "used":
+		if svc.LastUsedAt == nil {
+			return time.Unix(0, 0).UTC().Format(time.RFC3339Nano)
+		}
+		return svc.LastUsedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return svc.ServiceName
+	}
+}
+
+// cursorValue converts a cursor's sort value back to the column's type
+func cursorValue(sort, value string) (interface{}, error) {
+	switch sort {
+	case "created", "updated", "used":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+		}
+		return t, nil
+	case "strength":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+		}
+		return n, nil
+	default:
+		return value, nil
+	}
+}
+
+func encodeEntryCursor(cursor entryCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeEntryCursor(raw, sort string, desc, favoritesFirst bool) (*entryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var cursor entryCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if cursor.Sort != sort || cursor.Desc != desc || cursor.FavoritesFirst != favoritesFirst {
+		return nil, fmt.Errorf("%w: cursor belongs to a different sort order", ErrInvalidQuery)
+	}
+	return &cursor, nil
+}
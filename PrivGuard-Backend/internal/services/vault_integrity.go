@@ -0,0 +1,344 @@
+package services
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// integrityKeyLabel derives the vault's integrity key from its data key, so a
+// database operator without the master keys can't recompute a valid root
+const integrityKeyLabel = "vault-integrity"
+
+// IntegrityReport is the result of checking a vault's entries against its stored root
+type IntegrityReport struct {
+	VaultID     uuid.UUID `json:"vault_id"`
+	Counter     uint64    `json:"counter"`
+	Root        string    `json:"root"`
+	Entries     int       `json:"entries"`
+	Initialized bool      `json:"initialized"`
+	Valid       bool      `json:"valid"`
+	Rollback    bool      `json:"rollback"`
+	Problem     string    `json:"problem,omitempty"`
+	// Set when a write was refused because the entries didn't match the root
+	FlaggedAt *time.Time `json:"flagged_at,omitempty"`
+}
+
+// integrityLeaf encodes the fields of an entry the root covers: its ID, name,
+// domain, item type and data, username, password, notes, one-time password
+// seed, URIs, custom fields, encryption format and trash state. Each value is
+// length-prefixed so values can't be shifted between fields.
+//
+// The root does not cover, so rolling back or altering these goes undetected:
+//   - organisation: FolderID, Favorite, Tags and LogoURL
+//   - attachments and password history, which live in their own rows
+//   - columns derived from the password or kept up by background jobs:
+//     StrengthScore, StrengthFeedback, StrengthVersion, BreachCount,
+//     BreachCheckedAt and PasswordFingerprint
+//   - usage and timestamps: LastUsedAt, RevealCount, CreatedAt and UpdatedAt
+func integrityLeaf(svc *models.Service) []byte {
+	fields := []string{
+		svc.ID.String(),
+		svc.ServiceName,
+		svc.ServiceDomain,
+		svc.EncryptedUsername,
+		svc.EncryptedPassword,
+		svc.IV,
+		svc.Notes,
+		svc.EncryptedNotes,
+		svc.NotesIV,
+		fmt.Sprint(svc.AADVersion),
+		fmt.Sprint(svc.ClientEncrypted),
+	}
+
+	uris, customFields := sortedDetails(svc)
+	for _, uri := range uris {
+		fields = append(fields, "uri", uri.URI, uri.Match)
+	}
+	for _, field := range customFields {
+		fields = append(fields, "field", field.ID.String(), field.Name, field.Type, field.Value, field.EncryptedValue)
+	}
+	if svc.ItemType != "" && svc.ItemType != ItemLogin {
+		fields = append(fields, "item", svc.ItemType, svc.EncryptedData)
+	}
+	if svc.EncryptedOTP != "" {
+		fields = append(fields, "otp", svc.EncryptedOTP)
+	}
+	if svc.DeletedAt.Valid {
+		fields = append(fields, "trashed", fmt.Sprint(svc.DeletedAt.Time.UnixMicro()))
+	}
+
+	var leaf []byte
+	for _, field := range fields {
+		leaf = binary.BigEndian.AppendUint32(leaf, uint32(len(field)))
+		leaf = append(leaf, field...)
+	}
+	return leaf
+}
+
+// computeVaultRoot builds the keyed Merkle root over a vault's entries. The vault
+// ID and counter form the first leaf, so an old root can't be replayed as current.
+func computeVaultRoot(key []byte, vaultID uuid.UUID, counter uint64, entries []models.Service) string {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID.String() < entries[j].ID.String()
+	})
+
+	header := binary.BigEndian.AppendUint64([]byte("vault="+vaultID.String()+"|counter="), counter)
+	leaves := [][]byte{header}
+	for i := range entries {
+		leaves = append(leaves, integrityLeaf(&entries[i]))
+	}
+
+	return hex.EncodeToString(crypto.MerkleRoot(key, leaves))
+}
+
+func vaultIntegrityKey(repo storage.Repository, vault *models.Vault) ([]byte, error) {
+	dataKey, err := VaultDataKey(repo, vault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vault key: %w", err)
+	}
+	return crypto.DeriveKey(dataKey, integrityKeyLabel)
+}
+
+// ErrIntegrityMismatch is returned for writes to a vault whose entries no longer
+// match its stored root. Re-signing them would hide the change, so the write is
+// refused and the vault flagged; writes resume once an admin accepts its state.
+var ErrIntegrityMismatch = errors.New("vault entries do not match the integrity root")
+
+// updateVaultEntries runs write in a transaction holding the vault row lock. The
+// entries are checked against the stored root first; afterwards the root is
+// recomputed over them with the next counter, in the same transaction, so a
+// crash can't leave a committed write without its root. Vaults without a root
+// yet take whatever their entries are as the baseline. write may be nil.
+func updateVaultEntries(repo storage.Repository, vaultID uuid.UUID, write func(tx *gorm.DB) error) error {
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return fmt.Errorf("failed to load vault: %w", err)
+	}
+
+	// Outside the transaction: VaultDataKey may lock the vault row itself
+	key, err := vaultIntegrityKey(repo, &vault)
+	if err != nil {
+		return err
+	}
+
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Vault
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", vaultID).First(&locked).Error; err != nil {
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+
+		if locked.IntegrityCounter > 0 {
+			entries, err := loadIntegrityEntries(tx, vaultID)
+			if err != nil {
+				return err
+			}
+			expected := computeVaultRoot(key, vaultID, locked.IntegrityCounter, entries)
+			if !hmac.Equal([]byte(expected), []byte(locked.IntegrityRoot)) {
+				return ErrIntegrityMismatch
+			}
+		}
+
+		if write != nil {
+			if err := write(tx); err != nil {
+				return err
+			}
+		}
+
+		entries, err := loadIntegrityEntries(tx, vaultID)
+		if err != nil {
+			return err
+		}
+		counter := locked.IntegrityCounter + 1
+		if err := tx.Model(&models.Vault{}).Where("id = ?", vaultID).
+			Updates(map[string]interface{}{
+				"integrity_counter": counter,
+				"integrity_root":    computeVaultRoot(key, vaultID, counter, entries),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to update vault integrity: %w", err)
+		}
+		return nil
+	})
+
+	if errors.Is(err, ErrIntegrityMismatch) {
+		log.Printf("⚠️ Refused a write to vault %s: its entries do not match the integrity root\n", vaultID)
+		if flagErr := repo.DB.Model(&models.Vault{}).Where("id = ? AND integrity_flagged_at IS NULL", vaultID).
+			Update("integrity_flagged_at", time.Now()).Error; flagErr != nil {
+			log.Printf(" Failed to flag vault %s: %v\n", vaultID, flagErr)
+		}
+	}
+	return err
+}
+
+func loadIntegrityEntries(tx *gorm.DB, vaultID uuid.UUID) ([]models.Service, error) {
+	var entries []models.Service
+	if err := tx.Unscoped().Preload("URIs").Preload("Fields").Where("vault_id = ?", vaultID).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load vault entries: %w", err)
+	}
+	return entries, nil
+}
+
+// AcceptVaultIntegrity signs a flagged vault's current entries as the new baseline,
+// after an admin has checked them, and clears the flag so writes resume
+func AcceptVaultIntegrity(repo storage.Repository, userID string) (*IntegrityReport, error) {
+	if crypto.IsSealed() {
+		return nil, crypto.ErrSealed
+	}
+
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := vaultIntegrityKey(repo, vault)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Vault
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", vault.ID).First(&locked).Error; err != nil {
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+		entries, err := loadIntegrityEntries(tx, vault.ID)
+		if err != nil {
+			return err
+		}
+		counter := locked.IntegrityCounter + 1
+		return tx.Model(&models.Vault{}).Where("id = ?", vault.ID).
+			Updates(map[string]interface{}{
+				"integrity_counter":    counter,
+				"integrity_root":       computeVaultRoot(key, vault.ID, counter, entries),
+				"integrity_flagged_at": nil,
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept vault state: %w", err)
+	}
+	log.Printf(" Vault %s: current entries accepted as the integrity baseline\n", vault.ID)
+
+	if err := repo.DB.Where("id = ?", vault.ID).First(vault).Error; err != nil {
+		return nil, err
+	}
+	return VerifyVaultIntegrity(repo, vault, 0, "")
+}
+
+// VerifyVaultIntegrity recomputes a vault's root from its entries and compares it
+// with the stored one. If the client pinned a counter and root it saw earlier,
+// an older counter (or a different root for the same counter) is reported as rollback.
+func VerifyVaultIntegrity(repo storage.Repository, vault *models.Vault, pinnedCounter uint64, pinnedRoot string) (*IntegrityReport, error) {
+	if crypto.IsSealed() {
+		return nil, crypto.ErrSealed
+	}
+
+	report := &IntegrityReport{
+		VaultID:     vault.ID,
+		Counter:     vault.IntegrityCounter,
+		Root:        vault.IntegrityRoot,
+		Initialized: vault.IntegrityCounter > 0,
+		FlaggedAt:   vault.IntegrityFlaggedAt,
+	}
+
+	entries, err := loadIntegrityEntries(repo.DB, vault.ID)
+	if err != nil {
+		return nil, err
+	}
+	report.Entries = len(entries)
+
+	switch {
+	case pinnedCounter > vault.IntegrityCounter:
+		report.Rollback = true
+		report.Problem = fmt.Sprintf("vault counter %d is older than pinned counter %d", vault.IntegrityCounter, pinnedCounter)
+	case pinnedCounter > 0 && pinnedCounter == vault.IntegrityCounter && pinnedRoot != "" && pinnedRoot != vault.IntegrityRoot:
+		report.Rollback = true
+		report.Problem = "vault root differs from the pinned root for the same counter"
+	}
+
+	if !report.Initialized {
+		if report.Problem == "" {
+			report.Problem = "vault has no integrity root yet"
+		}
+		return report, nil
+	}
+
+	key, err := vaultIntegrityKey(repo, vault)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := computeVaultRoot(key, vault.ID, vault.IntegrityCounter, entries)
+	report.Valid = hmac.Equal([]byte(expected), []byte(vault.IntegrityRoot))
+	if !report.Valid && report.Problem == "" {
+		report.Problem = "entries do not match the stored root: rows were added, removed or modified outside the API"
+	}
+	if report.FlaggedAt != nil && report.Problem == "" {
+		report.Problem = "a write was refused because the entries did not match the stored root"
+	}
+
+	return report, nil
+}
+
+// VerifyUserVaultIntegrity checks the vault owned by a user
+func VerifyUserVaultIntegrity(repo storage.Repository, userID string, pinnedCounter uint64, pinnedRoot string) (*IntegrityReport, error) {
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyVaultIntegrity(repo, vault, pinnedCounter, pinnedRoot)
+}
+
+// VerifyAllVaultIntegrity checks every vault and returns the reports of those that failed
+func VerifyAllVaultIntegrity(repo storage.Repository) (int, []IntegrityReport, error) {
+	var vaults []models.Vault
+	if err := repo.DB.Find(&vaults).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to load vaults: %w", err)
+	}
+
+	var failed []IntegrityReport
+	for i := range vaults {
+		report, err := VerifyVaultIntegrity(repo, &vaults[i], 0, "")
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to verify vault %s: %w", vaults[i].ID, err)
+		}
+		if report.Initialized && (!report.Valid || report.FlaggedAt != nil) {
+			failed = append(failed, *report)
+		}
+	}
+
+	return len(vaults), failed, nil
+}
+
+// InitVaultIntegrity computes the first root for vaults that don't have one yet.
+// Whatever the entries are at this point is trusted as the baseline.
+func InitVaultIntegrity(repo storage.Repository) error {
+	var vaultIDs []uuid.UUID
+	if err := repo.DB.Model(&models.Vault{}).Where("integrity_counter = 0").
+		Pluck("id", &vaultIDs).Error; err != nil {
+		return fmt.Errorf("failed to find vaults without integrity root: %w", err)
+	}
+
+	for _, id := range vaultIDs {
+		if err := updateVaultEntries(repo, id, nil); err != nil {
+			return fmt.Errorf("failed to initialize integrity of vault %s: %w", id, err)
+		}
+	}
+
+	if len(vaultIDs) > 0 {
+		log.Printf("✅ Initialized integrity roots for %d vaults\n", len(vaultIDs))
+	}
+	return nil
+}
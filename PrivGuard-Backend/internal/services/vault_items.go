@@ -0,0 +1,409 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// Vault item types. Logins are the original website entries; every other type
+// keeps its fields in one encrypted JSON object (Service.EncryptedData).
+const (
+	ItemLogin    = "login"
+	ItemNote     = "note"
+	ItemCard     = "card"
+	ItemIdentity = "identity"
+	ItemAPIKey   = "api_key"
+	ItemWiFi     = "wifi"
+)
+
+// FieldItemData labels the typed item data in its associated data; it is always bound
+const FieldItemData = "item_data"
+
+const maxItemNameLength = 200
+
+// ItemField describes one field of an item type. Hidden fields should be masked by clients.
+type ItemField struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Hidden   bool   `json:"hidden"`
+	check    func(string) error
+}
+
+// ItemType is the schema of a vault item type
+type ItemType struct {
+	Type   string      `json:"type"`
+	Fields []ItemField `json:"fields"`
+}
+
+// ItemTypes lists the schemas of the typed items; secure notes keep their text in the notes field
+var ItemTypes = []ItemType{
+	{Type: ItemNote},
+	{Type: ItemCard, Fields: []ItemField{
+		{Name: "cardholder_name"},
+		{Name: "number", Required: true, Hidden: true, check: checkCardNumber},
+		{Name: "exp_month", check: checkIntRange(1, 12)},
+		{Name: "exp_year", check: checkIntRange(2000, 2999)},
+		{Name: "cvv", Hidden: true, check: checkDigits(3, 4)},
+		{Name: "brand"},
+	}},
+	{Type: ItemIdentity, Fields: []ItemField{
+		{Name: "title"},
+		{Name: "first_name"},
+		{Name: "middle_name"},
+		{Name: "last_name"},
+		{Name: "email", check: checkEmail},
+		{Name: "phone"},
+		{Name: "company"},
+		{Name: "address1"},
+		{Name: "address2"},
+		{Name: "city"},
+		{Name: "state"},
+		{Name: "postal_code"},
+		{Name: "country"},
+		{Name: "national_id", Hidden: true},
+		{Name: "passport_number", Hidden: true},
+		{Name: "license_number", Hidden: true},
+	}},
+	{Type: ItemAPIKey, Fields: []ItemField{
+		{Name: "key", Required: true, Hidden: true},
+		{Name: "secret", Hidden: true},
+		{Name: "endpoint", check: checkURL},
+		{Name: "expires_at", check: checkDate},
+	}},
+	{Type: ItemWiFi, Fields: []ItemField{
+		{Name: "ssid", Required: true, check: checkMaxLength(32)},
+		{Name: "password", Hidden: true, check: checkMaxLength(63)},
+		{Name: "security", check: checkOneOf("none", "wep", "wpa", "wpa2", "wpa3")},
+		{Name: "hidden", check: checkOneOf("true", "false")},
+	}},
+}
+
+// ItemInput is a typed item as sent by the client. Zero-knowledge vaults send
+// EncryptedData and EncryptedNotes instead of Data and Notes.
+type ItemInput struct {
+	Type           string            `json:"type"`
+	Name           string            `json:"name"`
+	Notes          string            `json:"notes"`
+	Data           map[string]string `json:"data"`
+	EncryptedData  *ClientEnvelope   `json:"encrypted_data"`
+	EncryptedNotes *ClientEnvelope   `json:"encrypted_notes"`
+}
+
+// IsItemType reports whether t is a known item type, logins included
+func IsItemType(t string) bool {
+	return t == ItemLogin || itemType(t) != nil
+}
+
+func itemType(t string) *ItemType {
+	for i := range ItemTypes {
+		if ItemTypes[i].Type == t {
+			return &ItemTypes[i]
+		}
+	}
+	return nil
+}
+
+// validate checks the item against its type's schema. Zero-knowledge data can't
+// be read, so only its shape is checked.
+func (in *ItemInput) validate(zeroKnowledge bool) error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || len(in.Name) > maxItemNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidEntry, maxItemNameLength)
+	}
+	schema := itemType(in.Type)
+	if schema == nil {
+		return fmt.Errorf("%w: unknown item type %q (logins are added through /vault/add)", ErrInvalidEntry, in.Type)
+	}
+
+	if zeroKnowledge {
+		if len(in.Data) > 0 || in.Notes != "" {
+			return ErrZeroKnowledgeEnabled
+		}
+		if len(schema.Fields) > 0 && in.EncryptedData == nil {
+			return fmt.Errorf("%w: encrypted_data is required", ErrInvalidEntry)
+		}
+		return nil
+	}
+	if in.EncryptedData != nil || in.EncryptedNotes != nil {
+		return ErrZeroKnowledgeDisabled
+	}
+
+	known := make(map[string]bool, len(schema.Fields))
+	for _, field := range schema.Fields {
+		known[field.Name] = true
+		value := strings.TrimSpace(in.Data[field.Name])
+		if value == "" {
+			if field.Required {
+				return fmt.Errorf("%w: %s is required", ErrInvalidEntry, field.Name)
+			}
+			delete(in.Data, field.Name)
+			continue
+		}
+		if len(value) > maxFieldValueLength {
+			return fmt.Errorf("%w: %s is too long", ErrInvalidEntry, field.Name)
+		}
+		if field.check != nil {
+			if err := field.check(value); err != nil {
+				return fmt.Errorf("%w: %s %v", ErrInvalidEntry, field.Name, err)
+			}
+		}
+		in.Data[field.Name] = value
+	}
+	for name := range in.Data {
+		if !known[name] {
+			return fmt.Errorf("%w: %s items have no field %q", ErrInvalidEntry, in.Type, name)
+		}
+	}
+	if in.Type == ItemIdentity && len(in.Data) == 0 {
+		return fmt.Errorf("%w: an identity needs at least one field", ErrInvalidEntry)
+	}
+	return nil
+}
+
+// sealItemData encrypts the item's fields (or encodes the client's envelope)
+func sealItemData(key []byte, vaultID, serviceID uuid.UUID, in *ItemInput, clientEncrypted bool) (string, error) {
+	if clientEncrypted {
+		return encodeOptionalEnvelope(in.EncryptedData)
+	}
+	if len(in.Data) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(in.Data)
+	if err != nil {
+		return "", err
+	}
+	return sealServiceField(key, vaultID, serviceID, FieldItemData, data)
+}
+
+// DecryptItemData returns the fields of a server-encrypted typed item
+func DecryptItemData(key []byte, svc *models.Service) (map[string]string, error) {
+	data := map[string]string{}
+	if svc.EncryptedData == "" {
+		return data, nil
+	}
+	plain, err := crypto.Decrypt(svc.EncryptedData, crypto.SingleKey(crypto.DataKeyID, key),
+		crypto.RowAAD(svc.VaultID.String(), svc.ID.String(), FieldItemData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt item data: %w", err)
+	}
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode item data: %w", err)
+	}
+	return data, nil
+}
+
+// AddVaultItem stores a typed item (anything but a login) and returns its ID
+func AddVaultItem(repo storage.Repository, userID string, in ItemInput) (uuid.UUID, error) {
+	if crypto.IsSealed() {
+		return uuid.Nil, crypto.ErrSealed
+	}
+
+	vaultID, err := GetOrCreateVault(repo, userID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find/create vault: %w", err)
+	}
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return uuid.Nil, fmt.Errorf("failed to load vault: %w", err)
+	}
+	if err := in.validate(vault.ZeroKnowledge); err != nil {
+		return uuid.Nil, err
+	}
+
+	service := models.Service{
+		ID:              uuid.New(),
+		VaultID:         vault.ID,
+		ItemType:        in.Type,
+		ServiceName:     in.Name,
+		AADVersion:      AADVersionBound,
+		ClientEncrypted: vault.ZeroKnowledge,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	var key []byte
+	if vault.ZeroKnowledge {
+		service.AADVersion = AADVersionLegacy
+		if service.EncryptedNotes, err = encodeOptionalEnvelope(in.EncryptedNotes); err != nil {
+			return uuid.Nil, err
+		}
+	} else {
+		if key, err = VaultDataKey(repo, &vault); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to load vault key: %w", err)
+		}
+		if service.EncryptedNotes, err = sealNotes(key, &service, in.Notes); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to encrypt notes: %w", err)
+		}
+	}
+	if service.EncryptedData, err = sealItemData(key, vault.ID, service.ID, &in, vault.ZeroKnowledge); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to encrypt item data: %w", err)
+	}
+
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		return tx.Create(&service).Error
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to save item: %w", err)
+	}
+
+	invalidateVaultCache(repo, userID)
+	return service.ID, nil
+}
+
+// UpdateVaultItem replaces the name and fields of a typed item. Its type can't
+// change, and notes are updated through the notes endpoint like for logins.
+func UpdateVaultItem(repo storage.Repository, userID, serviceID string, in ItemInput) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return fmt.Errorf("invalid service ID: %w", err)
+	}
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+
+	var service models.Service
+	if err := repo.DB.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).First(&service).Error; err != nil {
+		return fmt.Errorf("failed to find service: %w", err)
+	}
+	if in.Type == "" {
+		in.Type = service.ItemType
+	}
+	if in.Type != service.ItemType {
+		return fmt.Errorf("%w: the type of an item can't be changed", ErrInvalidEntry)
+	}
+	if in.Notes != "" || in.EncryptedNotes != nil {
+		return fmt.Errorf("%w: notes are updated through update-note", ErrInvalidEntry)
+	}
+	if err := in.validate(vault.ZeroKnowledge); err != nil {
+		return err
+	}
+
+	var key []byte
+	if !vault.ZeroKnowledge {
+		if key, err = VaultDataKey(repo, vault); err != nil {
+			return fmt.Errorf("failed to load vault key: %w", err)
+		}
+	}
+	data, err := sealItemData(key, vault.ID, service.ID, &in, vault.ZeroKnowledge)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt item data: %w", err)
+	}
+
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		return tx.Model(&models.Service{}).Where("id = ?", service.ID).
+			Updates(map[string]interface{}{
+				"service_name":   in.Name,
+				"encrypted_data": data,
+				"updated_at":     time.Now(),
+			}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+
+	invalidateVaultCache(repo, userID)
+	return nil
+}
+
+func checkCardNumber(value string) error {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(value)
+	if len(digits) < 12 || len(digits) > 19 {
+		return fmt.Errorf("must have 12 to 19 digits")
+	}
+	// Luhn checksum
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if d < 0 || d > 9 {
+			return fmt.Errorf("must contain only digits")
+		}
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	if sum%10 != 0 {
+		return fmt.Errorf("is not a valid card number")
+	}
+	return nil
+}
+
+func checkIntRange(min, max int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("must be a number from %d to %d", min, max)
+		}
+		return nil
+	}
+}
+
+func checkDigits(min, max int) func(string) error {
+	return func(value string) error {
+		if len(value) < min || len(value) > max || strings.Trim(value, "0123456789") != "" {
+			return fmt.Errorf("must be %d to %d digits", min, max)
+		}
+		return nil
+	}
+}
+
+func checkMaxLength(max int) func(string) error {
+	return func(value string) error {
+		if len(value) > max {
+			return fmt.Errorf("must be at most %d characters", max)
+		}
+		return nil
+	}
+}
+
+func checkOneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func checkEmail(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("is not a valid email address")
+	}
+	return nil
+}
+
+func checkURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be an absolute URL")
+	}
+	return nil
+}
+
+func checkDate(value string) error {
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return fmt.Errorf("must be a date (YYYY-MM-DD)")
+	}
+	return nil
+}
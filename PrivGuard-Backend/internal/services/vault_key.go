@@ -0,0 +1,243 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+// AAD versions recorded on encrypted rows
+const (
+	AADVersionLegacy = 0 // encrypted without associated data
+	AADVersionBound  = 1 // bound to vault ID, row ID and field label
+)
+
+// ErrLegacyCiphertext is returned for rows marked as encrypted without associated
+// data once legacy reads are switched off
+var ErrLegacyCiphertext = errors.New("ciphertext without associated data rejected")
+
+// legacyReadsRejected reports whether LEGACY_CIPHERTEXTS is "reject". Set it once
+// BindLegacyCiphertexts has bound every row: the AAD version is read from the row
+// itself, so otherwise resetting it to 0 in the database downgrades decryption
+// to no associated data.
+func legacyReadsRejected() bool {
+	return os.Getenv("LEGACY_CIPHERTEXTS") == "reject"
+}
+
+// Field labels bound into the associated data of encrypted columns
+const (
+	FieldPassword = "password"
+	FieldNotes    = "notes"
+	FieldVaultKey = "vault_key"
+
+	// Fields added after AAD binding existed are always bound, whatever the row's AAD version
+	FieldUsername    = "username"
+	FieldCustomValue = "custom_field"
+)
+
+// ServiceFieldAAD returns the associated data for an encrypted field of a service,
+// or nil for rows written before ciphertexts were bound to their row.
+func ServiceFieldAAD(svc *models.Service, field string) []byte {
+	if svc.AADVersion == AADVersionLegacy {
+		return nil
+	}
+	return crypto.RowAAD(svc.VaultID.String(), svc.ID.String(), field)
+}
+
+// sealServiceField encrypts a service field bound to its vault, row and field label
+func sealServiceField(key []byte, vaultID, serviceID uuid.UUID, field string, plaintext []byte) (string, error) {
+	return crypto.Encrypt(plaintext, crypto.DataKeyID, key, crypto.RowAAD(vaultID.String(), serviceID.String(), field))
+}
+
+// sealNotes encrypts notes for a service, or returns an empty value for empty notes
+func sealNotes(key []byte, svc *models.Service, notes string) (string, error) {
+	if notes == "" {
+		return "", nil
+	}
+	return crypto.Encrypt([]byte(notes), crypto.DataKeyID, key, ServiceFieldAAD(svc, FieldNotes))
+}
+
+// openServiceField decrypts a service field stored either as an envelope or
+// in the legacy ciphertext + IV column layout
+func openServiceField(key []byte, svc *models.Service, field, value, iv string) (string, error) {
+	if svc.AADVersion == AADVersionLegacy && legacyReadsRejected() {
+		return "", fmt.Errorf("entry %s: %w", svc.ID, ErrLegacyCiphertext)
+	}
+	plain, err := crypto.Decrypt(crypto.EnvelopeFromColumns(value, iv, crypto.DataKeyID),
+		crypto.SingleKey(crypto.DataKeyID, key), ServiceFieldAAD(svc, field))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// DecryptServicePassword returns the plaintext password of a server-encrypted service
+// (empty for items other than logins)
+func DecryptServicePassword(key []byte, svc *models.Service) (string, error) {
+	if svc.EncryptedPassword == "" {
+		return "", nil
+	}
+	return openServiceField(key, svc, FieldPassword, svc.EncryptedPassword, svc.IV)
+}
+
+// bindLegacyRowUpdates adds the column updates that re-encrypt a legacy row's notes
+// with bound AAD, for writes that mark the whole row as AADVersionBound
+func bindLegacyRowUpdates(key []byte, svc *models.Service, updates map[string]interface{}) error {
+	updates["aad_version"] = AADVersionBound
+	if svc.AADVersion != AADVersionLegacy || svc.ClientEncrypted {
+		return nil
+	}
+
+	notes, err := DecryptServiceNotes(key, svc)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt notes of entry %s: %w", svc.ID, err)
+	}
+	bound := *svc
+	bound.AADVersion = AADVersionBound
+	encryptedNotes, err := sealNotes(key, &bound, notes)
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt notes of entry %s: %w", svc.ID, err)
+	}
+
+	updates["notes"] = ""
+	updates["encrypted_notes"] = encryptedNotes
+	updates["notes_iv"] = ""
+	return nil
+}
+
+// DecryptServiceNotes returns the plaintext notes of a server-encrypted service,
+// falling back to legacy plaintext notes that have not been migrated yet
+func DecryptServiceNotes(key []byte, svc *models.Service) (string, error) {
+	if svc.EncryptedNotes == "" {
+		return svc.Notes, nil
+	}
+	return openServiceField(key, svc, FieldNotes, svc.EncryptedNotes, svc.NotesIV)
+}
+
+func vaultKeyAAD(vault *models.Vault) []byte {
+	if vault.KeyAADVersion == AADVersionLegacy {
+		return nil
+	}
+	return crypto.RowAAD(vault.ID.String(), vault.ID.String(), FieldVaultKey)
+}
+
+// VaultDataKey returns the unwrapped data-encryption key of a vault.
+// Vaults created before envelope encryption get a key on first use, and
+// their existing entries are re-encrypted from the master key to it.
+func VaultDataKey(repo storage.Repository, vault *models.Vault) ([]byte, error) {
+	ring, err := crypto.LoadKeyRing()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+
+	if vault.WrappedKey != "" {
+		return unwrapVaultKey(ring, vault)
+	}
+
+	wrapKeyID, wrapKey, err := ring.KeyFor(vault.UserID.String(), crypto.PurposeVaultKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault wrapping key: %w", err)
+	}
+
+	var dataKey []byte
+	err = repo.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the vault row so concurrent requests don't generate two keys
+		var locked models.Vault
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", vault.ID).First(&locked).Error; err != nil {
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+
+		// Another request created the key while we were waiting
+		if locked.WrappedKey != "" {
+			key, err := unwrapVaultKey(ring, &locked)
+			if err != nil {
+				return err
+			}
+			dataKey = key
+			*vault = locked
+			return nil
+		}
+
+		key, err := crypto.GenerateDataKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate vault key: %w", err)
+		}
+
+		// Move existing entries from the legacy master key to the new vault key
+		var existing []models.Service
+		if err := tx.Unscoped().Where("vault_id = ? AND client_encrypted = ?", vault.ID, false).Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to load vault entries: %w", err)
+		}
+		legacyKey, legacyErr := ring.Key(crypto.LegacyKeyID)
+		if len(existing) > 0 && legacyErr != nil {
+			return fmt.Errorf("legacy entries need the %s master key: %w", crypto.LegacyKeyID, legacyErr)
+		}
+		for _, svc := range existing {
+			plain, err := DecryptServicePassword(legacyKey, &svc)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt entry %s: %w", svc.ID, err)
+			}
+			encrypted, err := sealServiceField(key, svc.VaultID, svc.ID, FieldPassword, []byte(plain))
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt entry %s: %w", svc.ID, err)
+			}
+			if err := tx.Unscoped().Model(&models.Service{}).Where("id = ?", svc.ID).
+				Updates(map[string]interface{}{
+					"encrypted_password": encrypted,
+					"iv":                 "",
+					"aad_version":        AADVersionBound,
+				}).Error; err != nil {
+				return fmt.Errorf("failed to save entry %s: %w", svc.ID, err)
+			}
+		}
+
+		locked.KeyAADVersion = AADVersionBound
+		wrapped, err := crypto.WrapKey(key, wrapKeyID, wrapKey, vaultKeyAAD(&locked))
+		if err != nil {
+			return fmt.Errorf("failed to wrap vault key: %w", err)
+		}
+		if err := tx.Model(&models.Vault{}).Where("id = ?", vault.ID).
+			Updates(map[string]interface{}{
+				"wrapped_key":     wrapped,
+				"wrapped_key_iv":  "",
+				"master_key_id":   wrapKeyID,
+				"key_aad_version": AADVersionBound,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to save vault key: %w", err)
+		}
+
+		if len(existing) > 0 {
+			log.Printf(" Migrated %d entries of vault %s to envelope encryption\n", len(existing), vault.ID)
+		}
+
+		dataKey = key
+		vault.WrappedKey, vault.WrappedKeyIV = wrapped, ""
+		vault.MasterKeyID, vault.KeyAADVersion = wrapKeyID, AADVersionBound
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dataKey, nil
+}
+
+// unwrapVaultKey unwraps a vault key with the (per-user or master) key it was wrapped with
+func unwrapVaultKey(ring *crypto.KeyRing, vault *models.Vault) ([]byte, error) {
+	if vault.KeyAADVersion == AADVersionLegacy && legacyReadsRejected() {
+		return nil, fmt.Errorf("key of vault %s: %w", vault.ID, ErrLegacyCiphertext)
+	}
+	wrapped := crypto.EnvelopeFromColumns(vault.WrappedKey, vault.WrappedKeyIV, vault.MasterKeyID)
+	resolve := crypto.UserKeyResolver(ring, vault.UserID.String(), crypto.PurposeVaultKey)
+	return crypto.UnwrapKey(wrapped, resolve, vaultKeyAAD(vault))
+}
@@ -10,12 +10,18 @@ import (
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
 	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/strength"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 const vaultTTL = 7 * 24 * time.Hour // 7 days
 
-func AddPasswordToVault(repo storage.Repository, userID string, serviceName, domain, logo, rawPassword, notes string, strengthScore int8) error {
+func AddPasswordToVault(repo storage.Repository, userID string, serviceName, domain, logo, rawPassword, notes string, details EntryDetails) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
 	log.Println("🔧 Starting AddPasswordToVault...")
 
 	ctx := context.Background()
@@ -42,38 +48,70 @@ func AddPasswordToVault(repo storage.Repository, userID string, serviceName, dom
 		log.Printf(" Failed to get/create vault: %v\n", err)
 		return fmt.Errorf("failed to find/create vault: %w", err)
 	}
+	if vault.ZeroKnowledge {
+		return ErrZeroKnowledgeEnabled
+	}
+	if err := details.validate(false); err != nil {
+		return err
+	}
 
-	// Step 4: Load encryption key
-	key, err := crypto.LoadAESKey()
+	// Step 4: Load the vault's data-encryption key
+	key, err := VaultDataKey(repo, &vault)
 	if err != nil {
-		log.Printf(" Failed to load AES key: %v\n", err)
-		return fmt.Errorf("failed to load encryption key: %w", err)
+		log.Printf(" Failed to load vault key: %v\n", err)
+		return fmt.Errorf("failed to load vault key: %w", err)
 	}
 
-	// Step 5: Encrypt password
-	encryptedPass, iv, err := crypto.EncryptAES([]byte(rawPassword), key)
+	// Step 5: Encrypt password, bound to the new entry's ID
+	serviceID := uuid.New()
+	encryptedPass, err := sealServiceField(key, vault.ID, serviceID, FieldPassword, []byte(rawPassword))
 	if err != nil {
 		log.Printf(" Encryption failed: %v\n", err)
 		return fmt.Errorf("failed to encrypt password: %w", err)
 	}
+	estimate, err := estimatePasswordStrength(key, vault.ID, serviceID, rawPassword, serviceName, domain, details.Username)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := passwordFingerprint(key, rawPassword)
+	if err != nil {
+		return err
+	}
+	breachCount, breachCheckedAt := localBreachCheck(repo, rawPassword)
 
-	// Step 6: Create service entry
+	// Step 6: Create service entry with encrypted notes
 	service := models.Service{
-		ID:                uuid.New(),
-		VaultID:           vault.ID,
-		ServiceName:       serviceName,
-		ServiceDomain:     domain,
-		LogoURL:           logo,
-		EncryptedPassword: encryptedPass,
-		Notes:             notes,
-		StrengthScore:     strengthScore,
-		IV:                iv,
-		CreatedAt:         time.Now(),
-		UpdatedAt:         time.Now(),
+		ID:                  serviceID,
+		VaultID:             vault.ID,
+		ServiceName:         serviceName,
+		ServiceDomain:       domain,
+		LogoURL:             logo,
+		EncryptedPassword:   encryptedPass,
+		StrengthScore:       estimate.Score,
+		StrengthFeedback:    estimate.EncryptedFeedback,
+		StrengthVersion:     strength.Version,
+		PasswordFingerprint: fingerprint,
+		BreachCount:         breachCount,
+		BreachCheckedAt:     breachCheckedAt,
+		AADVersion:          AADVersionBound,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+	service.EncryptedNotes, err = sealNotes(key, &service, notes)
+	if err != nil {
+		log.Printf(" Notes encryption failed: %v\n", err)
+		return fmt.Errorf("failed to encrypt notes: %w", err)
+	}
+	service.EncryptedUsername, service.URIs, service.Fields, err = buildEntryDetails(key, vault.ID, serviceID, &details, false)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt entry details: %w", err)
 	}
 
 	// Step 7: Save to DB
-	if err := db.Create(&service).Error; err != nil {
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		return tx.Create(&service).Error
+	})
+	if err != nil {
 		log.Printf(" Failed to save service: %v\n", err)
 		return fmt.Errorf("failed to save password: %w", err)
 	}
@@ -90,6 +128,10 @@ func AddPasswordToVault(repo storage.Repository, userID string, serviceName, dom
 }
 
 func GetOrCreateVault(repo storage.Repository, userID string) (uuid.UUID, error) {
+	if crypto.IsSealed() {
+		return uuid.Nil, crypto.ErrSealed
+	}
+
 	ctx := context.Background()
 	db := repo.DB
 	redis := repo.RedisClient
@@ -142,6 +184,10 @@ func GetOrCreateVault(repo storage.Repository, userID string) (uuid.UUID, error)
 }
 
 func DeleteServiceFromVault(repo storage.Repository, userID, serviceID string) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
 	ctx := context.Background()
 	db := repo.DB
 	redis := repo.RedisClient
@@ -163,8 +209,12 @@ func DeleteServiceFromVault(repo storage.Repository, userID, serviceID string) e
 		return fmt.Errorf("failed to find vault: %w", err)
 	}
 
-	// Delete the service
-	if err := db.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).Delete(&models.Service{}).Error; err != nil {
+	// Move the service to the trash; it is purged after the retention period.
+	// Its attachments stay so it can be restored and go when it is purged.
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		return tx.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).Delete(&models.Service{}).Error
+	})
+	if err != nil {
 		return fmt.Errorf("failed to delete service: %w", err)
 	}
 
@@ -178,6 +228,10 @@ func DeleteServiceFromVault(repo storage.Repository, userID, serviceID string) e
 }
 
 func UpdateServiceNotes(repo storage.Repository, userID, serviceID, newNotes string) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
 	ctx := context.Background()
 	db := repo.DB
 	redis := repo.RedisClient
@@ -196,12 +250,35 @@ func UpdateServiceNotes(repo storage.Repository, userID, serviceID, newNotes str
 	if err := db.Where("user_id = ?", parsedUserID).First(&vault).Error; err != nil {
 		return fmt.Errorf("failed to find vault: %w", err)
 	}
+	if vault.ZeroKnowledge {
+		return ErrZeroKnowledgeEnabled
+	}
+
+	var service models.Service
+	if err := db.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).First(&service).Error; err != nil {
+		return fmt.Errorf("failed to find service: %w", err)
+	}
+
+	// Re-encrypt notes with the vault key
+	key, err := VaultDataKey(repo, &vault)
+	if err != nil {
+		return fmt.Errorf("failed to load vault key: %w", err)
+	}
+	encryptedNotes, err := sealNotes(key, &service, newNotes)
+	if err != nil {
+		return fmt.Errorf("encryption failed: %w", err)
+	}
 
-	
-	// Update notes
-	if err := db.Model(&models.Service{}).
-		Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).
-		Update("notes", newNotes).Error; err != nil {
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		return tx.Model(&models.Service{}).
+			Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).
+			Updates(map[string]interface{}{
+				"notes":           "",
+				"encrypted_notes": encryptedNotes,
+				"notes_iv":        "",
+			}).Error
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update notes: %w", err)
 	}
 
@@ -214,7 +291,11 @@ func UpdateServiceNotes(repo storage.Repository, userID, serviceID, newNotes str
 	return nil
 }
 
-func UpdateServicePassword(repo storage.Repository, userID, serviceID, newRawPassword string, strength int8) error {
+func UpdateServicePassword(repo storage.Repository, userID, serviceID, newRawPassword string) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
 	ctx := context.Background()
 	db := repo.DB
 	redis := repo.RedisClient
@@ -233,27 +314,69 @@ func UpdateServicePassword(repo storage.Repository, userID, serviceID, newRawPas
 	if err := db.Where("user_id = ?", parsedUserID).First(&vault).Error; err != nil {
 		return fmt.Errorf("failed to find vault: %w", err)
 	}
+	if vault.ZeroKnowledge {
+		return ErrZeroKnowledgeEnabled
+	}
 
-	// Load encryption key
-	key, err := crypto.LoadAESKey()
+	// Load the vault's data-encryption key
+	key, err := VaultDataKey(repo, &vault)
 	if err != nil {
-		return fmt.Errorf("failed to load encryption key: %w", err)
+		return fmt.Errorf("failed to load vault key: %w", err)
 	}
 
-	encryptedPass, iv, err := crypto.EncryptAES([]byte(newRawPassword), key)
+	encryptedPass, err := sealServiceField(key, vault.ID, parsedServiceID, FieldPassword, []byte(newRawPassword))
 	if err != nil {
 		return fmt.Errorf("encryption failed: %w", err)
 	}
 
-	// Update the encrypted password and IV
-	if err := db.Model(&models.Service{}).
-		Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).
-		Updates(map[string]interface{}{
-			"encrypted_password": encryptedPass,
-			"iv":                 iv,
-			"updated_at":         time.Now(),
-			"StrengthScore":       strength, 
-		}).Error; err != nil {
+	// Score the password before the row is locked; estimating is the slow part
+	var snapshot models.Service
+	if err := db.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).First(&snapshot).Error; err != nil {
+		return fmt.Errorf("failed to find service: %w", err)
+	}
+	if snapshot.ItemType != ItemLogin {
+		return fmt.Errorf("%w: only logins have a password", ErrInvalidEntry)
+	}
+	estimate, err := estimateEntryPassword(key, &snapshot, newRawPassword)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := passwordFingerprint(key, newRawPassword)
+	if err != nil {
+		return err
+	}
+	breachCount, breachCheckedAt := localBreachCheck(repo, newRawPassword)
+
+	// Archive the old password and update it; the IV now lives in the envelope
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		current, err := lockUserService(tx, vault.ID, parsedServiceID)
+		if err != nil {
+			return err
+		}
+		if current.ItemType != ItemLogin {
+			return fmt.Errorf("%w: only logins have a password", ErrInvalidEntry)
+		}
+		if !sameStrengthInputs(current, &snapshot) {
+			if estimate, err = estimateEntryPassword(key, current, newRawPassword); err != nil {
+				return err
+			}
+		}
+
+		updates := estimate.updates()
+		updates["encrypted_password"] = encryptedPass
+		updates["iv"] = ""
+		updates["password_fingerprint"] = fingerprint
+		updates["breach_count"], updates["breach_checked_at"] = breachCount, breachCheckedAt
+		updates["updated_at"] = time.Now()
+		if err := bindLegacyRowUpdates(key, current, updates); err != nil {
+			return err
+		}
+		if err := archivePassword(tx, current); err != nil {
+			return err
+		}
+		return tx.Model(&models.Service{}).Where("id = ?", current.ID).Updates(updates).Error
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update encrypted password: %w", err)
 	}
 
@@ -265,3 +388,58 @@ func UpdateServicePassword(repo storage.Repository, userID, serviceID, newRawPas
 
 	return nil
 }
+
+// UpdateServiceDetails replaces the username, URIs and custom fields of an entry.
+// Zero-knowledge vaults must send the username and hidden values as client envelopes.
+func UpdateServiceDetails(repo storage.Repository, userID, serviceID string, details EntryDetails) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return fmt.Errorf("invalid service ID: %w", err)
+	}
+
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+	if err := details.validate(vault.ZeroKnowledge); err != nil {
+		return err
+	}
+
+	var service models.Service
+	if err := repo.DB.Where("id = ? AND vault_id = ?", parsedServiceID, vault.ID).First(&service).Error; err != nil {
+		return fmt.Errorf("failed to find service: %w", err)
+	}
+
+	var key []byte
+	if !vault.ZeroKnowledge {
+		if key, err = VaultDataKey(repo, vault); err != nil {
+			return fmt.Errorf("failed to load vault key: %w", err)
+		}
+	}
+
+	username, uris, fields, err := buildEntryDetails(key, vault.ID, service.ID, &details, vault.ZeroKnowledge)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt entry details: %w", err)
+	}
+
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Service{}).Where("id = ?", service.ID).
+			Updates(map[string]interface{}{
+				"encrypted_username": username,
+				"updated_at":         time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to update username: %w", err)
+		}
+		return replaceEntryDetails(tx, service.ID, uris, fields)
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateVaultCache(repo, userID)
+	return nil
+}
@@ -0,0 +1,475 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/internal/models"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/crypto"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/storage"
+)
+
+var (
+	ErrZeroKnowledgeEnabled  = errors.New("vault is in zero-knowledge mode; send client-encrypted envelopes")
+	ErrZeroKnowledgeDisabled = errors.New("vault is not in zero-knowledge mode")
+	ErrInvalidEnvelope       = errors.New("invalid client-encrypted envelope")
+	ErrInvalidKDFParams      = errors.New("invalid KDF parameters")
+	ErrMigrationIncomplete   = errors.New("every vault entry must be included exactly once")
+)
+
+// Limits for client-supplied zero-knowledge data
+const (
+	maxEnvelopeBytes   = 64 * 1024
+	minKDFSaltBytes    = 16
+	minKDFMemoryKiB    = 19 * 1024 // OWASP minimum for Argon2id
+	maxKDFMemoryKiB    = 4 * 1024 * 1024
+	minKDFIterations   = 2
+	maxKDFIterations   = 100
+	maxKDFParallelism  = 16
+	kdfAlgorithmArgon2 = "argon2id"
+)
+
+// ClientEnvelope is an opaque ciphertext produced by the client. The server
+// only checks its shape; it never holds the key to open it.
+type ClientEnvelope struct {
+	Version    int    `json:"version"`
+	Algorithm  string `json:"algorithm"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// KDFParams are the Argon2id parameters the client uses to derive its vault key
+type KDFParams struct {
+	Algorithm   string `json:"algorithm"`
+	Salt        string `json:"salt"`
+	Memory      uint32 `json:"memory"`
+	Iterations  uint32 `json:"iterations"`
+	Parallelism uint8  `json:"parallelism"`
+}
+
+// ZeroKnowledgeEntry is one re-encrypted vault entry submitted during migration.
+// EncryptedFields holds the new value of every hidden custom field, by field ID;
+// items other than logins send EncryptedData instead of a password, and
+// entries with a one-time password seed send it as EncryptedOTP.
+type ZeroKnowledgeEntry struct {
+	ID                uuid.UUID                    `json:"id"`
+	EncryptedPassword *ClientEnvelope              `json:"encrypted_password"`
+	EncryptedData     *ClientEnvelope              `json:"encrypted_data"`
+	EncryptedOTP      *ClientEnvelope              `json:"encrypted_otp"`
+	EncryptedNotes    *ClientEnvelope              `json:"encrypted_notes"`
+	EncryptedUsername *ClientEnvelope              `json:"encrypted_username"`
+	EncryptedFields   map[uuid.UUID]ClientEnvelope `json:"encrypted_fields"`
+}
+
+func (e ClientEnvelope) validate() error {
+	if e.Version < 1 || e.Algorithm == "" || e.Nonce == "" || e.Ciphertext == "" {
+		return ErrInvalidEnvelope
+	}
+	if len(e.Ciphertext) > maxEnvelopeBytes {
+		return fmt.Errorf("%w: ciphertext too large", ErrInvalidEnvelope)
+	}
+	if _, err := base64.StdEncoding.DecodeString(e.Nonce); err != nil {
+		return fmt.Errorf("%w: nonce is not base64", ErrInvalidEnvelope)
+	}
+	if _, err := base64.StdEncoding.DecodeString(e.Ciphertext); err != nil {
+		return fmt.Errorf("%w: ciphertext is not base64", ErrInvalidEnvelope)
+	}
+	return nil
+}
+
+func (e ClientEnvelope) encode() (string, error) {
+	if err := e.validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+// encodeOptionalEnvelope encodes an envelope, or returns "" for nil
+func encodeOptionalEnvelope(e *ClientEnvelope) (string, error) {
+	if e == nil {
+		return "", nil
+	}
+	return e.encode()
+}
+
+// DecodeClientEnvelope parses an envelope stored by the zero-knowledge mode.
+// An empty string decodes to nil.
+func DecodeClientEnvelope(stored string) (*ClientEnvelope, error) {
+	if stored == "" {
+		return nil, nil
+	}
+	var e ClientEnvelope
+	if err := json.Unmarshal([]byte(stored), &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (p KDFParams) validate() error {
+	if p.Algorithm != kdfAlgorithmArgon2 {
+		return fmt.Errorf("%w: only %s is supported", ErrInvalidKDFParams, kdfAlgorithmArgon2)
+	}
+	salt, err := base64.StdEncoding.DecodeString(p.Salt)
+	if err != nil || len(salt) < minKDFSaltBytes {
+		return fmt.Errorf("%w: salt must be at least %d base64 bytes", ErrInvalidKDFParams, minKDFSaltBytes)
+	}
+	if p.Memory < minKDFMemoryKiB || p.Memory > maxKDFMemoryKiB {
+		return fmt.Errorf("%w: memory must be between %d and %d KiB", ErrInvalidKDFParams, minKDFMemoryKiB, maxKDFMemoryKiB)
+	}
+	if p.Iterations < minKDFIterations || p.Iterations > maxKDFIterations {
+		return fmt.Errorf("%w: iterations must be between %d and %d", ErrInvalidKDFParams, minKDFIterations, maxKDFIterations)
+	}
+	if p.Parallelism < 1 || p.Parallelism > maxKDFParallelism {
+		return fmt.Errorf("%w: parallelism must be between 1 and %d", ErrInvalidKDFParams, maxKDFParallelism)
+	}
+	return nil
+}
+
+// VaultKDFParams returns the KDF parameters stored on a zero-knowledge vault
+func VaultKDFParams(vault *models.Vault) KDFParams {
+	return KDFParams{
+		Algorithm:   vault.KDFAlgorithm,
+		Salt:        vault.KDFSalt,
+		Memory:      vault.KDFMemory,
+		Iterations:  vault.KDFIterations,
+		Parallelism: vault.KDFParallelism,
+	}
+}
+
+// GetUserVault returns the user's vault, creating it if needed
+func GetUserVault(repo storage.Repository, userID string) (*models.Vault, error) {
+	vaultID, err := GetOrCreateVault(repo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var vault models.Vault
+	if err := repo.DB.Where("id = ?", vaultID).First(&vault).Error; err != nil {
+		return nil, fmt.Errorf("failed to load vault: %w", err)
+	}
+	return &vault, nil
+}
+
+// findUserVault loads the vault owned by a user
+func findUserVault(db *gorm.DB, userID string) (*models.Vault, error) {
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	var vault models.Vault
+	if err := db.Where("user_id = ?", parsedUserID).First(&vault).Error; err != nil {
+		return nil, fmt.Errorf("failed to find vault: %w", err)
+	}
+	return &vault, nil
+}
+
+// EnableZeroKnowledge moves a vault into zero-knowledge mode. The client sends
+// every existing entry re-encrypted under its own key; they replace the
+// server-encrypted values in a single transaction.
+func EnableZeroKnowledge(repo storage.Repository, userID string, params KDFParams, verifier ClientEnvelope, entries []ZeroKnowledgeEntry) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	if err := params.validate(); err != nil {
+		return err
+	}
+	encodedVerifier, err := verifier.encode()
+	if err != nil {
+		return err
+	}
+
+	vaultID, err := GetOrCreateVault(repo, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find/create vault: %w", err)
+	}
+
+	err = updateVaultEntries(repo, vaultID, func(tx *gorm.DB) error {
+		var vault models.Vault
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", vaultID).First(&vault).Error; err != nil {
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+		if vault.ZeroKnowledge {
+			return ErrZeroKnowledgeEnabled
+		}
+
+		// Trashed entries can't be re-encrypted by the client, so they must be dealt with first
+		var trashed int64
+		if err := tx.Unscoped().Model(&models.Service{}).
+			Where("vault_id = ? AND deleted_at IS NOT NULL", vault.ID).Count(&trashed).Error; err != nil {
+			return fmt.Errorf("failed to check trash: %w", err)
+		}
+		if trashed > 0 {
+			return fmt.Errorf("%w: restore or empty the trash first", ErrMigrationIncomplete)
+		}
+
+		// Nor can server-encrypted attachments; the client re-uploads them after migrating
+		var attachments int64
+		if err := tx.Model(&models.Attachment{}).
+			Where("vault_id = ? AND client_encrypted = ?", vault.ID, false).Count(&attachments).Error; err != nil {
+			return fmt.Errorf("failed to check attachments: %w", err)
+		}
+		if attachments > 0 {
+			return fmt.Errorf("%w: download and delete server-encrypted attachments first", ErrMigrationIncomplete)
+		}
+
+		var existing []models.Service
+		if err := tx.Preload("Fields", "type = ?", CustomFieldHidden).
+			Where("vault_id = ?", vault.ID).Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to load vault entries: %w", err)
+		}
+
+		submitted := make(map[uuid.UUID]ZeroKnowledgeEntry, len(entries))
+		for _, entry := range entries {
+			if _, dup := submitted[entry.ID]; dup {
+				return ErrMigrationIncomplete
+			}
+			submitted[entry.ID] = entry
+		}
+		if len(submitted) != len(existing) {
+			return ErrMigrationIncomplete
+		}
+
+		for _, svc := range existing {
+			id := svc.ID
+			entry, ok := submitted[id]
+			if !ok {
+				return ErrMigrationIncomplete
+			}
+			if (svc.EncryptedPassword != "") != (entry.EncryptedPassword != nil) ||
+				(svc.EncryptedData != "") != (entry.EncryptedData != nil) ||
+				(svc.EncryptedOTP != "") != (entry.EncryptedOTP != nil) {
+				return ErrMigrationIncomplete
+			}
+			password, err := encodeOptionalEnvelope(entry.EncryptedPassword)
+			if err != nil {
+				return err
+			}
+			data, err := encodeOptionalEnvelope(entry.EncryptedData)
+			if err != nil {
+				return err
+			}
+			otpSeed, err := encodeOptionalEnvelope(entry.EncryptedOTP)
+			if err != nil {
+				return err
+			}
+			notes, err := encodeOptionalEnvelope(entry.EncryptedNotes)
+			if err != nil {
+				return err
+			}
+			if svc.EncryptedUsername != "" && entry.EncryptedUsername == nil {
+				return ErrMigrationIncomplete
+			}
+			username, err := encodeOptionalEnvelope(entry.EncryptedUsername)
+			if err != nil {
+				return err
+			}
+
+			// Every hidden custom field must come back re-encrypted
+			if len(entry.EncryptedFields) != len(svc.Fields) {
+				return ErrMigrationIncomplete
+			}
+			for _, field := range svc.Fields {
+				envelope, ok := entry.EncryptedFields[field.ID]
+				if !ok {
+					return ErrMigrationIncomplete
+				}
+				value, err := envelope.encode()
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&models.ServiceField{}).Where("id = ?", field.ID).
+					Update("encrypted_value", value).Error; err != nil {
+					return fmt.Errorf("failed to save field %s: %w", field.ID, err)
+				}
+			}
+
+			if err := tx.Model(&models.Service{}).Where("id = ?", id).
+				Updates(map[string]interface{}{
+					"encrypted_password":   password,
+					"encrypted_username":   username,
+					"encrypted_data":       data,
+					"encrypted_otp":        otpSeed,
+					"iv":                   "",
+					"notes":                "",
+					"encrypted_notes":      notes,
+					"notes_iv":             "",
+					"strength_feedback":    "", // sealed with the server key, describes the password
+					"password_fingerprint": "",
+					"breach_count":         0,
+					"breach_checked_at":    nil,
+					"aad_version":          AADVersionLegacy,
+					"client_encrypted":     true,
+					"updated_at":           time.Now(),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to save entry %s: %w", id, err)
+			}
+		}
+
+		// Old passwords the server could still decrypt don't survive the switch
+		if err := tx.Where("vault_id = ? AND client_encrypted = ?", vault.ID, false).
+			Delete(&models.PasswordHistory{}).Error; err != nil {
+			return fmt.Errorf("failed to remove password history: %w", err)
+		}
+
+		return tx.Model(&models.Vault{}).Where("id = ?", vault.ID).
+			Updates(map[string]interface{}{
+				"zero_knowledge":  true,
+				"kdf_algorithm":   params.Algorithm,
+				"kdf_salt":        params.Salt,
+				"kdf_memory":      params.Memory,
+				"kdf_iterations":  params.Iterations,
+				"kdf_parallelism": params.Parallelism,
+				"kdf_verifier":    encodedVerifier,
+			}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateVaultCache(repo, userID)
+	log.Printf(" Vault %s moved to zero-knowledge mode (%d entries)\n", vaultID, len(entries))
+	return nil
+}
+
+// AddClientEncryptedPasswordToVault stores an entry whose password (and notes)
+// were encrypted on the client
+func AddClientEncryptedPasswordToVault(repo storage.Repository, userID string, serviceName, domain, logo string, password ClientEnvelope, notes *ClientEnvelope, strengthScore int, details EntryDetails) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+	if !vault.ZeroKnowledge {
+		return ErrZeroKnowledgeDisabled
+	}
+	if err := details.validate(true); err != nil {
+		return err
+	}
+
+	encodedPassword, err := password.encode()
+	if err != nil {
+		return err
+	}
+	encodedNotes, err := encodeOptionalEnvelope(notes)
+	if err != nil {
+		return err
+	}
+
+	service := models.Service{
+		ID:                uuid.New(),
+		VaultID:           vault.ID,
+		ServiceName:       serviceName,
+		ServiceDomain:     domain,
+		LogoURL:           logo,
+		EncryptedPassword: encodedPassword,
+		EncryptedNotes:    encodedNotes,
+		StrengthScore:     clampClientStrength(strengthScore),
+		ClientEncrypted:   true,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+	service.EncryptedUsername, service.URIs, service.Fields, err = buildEntryDetails(nil, vault.ID, service.ID, &details, true)
+	if err != nil {
+		return err
+	}
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		return tx.Create(&service).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save password: %w", err)
+	}
+
+	invalidateVaultCache(repo, userID)
+	return nil
+}
+
+// UpdateClientEncryptedPassword replaces the password envelope of a zero-knowledge entry
+func UpdateClientEncryptedPassword(repo storage.Repository, userID, serviceID string, password ClientEnvelope, strength int) error {
+	encoded, err := password.encode()
+	if err != nil {
+		return err
+	}
+	return updateClientEncryptedEntry(repo, userID, serviceID, map[string]interface{}{
+		"encrypted_password": encoded,
+		"strength_score":     clampClientStrength(strength),
+	})
+}
+
+// UpdateClientEncryptedNotes replaces the notes envelope of a zero-knowledge entry
+func UpdateClientEncryptedNotes(repo storage.Repository, userID, serviceID string, notes ClientEnvelope) error {
+	encoded, err := notes.encode()
+	if err != nil {
+		return err
+	}
+	return updateClientEncryptedEntry(repo, userID, serviceID, map[string]interface{}{
+		"encrypted_notes": encoded,
+	})
+}
+
+func updateClientEncryptedEntry(repo storage.Repository, userID, serviceID string, updates map[string]interface{}) error {
+	if crypto.IsSealed() {
+		return crypto.ErrSealed
+	}
+
+	parsedServiceID, err := uuid.Parse(serviceID)
+	if err != nil {
+		return fmt.Errorf("invalid service ID: %w", err)
+	}
+
+	vault, err := findUserVault(repo.DB, userID)
+	if err != nil {
+		return err
+	}
+	if !vault.ZeroKnowledge {
+		return ErrZeroKnowledgeDisabled
+	}
+
+	updates["updated_at"] = time.Now()
+	err = updateVaultEntries(repo, vault.ID, func(tx *gorm.DB) error {
+		current, err := lockUserService(tx, vault.ID, parsedServiceID)
+		if err != nil {
+			return err
+		}
+		if !current.ClientEncrypted {
+			return ErrZeroKnowledgeDisabled
+		}
+		if _, ok := updates["encrypted_password"]; ok {
+			if current.ItemType != ItemLogin {
+				return fmt.Errorf("%w: only logins have a password", ErrInvalidEntry)
+			}
+			if err := archivePassword(tx, current); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Service{}).Where("id = ?", current.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update entry: %w", err)
+	}
+
+	invalidateVaultCache(repo, userID)
+	return nil
+}
+
+func invalidateVaultCache(repo storage.Repository, userID string) {
+	cacheKey := fmt.Sprintf("vault:%s", userID)
+	if err := repo.RedisClient.Del(context.Background(), cacheKey).Err(); err != nil {
+		log.Printf("Failed to invalidate Redis cache: %v", err)
+	}
+}
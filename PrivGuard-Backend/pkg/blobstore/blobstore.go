@@ -0,0 +1,61 @@
+// pkg/blobstore/blobstore.go
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps opaque blobs under slash-separated keys such as
+// "attachments/<vault id>/<attachment id>". Callers encrypt before Put;
+// stores never see plaintext.
+type Store interface {
+	Name() string
+	// Put writes size bytes from r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the blob under key, or returns ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key; deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv builds the store selected by BLOB_STORE ("local" or "s3")
+func FromEnv() (Store, error) {
+	switch backend := os.Getenv("BLOB_STORE"); backend {
+	case "", "local":
+		dir := os.Getenv("BLOB_STORE_DIR")
+		if dir == "" {
+			dir = "data/blobs"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		return S3StoreFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", backend)
+	}
+}
+
+// validKey rejects keys that could escape the store's root or need escaping:
+// segments may only hold letters, digits, '-', '_' and '.'
+func validKey(key string) error {
+	if key == "" {
+		return errors.New("blob key is empty")
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+				return fmt.Errorf("invalid blob key %q", key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// pkg/breach/breach.go
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Checker looks passwords up in a Pwned Passwords style range API. Only the
+// first five hex characters of a password's SHA-1 hash leave the server (k-anonymity);
+// the API answers with every suffix under that prefix and the match is made locally.
+// With a local index the checker never touches the network.
+type Checker struct {
+	baseURL string
+	client  *http.Client
+	cache   rangeCache // nil disables caching
+	ttl     time.Duration
+	group   singleflight.Group
+	local   Index // offline index, replaces the range API when set
+}
+
+const (
+	defaultBaseURL      = "https://api.pwnedpasswords.com"
+	defaultCacheTTL     = 24 * time.Hour
+	requestTimeout      = 10 * time.Second
+	maxRangeSize        = 2 << 20 // padded responses are around 40 KB
+	PrefixLength        = 5
+	rangeCacheKeyFormat = "breach:range:%s"
+)
+
+// rangeCache stores range responses between lookups
+type rangeCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+type redisCache struct{ client *redis.Client }
+
+func (r redisCache) Get(ctx context.Context, key string) (string, error) {
+	return r.client.Get(ctx, key).Result()
+}
+
+func (r redisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+var (
+	ErrInvalidPrefix = errors.New("range prefix must be 5 hexadecimal characters")
+	ErrUnavailable   = errors.New("breach range API is unavailable")
+)
+
+// FromEnv builds an offline checker for the index at BREACH_INDEX_FILE, or else
+// a checker for BREACH_API_URL (default the public Pwned Passwords API) caching
+// ranges for BREACH_CACHE_TTL_HOURS (default 24). It returns nil when
+// BREACH_CHECKS is "false".
+func FromEnv(cache *redis.Client) (*Checker, error) {
+	if os.Getenv("BREACH_CHECKS") == "false" {
+		return nil, nil
+	}
+	if path := os.Getenv("BREACH_INDEX_FILE"); path != "" {
+		index, err := OpenIndex(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewOffline(index), nil
+	}
+	ttl := defaultCacheTTL
+	if hours, err := strconv.Atoi(os.Getenv("BREACH_CACHE_TTL_HOURS")); err == nil && hours >= 0 {
+		ttl = time.Duration(hours) * time.Hour
+	}
+	return New(os.Getenv("BREACH_API_URL"), cache, ttl), nil
+}
+
+// New returns a checker for the range API at baseURL (the part before /range/).
+// A zero ttl disables caching.
+func New(baseURL string, cache *redis.Client, ttl time.Duration) *Checker {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	c := &Checker{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: requestTimeout},
+		ttl:     ttl,
+	}
+	if cache != nil && ttl > 0 {
+		c.cache = redisCache{cache}
+	}
+	return c
+}
+
+// NewOffline returns a checker that answers from a local index only
+func NewOffline(index Index) *Checker {
+	return &Checker{local: index}
+}
+
+// Offline reports whether lookups are answered locally. Offline lookups take
+// microseconds, so callers can check synchronously instead of in the background.
+func (c *Checker) Offline() bool {
+	return c.local != nil
+}
+
+// Source describes where lookups are answered, for logs
+func (c *Checker) Source() string {
+	if c.local != nil {
+		return "local " + c.local.Format()
+	}
+	return c.baseURL
+}
+
+// Count returns how many times a password appears in known breaches
+func (c *Checker) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	if c.local != nil {
+		return c.local.Lookup(sum)
+	}
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	body, err := c.Range(ctx, hash[:PrefixLength])
+	if err != nil {
+		return 0, err
+	}
+	return Lookup(body, hash[PrefixLength:]), nil
+}
+
+// Range returns the raw range response for a hash prefix, from the cache when
+// possible. Concurrent misses for the same prefix share one request, which runs
+// detached from any one caller: a caller that gives up doesn't fail the others.
+func (c *Checker) Range(ctx context.Context, prefix string) (string, error) {
+	prefix = strings.ToUpper(prefix)
+	if len(prefix) != PrefixLength || strings.Trim(prefix, "0123456789ABCDEF") != "" {
+		return "", ErrInvalidPrefix
+	}
+	if c.local != nil {
+		// A bloom filter can't list what it holds
+		if index, ok := c.local.(RangeIndex); ok {
+			return index.Range(prefix)
+		}
+		return "", fmt.Errorf("%w: range lookups need the range API or a sorted hash file", ErrUnavailable)
+	}
+
+	cacheKey := fmt.Sprintf(rangeCacheKeyFormat, prefix)
+	if c.cache != nil {
+		if body, err := c.cache.Get(ctx, cacheKey); err == nil {
+			return body, nil
+		}
+	}
+
+	shared := c.group.DoChan(prefix, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requestTimeout)
+		defer cancel()
+
+		body, err := c.fetch(fetchCtx, prefix)
+		if err != nil {
+			return "", err
+		}
+		// A cache failure only costs another request later
+		if c.cache != nil {
+			c.cache.Set(fetchCtx, cacheKey, body, c.ttl)
+		}
+		return body, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-shared:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	}
+}
+
+// fetch requests a range with padding, so the response size doesn't reveal
+// which prefix was asked for to anyone watching the connection
+func (c *Checker) fetch(ctx context.Context, prefix string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "PrivGuard")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRangeSize+1))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if len(body) > maxRangeSize {
+		return "", fmt.Errorf("%w: response is larger than %d bytes", ErrUnavailable, maxRangeSize)
+	}
+	return string(body), nil
+}
+
+// Lookup finds a hash suffix in a range response ("SUFFIX:COUNT" per line) and
+// returns its count. Padding lines have a count of 0, so they never match.
+func Lookup(body, suffix string) int {
+	suffix = strings.ToUpper(suffix)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		hashSuffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(hashSuffix, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,253 @@
+package breach
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryCache is a rangeCache with a clock the test moves by hand
+type memoryCache struct {
+	mu      sync.Mutex
+	now     time.Time
+	entries map[string]cacheEntry
+	sets    chan string
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+var errCacheMiss = errors.New("cache miss")
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{now: time.Now(), entries: make(map[string]cacheEntry), sets: make(chan string, 16)}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok || !m.now.Before(entry.expires) {
+		return "", errCacheMiss
+	}
+	return entry.value, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	m.entries[key] = cacheEntry{value: value, expires: m.now.Add(ttl)}
+	m.mu.Unlock()
+	m.sets <- key
+	return nil
+}
+
+func (m *memoryCache) advance(d time.Duration) {
+	m.mu.Lock()
+	m.now = m.now.Add(d)
+	m.mu.Unlock()
+}
+
+// rangeStandIn answers /range/{prefix} with fixed bodies and counts requests
+type rangeStandIn struct {
+	requests atomic.Int32
+	handler  func(w http.ResponseWriter, prefix string)
+}
+
+func newRangeStandIn(t *testing.T, handler func(w http.ResponseWriter, prefix string)) (*rangeStandIn, *Checker) {
+	s := &rangeStandIn{handler: handler}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Errorf("request for %s was sent without Add-Padding", r.URL.Path)
+		}
+		prefix, ok := strings.CutPrefix(r.URL.Path, "/range/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.handler(w, prefix)
+	}))
+	t.Cleanup(srv.Close)
+	return s, New(srv.URL, nil, 0)
+}
+
+func hashParts(password string) (prefix, suffix string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:PrefixLength], hash[PrefixLength:]
+}
+
+func TestCountFindsSuffixInRange(t *testing.T) {
+	prefix, suffix := hashParts("hunter2")
+	_, padSuffix := hashParts("not-breached")
+	s, checker := newRangeStandIn(t, func(w http.ResponseWriter, got string) {
+		if got != prefix {
+			t.Errorf("requested prefix %s, want %s", got, prefix)
+		}
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:17043\r\n%s:0\r\n", suffix, padSuffix)
+	})
+
+	count, err := checker.Count(context.Background(), "hunter2")
+	if err != nil || count != 17043 {
+		t.Fatalf("Count = %d, %v; want 17043", count, err)
+	}
+	if s.requests.Load() != 1 {
+		t.Fatalf("%d requests, want 1", s.requests.Load())
+	}
+}
+
+func TestCountIgnoresPaddingLines(t *testing.T) {
+	_, suffix := hashParts("correct horse battery staple")
+	_, checker := newRangeStandIn(t, func(w http.ResponseWriter, _ string) {
+		fmt.Fprintf(w, "%s:0\n003D68EB55068C33ACE09247EE4C639306B:3\n", suffix)
+	})
+
+	count, err := checker.Count(context.Background(), "correct horse battery staple")
+	if err != nil || count != 0 {
+		t.Fatalf("Count = %d, %v; a padding line must count as 0", count, err)
+	}
+}
+
+func TestRangeCache(t *testing.T) {
+	s, checker := newRangeStandIn(t, func(w http.ResponseWriter, _ string) {
+		fmt.Fprint(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\n")
+	})
+	cache := newMemoryCache()
+	checker.cache, checker.ttl = cache, time.Hour
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := checker.Range(ctx, "abcde"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.requests.Load() != 1 {
+		t.Fatalf("%d requests for a cached range, want 1", s.requests.Load())
+	}
+	if key := <-cache.sets; key != "breach:range:ABCDE" {
+		t.Fatalf("cached under %q", key)
+	}
+
+	cache.advance(time.Hour)
+	if _, err := checker.Range(ctx, "ABCDE"); err != nil {
+		t.Fatal(err)
+	}
+	if s.requests.Load() != 2 {
+		t.Fatalf("%d requests after the TTL, want 2", s.requests.Load())
+	}
+}
+
+func TestNewWithoutTTLDisablesCache(t *testing.T) {
+	if New("", nil, time.Hour).cache != nil {
+		t.Fatal("a checker without a Redis client has a cache")
+	}
+	if c := New("", nil, 0); c.cache != nil || c.baseURL != defaultBaseURL {
+		t.Fatalf("New(\"\", nil, 0) = %+v", c)
+	}
+}
+
+func TestRangeRejectsInvalidPrefix(t *testing.T) {
+	s, checker := newRangeStandIn(t, func(w http.ResponseWriter, _ string) {})
+	for _, prefix := range []string{"", "ABCD", "ABCDEF", "ABCDG", "../ab"} {
+		if _, err := checker.Range(context.Background(), prefix); !errors.Is(err, ErrInvalidPrefix) {
+			t.Errorf("Range(%q) = %v, want ErrInvalidPrefix", prefix, err)
+		}
+	}
+	if s.requests.Load() != 0 {
+		t.Fatalf("invalid prefixes reached the API")
+	}
+}
+
+func TestRangeErrorStatus(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusNotFound} {
+		_, checker := newRangeStandIn(t, func(w http.ResponseWriter, _ string) {
+			w.WriteHeader(status)
+			fmt.Fprint(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\n")
+		})
+		_, err := checker.Count(context.Background(), "hunter2")
+		if !errors.Is(err, ErrUnavailable) || !strings.Contains(err.Error(), fmt.Sprint(status)) {
+			t.Errorf("status %d: Count error = %v, want ErrUnavailable", status, err)
+		}
+	}
+}
+
+func TestRangeOversizedBody(t *testing.T) {
+	cache := newMemoryCache()
+	_, checker := newRangeStandIn(t, func(w http.ResponseWriter, _ string) {
+		line := "0018A45C4D1DEF81644B54AB7F969B88D65:1\n"
+		w.Write([]byte(strings.Repeat(line, maxRangeSize/len(line)+1)))
+	})
+	checker.cache, checker.ttl = cache, time.Hour
+
+	if _, err := checker.Range(context.Background(), "ABCDE"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Range error = %v, want ErrUnavailable", err)
+	}
+	if len(cache.entries) != 0 {
+		t.Fatal("an oversized response was cached")
+	}
+}
+
+func TestRangeFetchOutlivesCallerContext(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s, checker := newRangeStandIn(t, func(w http.ResponseWriter, _ string) {
+		close(started)
+		<-release
+		fmt.Fprint(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\n")
+	})
+	cache := newMemoryCache()
+	checker.cache, checker.ttl = cache, time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := checker.Range(ctx, "ABCDE")
+		done <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller got %v", err)
+	}
+
+	// The shared fetch carries on for other callers and still fills the cache
+	close(release)
+	select {
+	case <-cache.sets:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fetch was cancelled with its first caller")
+	}
+	body, err := checker.Range(context.Background(), "ABCDE")
+	if err != nil || Lookup(body, "0018a45c4d1def81644b54ab7f969b88d65") != 1 {
+		t.Fatalf("Range after the fetch = %q, %v", body, err)
+	}
+	if s.requests.Load() != 1 {
+		t.Fatalf("%d requests, want 1", s.requests.Load())
+	}
+}
+
+func TestLookup(t *testing.T) {
+	body := "0018A45C4D1DEF81644B54AB7F969B88D65:12\r\n00D4F6E8FA6EECAD2A3AA415EEC418D38EC:0\nmalformed\n011053FD0102E94D6AE2F8B83D76FAF94F6:x\n"
+	for suffix, want := range map[string]int{
+		"0018A45C4D1DEF81644B54AB7F969B88D65": 12,
+		"0018a45c4d1def81644b54ab7f969b88d65": 12,
+		"00D4F6E8FA6EECAD2A3AA415EEC418D38EC": 0,
+		"011053FD0102E94D6AE2F8B83D76FAF94F6": 0,
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF": 0,
+	} {
+		if got := Lookup(body, suffix); got != want {
+			t.Errorf("Lookup(%s) = %d, want %d", suffix, got, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// pkg/breach/index.go
+package breach
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Index answers breach lookups locally, for deployments without network access
+// to a range API. Two formats are supported:
+//
+//   - the sorted text dump itself ("SHA1:COUNT" per line, ordered by hash, as
+//     the Pwned Passwords downloader writes it), searched on disk;
+//   - a bloom filter built from the dump with "privguardctl breach-index build",
+//     held in memory.
+type Index interface {
+	// Lookup returns how often the password with this SHA-1 hash was seen, or 0.
+	// Formats that don't keep counts return 1 for a hit.
+	Lookup(hash [20]byte) (int, error)
+	// Format names the index format, for logs
+	Format() string
+	Close() error
+}
+
+// RangeIndex is an index that can also list the suffixes under a hash prefix,
+// so the range proxy keeps working offline
+type RangeIndex interface {
+	Index
+	Range(prefix string) (string, error)
+}
+
+var ErrMalformedIndex = errors.New("malformed breach index")
+
+// OpenIndex opens a bloom filter or a sorted hash file, telling them apart by
+// the bloom filter's magic header
+func OpenIndex(path string) (Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breach index: %w", err)
+	}
+
+	head := make([]byte, len(bloomMagic))
+	if _, err := io.ReadFull(f, head); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%w: %v", ErrMalformedIndex, err)
+	}
+	if bytes.Equal(head, []byte(bloomMagic)) {
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open breach index: %w", err)
+		}
+		return ReadBloomFilter(f, info.Size()-int64(len(bloomMagic)))
+	}
+	return openSortedFile(f)
+}
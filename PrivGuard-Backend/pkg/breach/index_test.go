@@ -0,0 +1,323 @@
+package breach
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type dumpEntry struct {
+	hash  [20]byte
+	count int
+}
+
+// randomDump returns n random hashes in sorted order with counts 1..n
+func randomDump(n int) []dumpEntry {
+	entries := make([]dumpEntry, n)
+	for i := range entries {
+		rand.Read(entries[i].hash[:])
+		entries[i].count = i + 1
+	}
+	sortDump(entries)
+	return entries
+}
+
+func sortDump(entries []dumpEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].hash[:], entries[j].hash[:]) < 0
+	})
+}
+
+type dumpStyle struct {
+	lowercase bool
+	crlf      bool
+	bare      bool // no ":COUNT"
+}
+
+// writeDump writes entries as a sorted text dump and returns its path
+func writeDump(t testing.TB, entries []dumpEntry, style dumpStyle) string {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, e := range entries {
+		hashHex := strings.ToUpper(hex.EncodeToString(e.hash[:]))
+		if style.lowercase {
+			hashHex = strings.ToLower(hashHex)
+		}
+		buf.WriteString(hashHex)
+		if !style.bare {
+			fmt.Fprintf(&buf, ":%d", e.count)
+		}
+		if style.crlf {
+			buf.WriteString("\r\n")
+		} else {
+			buf.WriteString("\n")
+		}
+	}
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openSorted(t testing.TB, path string) *SortedFile {
+	t.Helper()
+	index, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { index.Close() })
+	sorted, ok := index.(*SortedFile)
+	if !ok {
+		t.Fatalf("OpenIndex returned a %s", index.Format())
+	}
+	return sorted
+}
+
+func hashFromHex(t *testing.T, s string) [20]byte {
+	t.Helper()
+	var hash [20]byte
+	if _, err := hex.Decode(hash[:], []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestSortedFileFindsEveryHash(t *testing.T) {
+	entries := randomDump(3000)
+	for _, style := range []dumpStyle{{}, {crlf: true}, {lowercase: true}, {lowercase: true, crlf: true}, {bare: true}} {
+		index := openSorted(t, writeDump(t, entries, style))
+		for _, e := range entries {
+			want := e.count
+			if style.bare {
+				want = 1
+			}
+			if got, err := index.Lookup(e.hash); err != nil || got != want {
+				t.Fatalf("%+v: Lookup(%x) = %d, %v; want %d", style, e.hash, got, err, want)
+			}
+		}
+	}
+}
+
+func TestSortedFileMisses(t *testing.T) {
+	entries := randomDump(3000)
+	// Keep the ends of the hash space free for targets before the first and after the last line
+	for i := range entries {
+		entries[i].hash[0] = 1 + entries[i].hash[0]%0xfe
+	}
+	sortDump(entries)
+	index := openSorted(t, writeDump(t, entries, dumpStyle{}))
+
+	var first, last, between [20]byte
+	for i := range last {
+		last[i] = 0xff
+	}
+	between = entries[1500].hash
+	between[19] ^= 0x01 // neighbours differ long before the last byte
+
+	for name, hash := range map[string][20]byte{"before the first line": first, "after the last line": last, "between two lines": between} {
+		if got, err := index.Lookup(hash); err != nil || got != 0 {
+			t.Errorf("%s: Lookup = %d, %v; want 0", name, got, err)
+		}
+	}
+}
+
+func TestSortedFileSingleLine(t *testing.T) {
+	hash := hashFromHex(t, "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8")
+	for _, style := range []dumpStyle{{}, {crlf: true}, {lowercase: true}} {
+		index := openSorted(t, writeDump(t, []dumpEntry{{hash: hash, count: 9545824}}, style))
+
+		if got, err := index.Lookup(hash); err != nil || got != 9545824 {
+			t.Fatalf("%+v: Lookup = %d, %v", style, got, err)
+		}
+		for _, miss := range []string{"0000000000000000000000000000000000000000", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"} {
+			if got, err := index.Lookup(hashFromHex(t, miss)); err != nil || got != 0 {
+				t.Fatalf("%+v: Lookup(%s) = %d, %v", style, miss, got, err)
+			}
+		}
+		body, err := index.Range("5baa6")
+		if err != nil || body != "1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n" {
+			t.Fatalf("%+v: Range = %q, %v", style, body, err)
+		}
+		if body, err := index.Range("5BAA7"); err != nil || body != "" {
+			t.Fatalf("%+v: Range of another prefix = %q, %v", style, body, err)
+		}
+	}
+}
+
+func TestSortedFileRejectsEmptyAndMalformedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"empty":      "",
+		"short":      "5BAA61E4",
+		"not hex":    strings.Repeat("Z", 40) + ":1\n",
+		"text":       "# Pwned Passwords\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1\n",
+		"truncated":  "PGBLOOM1",
+		"long lines": strings.Repeat("A", 40) + strings.Repeat("1", 300) + "\n",
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_"))
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if index, err := OpenIndex(path); !errors.Is(err, ErrMalformedIndex) {
+			if index != nil {
+				index.Close()
+			}
+			t.Errorf("%s: OpenIndex error = %v, want ErrMalformedIndex", name, err)
+		}
+	}
+}
+
+func TestSortedFileRangeAcrossScanWindow(t *testing.T) {
+	entries := randomDump(2000)
+	// 300 hashes under one prefix take about 13 KB, several scan windows
+	var inRange []dumpEntry
+	for i := 0; i < 300; i++ {
+		var e dumpEntry
+		rand.Read(e.hash[:])
+		e.hash[0], e.hash[1], e.hash[2] = 0xAB, 0xCD, 0xE0|e.hash[2]&0x0f
+		e.count = 100000 + i
+		inRange = append(inRange, e)
+	}
+	sortDump(inRange)
+	// Drop random entries that would fall under the prefix too
+	var all []dumpEntry
+	for _, e := range entries {
+		if !strings.HasPrefix(strings.ToUpper(hex.EncodeToString(e.hash[:])), "ABCDE") {
+			all = append(all, e)
+		}
+	}
+	all = append(all, inRange...)
+	sortDump(all)
+
+	for _, style := range []dumpStyle{{}, {crlf: true}, {lowercase: true}} {
+		index := openSorted(t, writeDump(t, all, style))
+		body, err := index.Range("ABCDE")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want strings.Builder
+		for _, e := range inRange {
+			fmt.Fprintf(&want, "%s:%d\r\n", strings.ToUpper(hex.EncodeToString(e.hash[:]))[PrefixLength:], e.count)
+		}
+		if body != want.String() {
+			t.Fatalf("%+v: Range returned %d lines, want %d", style, strings.Count(body, "\n"), len(inRange))
+		}
+	}
+}
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	entries := randomDump(20000)
+	filter, err := NewBloomFilter(uint64(len(entries)), 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		filter.Add(e.hash)
+	}
+
+	path := filepath.Join(t.TempDir(), "pwned.bloom")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := filter.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	index, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	loaded, ok := index.(*BloomFilter)
+	if !ok {
+		t.Fatalf("OpenIndex returned a %s", index.Format())
+	}
+	if loaded.Items() != uint64(len(entries)) {
+		t.Fatalf("loaded filter has %d items, want %d", loaded.Items(), len(entries))
+	}
+
+	for _, e := range entries {
+		if got, _ := loaded.Lookup(e.hash); got != 1 {
+			t.Fatalf("false negative for %x", e.hash)
+		}
+	}
+	falsePositives := 0
+	for _, e := range randomDump(20000) {
+		if got, _ := loaded.Lookup(e.hash); got == 1 {
+			falsePositives++
+		}
+	}
+	if falsePositives > 60 { // 20 expected at 0.1%
+		t.Fatalf("%d false positives in 20000 lookups", falsePositives)
+	}
+}
+
+func TestBloomFilterRejectsHeaderNotMatchingFileSize(t *testing.T) {
+	filter, err := NewBloomFilter(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	filter.WriteTo(&buf)
+	valid := buf.Bytes()
+
+	dir := t.TempDir()
+	huge := bytes.Clone(valid)
+	binary.BigEndian.PutUint64(huge[len(bloomMagic):], 1<<62) // would allocate 512 PiB
+	for name, content := range map[string][]byte{
+		"truncated bit array": valid[:len(valid)-1],
+		"trailing bytes":      append(bytes.Clone(valid), 0),
+		"huge bit count":      huge,
+		"header only":         valid[:bloomHeaderSize],
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_"))
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenIndex(path); !errors.Is(err, ErrMalformedIndex) {
+			t.Errorf("%s: OpenIndex error = %v, want ErrMalformedIndex", name, err)
+		}
+	}
+}
+
+func BenchmarkBloomLookup(b *testing.B) {
+	entries := randomDump(1_000_000)
+	filter, err := NewBloomFilter(uint64(len(entries)), 0.001)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, e := range entries {
+		filter.Add(e.hash)
+	}
+	targets := randomDump(4096)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Lookup(targets[i%len(targets)].hash)
+	}
+}
+
+func BenchmarkSortedLookup(b *testing.B) {
+	entries := randomDump(1_000_000)
+	index := openSorted(b, writeDump(b, entries, dumpStyle{}))
+	targets := randomDump(4096)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := index.Lookup(targets[i%len(targets)].hash); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// pkg/breach/sorted.go
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Binary search narrows down to a window this size, which is then scanned
+const sortedScanWindow = 4096
+
+// Longest line read while searching; dump lines are about 45 bytes
+const maxSortedLine = 128
+
+// SortedFile looks hashes up in a sorted text dump by binary search over byte
+// offsets. Nothing is loaded into memory: a lookup costs about log2(size/4096)
+// small reads, served by the page cache once the file is warm.
+type SortedFile struct {
+	f    *os.File
+	size int64
+}
+
+func openSortedFile(f *os.File) (*SortedFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &SortedFile{f: f, size: info.Size()}
+
+	// Check the first line so a wrong file fails at startup, not on every lookup
+	_, line, err := s.lineAt(0)
+	if err == nil && len(line) < 40 {
+		err = fmt.Errorf("%w: first line is not a SHA-1 hash", ErrMalformedIndex)
+	}
+	if err == nil {
+		_, err = hex.DecodeString(string(line[:40]))
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%w: %v", ErrMalformedIndex, err)
+	}
+	return s, nil
+}
+
+func (s *SortedFile) Format() string { return "sorted hash file" }
+
+func (s *SortedFile) Close() error { return s.f.Close() }
+
+// Lookup finds a full hash and returns its count
+func (s *SortedFile) Lookup(hash [20]byte) (int, error) {
+	target := strings.ToUpper(hex.EncodeToString(hash[:]))
+	var count int
+	err := s.scanFrom(target, func(hashHex string, n int) bool {
+		if hashHex == target {
+			count = n
+		}
+		return false
+	})
+	return count, err
+}
+
+// Range lists the suffixes under a 5 character prefix in range API format
+func (s *SortedFile) Range(prefix string) (string, error) {
+	prefix = strings.ToUpper(prefix)
+	if len(prefix) != PrefixLength || strings.Trim(prefix, "0123456789ABCDEF") != "" {
+		return "", ErrInvalidPrefix
+	}
+
+	var out strings.Builder
+	err := s.scanFrom(prefix, func(hashHex string, n int) bool {
+		if !strings.HasPrefix(hashHex, prefix) {
+			return false
+		}
+		fmt.Fprintf(&out, "%s:%d\r\n", hashHex[PrefixLength:], n)
+		return true
+	})
+	return out.String(), err
+}
+
+// scanFrom calls fn for each line from the first one whose hash is >= target,
+// until fn returns false or the file ends
+func (s *SortedFile) scanFrom(target string, fn func(hashHex string, count int) bool) error {
+	// Lines before lo are all < target; lo is always the start of a line
+	lo, hi := int64(0), s.size
+	for hi-lo > sortedScanWindow {
+		mid := lo + (hi-lo)/2
+		start, line, err := s.lineAt(mid)
+		if err == io.EOF || start >= hi {
+			hi = mid
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if compareHash(line, target) < 0 {
+			lo = start + int64(len(line)) + 1
+		} else {
+			hi = start
+		}
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(s.f, lo, s.size-lo))
+	started := false
+	for {
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 {
+			hashHex, count, perr := parseDumpLine(line)
+			if perr != nil {
+				return perr
+			}
+			// A prefix target sorts before every hash that starts with it
+			if !started && hashHex >= target {
+				started = true
+			}
+			if started && !fn(hashHex, count) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lineAt returns the first line starting at or after offset, without its newline
+func (s *SortedFile) lineAt(offset int64) (int64, []byte, error) {
+	start := offset
+	buf := make([]byte, maxSortedLine*2)
+	if offset > 0 {
+		// Back up one byte: if it is a newline, offset is already a line start
+		start = offset - 1
+	}
+	n, err := s.f.ReadAt(buf, start)
+	if n == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, nil, err
+	}
+	buf = buf[:n]
+
+	if offset > 0 {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return 0, nil, fmt.Errorf("%w: line longer than %d bytes", ErrMalformedIndex, maxSortedLine)
+		}
+		start += int64(i) + 1
+		buf = buf[i+1:]
+	}
+	if len(buf) == 0 {
+		return 0, nil, io.EOF
+	}
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i]
+	} else if start+int64(len(buf)) < s.size {
+		return 0, nil, fmt.Errorf("%w: line longer than %d bytes", ErrMalformedIndex, maxSortedLine)
+	}
+	return start, buf, nil
+}
+
+// parseDumpLine reads "HASH:COUNT" (or a bare hash, counted once)
+func parseDumpLine(line []byte) (string, int, error) {
+	text := strings.TrimSpace(string(line))
+	hashHex, countStr, hasCount := strings.Cut(text, ":")
+	if len(hashHex) != 40 {
+		return "", 0, fmt.Errorf("%w: %q is not a SHA-1 hash", ErrMalformedIndex, hashHex)
+	}
+	count := 1
+	if hasCount {
+		n, err := strconv.Atoi(countStr)
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: bad count in %q", ErrMalformedIndex, text)
+		}
+		count = n
+	}
+	return strings.ToUpper(hashHex), count, nil
+}
+
+// compareHash compares the hash at the start of a dump line with target,
+// case-insensitively, over the length of target
+func compareHash(line []byte, target string) int {
+	n := min(len(line), len(target))
+	return strings.Compare(strings.ToUpper(string(line[:n])), target[:n])
+}
@@ -4,106 +4,29 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
-	"fmt"
-	"io"
-	"sync"
-	"os"
-
 )
 
-// EncryptAES encrypts plaintext using AES-256 with a given key and returns base64 ciphertext and IV
-func EncryptAES(plaintext, key []byte) (string, string, error) {
+// newAESGCM returns an AES-256-GCM AEAD for the given key
+func newAESGCM(key []byte) (cipher.AEAD, error) {
 	if len(key) != 32 {
-		return "", "", errors.New("key must be 32 bytes for AES-256")
+		return nil, errors.New("key must be 32 bytes for AES-256")
 	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	aead, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", "", err
-	}
-
-	nonce := make([]byte, aead.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", "", err
-	}
-
-	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
-
-	return base64.StdEncoding.EncodeToString(ciphertext), base64.StdEncoding.EncodeToString(nonce), nil
+	return cipher.NewGCM(block)
 }
 
-
-// DecryptAES decrypts a base64 ciphertext using AES-256 with the given key and IV
-func DecryptAES(ciphertextB64, nonceB64 string, key []byte) (string, error) {
-	if len(key) != 32 {
-		return "", errors.New("key must be 32 bytes for AES-256")
-	}
-
-	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
-	if err != nil {
-		return "", err
-	}
-
-	nonce, err := base64.StdEncoding.DecodeString(nonceB64)
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-
-	aead, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+// LoadAESKey returns the active master key from the key ring
+func LoadAESKey() ([]byte, error) {
+	ring, err := LoadKeyRing()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	return string(plaintext), nil
+	_, key := ring.Active()
+	return key, nil
 }
-
-
-var (
-	cachedKey []byte
-	loadOnce  sync.Once
-	loadErr   error
-)
-
-func LoadAESKey() ([]byte, error) {
-	loadOnce.Do(func() {
-		// Get key from environment variable
-		base64Key := os.Getenv("MASTER_ENCRYPTION_KEY")
-		if base64Key == "" {
-			loadErr = errors.New("MASTER_ENCRYPTION_KEY environment variable not set")
-			return
-		}
-
-		key, err := base64.StdEncoding.DecodeString(base64Key)
-		if err != nil {
-			loadErr = fmt.Errorf("failed to decode MASTER_ENCRYPTION_KEY: %w", err)
-			return
-		}
-
-		if len(key) != 32 {
-			loadErr = errors.New("MASTER_ENCRYPTION_KEY must be exactly 32 bytes after base64 decoding")
-			return
-		}
-
-		cachedKey = key
-	})
-
-	return cachedKey, loadErr
-}
\ No newline at end of file
@@ -0,0 +1,44 @@
+// pkg/crypto/datakey.go
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+)
+
+// RowAAD builds the associated data that binds a ciphertext to the vault,
+// row and field it is stored in, so it can't be swapped onto another row.
+func RowAAD(vaultID, rowID, field string) []byte {
+	return []byte(strings.Join([]string{"privguard:v1", "vault=" + vaultID, "row=" + rowID, "field=" + field}, "|"))
+}
+
+// GenerateDataKey returns a fresh random 256-bit data-encryption key
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// WrapKey seals a data-encryption key with the master key named by keyID
+func WrapKey(dataKey []byte, keyID string, masterKey, aad []byte) (string, error) {
+	if len(dataKey) != 32 {
+		return "", errors.New("data key must be 32 bytes")
+	}
+	return Encrypt(dataKey, keyID, masterKey, aad)
+}
+
+// UnwrapKey opens a wrapped data-encryption key with the master key its envelope names
+func UnwrapKey(wrapped string, resolve KeyResolver, aad []byte) ([]byte, error) {
+	dataKey, err := Decrypt(wrapped, resolve, aad)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) != 32 {
+		return nil, errors.New("unwrapped data key has invalid length")
+	}
+	return dataKey, nil
+}
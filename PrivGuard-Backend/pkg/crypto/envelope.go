@@ -0,0 +1,189 @@
+// pkg/crypto/envelope.go
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Ciphertexts are stored as self-describing envelopes:
+//
+//	$pg1$<algorithm>$<key id>$<base64 nonce>$<base64 ciphertext>
+//
+// so the algorithm, the key that sealed them and the nonce travel together
+// and older ciphertexts stay readable when the defaults change.
+const (
+	envelopeVersion   = "pg1"
+	envelopeSeparator = "$"
+)
+
+// Algorithm identifies the AEAD an envelope was sealed with
+type Algorithm string
+
+const (
+	AlgAES256GCM         Algorithm = "aes256gcm"
+	AlgXChaCha20Poly1305 Algorithm = "xchacha20poly1305"
+)
+
+// DataKeyID is the key ID recorded on fields sealed with a vault data key
+const DataKeyID = "dek"
+
+var ErrInvalidEnvelope = errors.New("invalid ciphertext envelope")
+
+// KeyResolver looks up the key an envelope names. KeyRing.Key satisfies it.
+type KeyResolver func(keyID string) ([]byte, error)
+
+// SingleKey resolves only the given key ID
+func SingleKey(keyID string, key []byte) KeyResolver {
+	return func(id string) ([]byte, error) {
+		if id != keyID {
+			return nil, fmt.Errorf("unexpected key id %q", id)
+		}
+		return key, nil
+	}
+}
+
+// Envelope is a parsed ciphertext envelope
+type Envelope struct {
+	Algorithm  Algorithm
+	KeyID      string
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// ParseAlgorithm maps a CIPHER_ALGORITHM value to an algorithm
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "")) {
+	case "", "xchacha20poly1305":
+		return AlgXChaCha20Poly1305, nil
+	case "aes256gcm":
+		return AlgAES256GCM, nil
+	default:
+		return "", fmt.Errorf("unknown CIPHER_ALGORITHM %q", name)
+	}
+}
+
+// ConfiguredAlgorithm returns the algorithm new ciphertexts are sealed with.
+// XChaCha20-Poly1305 is the default since its 192-bit random nonces don't wear
+// out on busy keys the way 96-bit GCM nonces do.
+func ConfiguredAlgorithm() (Algorithm, error) {
+	return ParseAlgorithm(os.Getenv("CIPHER_ALGORITHM"))
+}
+
+func newAEAD(alg Algorithm, key []byte) (cipher.AEAD, error) {
+	switch alg {
+	case AlgAES256GCM:
+		return newAESGCM(key)
+	case AlgXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// Encrypt seals plaintext with the configured algorithm and returns an envelope
+// naming keyID. The same AAD must be supplied to Decrypt or decryption fails.
+func Encrypt(plaintext []byte, keyID string, key, aad []byte) (string, error) {
+	alg, err := ConfiguredAlgorithm()
+	if err != nil {
+		return "", err
+	}
+	return EncryptWith(alg, plaintext, keyID, key, aad)
+}
+
+// EncryptWith is Encrypt with an explicit algorithm
+func EncryptWith(alg Algorithm, plaintext []byte, keyID string, key, aad []byte) (string, error) {
+	if strings.Contains(keyID, envelopeSeparator) {
+		return "", fmt.Errorf("key id must not contain %q", envelopeSeparator)
+	}
+
+	aead, err := newAEAD(alg, key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := aead.Seal(nil, nonce, plaintext, aad)
+
+	return formatEnvelope(alg, keyID,
+		base64.StdEncoding.EncodeToString(nonce),
+		base64.StdEncoding.EncodeToString(ciphertext)), nil
+}
+
+// Decrypt opens an envelope with the key it names
+func Decrypt(envelope string, resolve KeyResolver, aad []byte) ([]byte, error) {
+	env, err := ParseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := resolve(env.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(env.Algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, ErrInvalidEnvelope
+	}
+
+	return aead.Open(nil, env.Nonce, env.Ciphertext, aad)
+}
+
+// ParseEnvelope splits an envelope into its parts
+func ParseEnvelope(envelope string) (*Envelope, error) {
+	parts := strings.Split(envelope, envelopeSeparator)
+	if len(parts) != 6 || parts[0] != "" || parts[1] != envelopeVersion {
+		return nil, ErrInvalidEnvelope
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+
+	return &Envelope{
+		Algorithm:  Algorithm(parts[2]),
+		KeyID:      parts[3],
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+// IsEnvelope reports whether a stored value is an envelope rather than legacy base64 ciphertext
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopeSeparator+envelopeVersion+envelopeSeparator)
+}
+
+// EnvelopeFromColumns reads a value stored in the legacy two-column layout
+// (base64 AES-GCM ciphertext plus a separate base64 IV) as an envelope for keyID.
+// Values already stored as envelopes are returned unchanged.
+func EnvelopeFromColumns(ciphertextB64, ivB64, keyID string) string {
+	if IsEnvelope(ciphertextB64) {
+		return ciphertextB64
+	}
+	return formatEnvelope(AlgAES256GCM, keyID, ivB64, ciphertextB64)
+}
+
+func formatEnvelope(alg Algorithm, keyID, nonceB64, ciphertextB64 string) string {
+	return strings.Join([]string{"", envelopeVersion, string(alg), keyID, nonceB64, ciphertextB64}, envelopeSeparator)
+}
@@ -0,0 +1,209 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var algorithms = []Algorithm{AlgAES256GCM, AlgXChaCha20Poly1305}
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := GenerateDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	key := testKey(t)
+	aad := RowAAD("vault", "row", "password")
+
+	for _, alg := range algorithms {
+		for _, plaintext := range [][]byte{[]byte("hunter2"), {}, bytes.Repeat([]byte{0xff}, 4096)} {
+			envelope, err := EncryptWith(alg, plaintext, "v2", key, aad)
+			if err != nil {
+				t.Fatalf("%s: EncryptWith: %v", alg, err)
+			}
+			if !IsEnvelope(envelope) || !strings.HasPrefix(envelope, "$pg1$"+string(alg)+"$v2$") {
+				t.Fatalf("%s: envelope %q has the wrong header", alg, envelope)
+			}
+
+			got, err := Decrypt(envelope, SingleKey("v2", key), aad)
+			if err != nil {
+				t.Fatalf("%s: Decrypt: %v", alg, err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("%s: round trip returned %q", alg, got)
+			}
+		}
+	}
+}
+
+func TestEnvelopeNonceSizes(t *testing.T) {
+	key := testKey(t)
+	for alg, size := range map[Algorithm]int{AlgAES256GCM: 12, AlgXChaCha20Poly1305: 24} {
+		envelope, err := EncryptWith(alg, []byte("x"), "k", key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := ParseEnvelope(envelope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(env.Nonce) != size || env.Algorithm != alg || env.KeyID != "k" {
+			t.Errorf("%s: parsed %+v, want a %d-byte nonce", alg, env, size)
+		}
+	}
+}
+
+// replacePart swaps one $-separated part of an envelope
+func replacePart(envelope string, i int, value string) string {
+	parts := strings.Split(envelope, "$")
+	parts[i] = value
+	return strings.Join(parts, "$")
+}
+
+// flipBit flips one bit of a base64 part of an envelope
+func flipBit(t *testing.T, envelope string, i, byteIdx int) string {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(strings.Split(envelope, "$")[i])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[byteIdx] ^= 0x01
+	return replacePart(envelope, i, base64.StdEncoding.EncodeToString(raw))
+}
+
+func TestEnvelopeTampering(t *testing.T) {
+	key, otherKey := testKey(t), testKey(t)
+	aad := RowAAD("vault", "row", "password")
+	ring := func(id string) ([]byte, error) {
+		switch id {
+		case "v1":
+			return key, nil
+		case "v2":
+			return otherKey, nil
+		}
+		return nil, errors.New("unknown key")
+	}
+
+	for _, alg := range algorithms {
+		envelope, err := EncryptWith(alg, []byte("hunter2"), "v1", key, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := strings.Split(envelope, "$")
+		nonce, _ := base64.StdEncoding.DecodeString(parts[4])
+		ciphertext, _ := base64.StdEncoding.DecodeString(parts[5])
+
+		other := AlgAES256GCM
+		if alg == AlgAES256GCM {
+			other = AlgXChaCha20Poly1305
+		}
+
+		tests := []struct {
+			name     string
+			envelope string
+			aad      []byte
+		}{
+			{"wrong AAD", envelope, RowAAD("vault", "other-row", "password")},
+			{"wrong field in AAD", envelope, RowAAD("vault", "row", "notes")},
+			{"missing AAD", envelope, nil},
+			{"key ID pointing at another key", replacePart(envelope, 3, "v2"), aad},
+			{"unknown key ID", replacePart(envelope, 3, "v9"), aad},
+			{"other algorithm", replacePart(envelope, 2, string(other)), aad},
+			{"unknown algorithm", replacePart(envelope, 2, "rot13"), aad},
+			{"truncated nonce", replacePart(envelope, 4, base64.StdEncoding.EncodeToString(nonce[:len(nonce)-1])), aad},
+			{"altered nonce", flipBit(t, envelope, 4, 0), aad},
+			{"truncated ciphertext", replacePart(envelope, 5, base64.StdEncoding.EncodeToString(ciphertext[:len(ciphertext)-1])), aad},
+			{"ciphertext without tag", replacePart(envelope, 5, base64.StdEncoding.EncodeToString(ciphertext[:len(ciphertext)-16])), aad},
+			{"altered ciphertext", flipBit(t, envelope, 5, 0), aad},
+			{"altered tag", flipBit(t, envelope, 5, len(ciphertext)-1), aad},
+			{"nonce not base64", replacePart(envelope, 4, "!!"), aad},
+			{"other version", replacePart(envelope, 1, "pg2"), aad},
+			{"extra part", envelope + "$", aad},
+			{"legacy base64 value", parts[5], aad},
+		}
+		for _, tt := range tests {
+			if got, err := Decrypt(tt.envelope, ring, tt.aad); err == nil {
+				t.Errorf("%s, %s: decrypted to %q, want an error", alg, tt.name, got)
+			}
+		}
+	}
+}
+
+func TestSingleKeyRejectsOtherIDs(t *testing.T) {
+	key := testKey(t)
+	envelope, err := EncryptWith(AlgXChaCha20Poly1305, []byte("x"), "v1", key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(envelope, SingleKey("v2", key), nil); err == nil {
+		t.Error("expected an error for an envelope naming another key")
+	}
+}
+
+func TestEncryptRejectsSeparatorInKeyID(t *testing.T) {
+	if _, err := EncryptWith(AlgAES256GCM, []byte("x"), "v$1", testKey(t), nil); err == nil {
+		t.Error("expected an error for a key ID containing the separator")
+	}
+}
+
+func TestEnvelopeFromColumns(t *testing.T) {
+	key := testKey(t)
+	aad := RowAAD("vault", "row", "password")
+
+	// The pre-envelope layout: base64 AES-GCM ciphertext with the IV in its own column
+	aead, err := newAESGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatal(err)
+	}
+	ciphertextB64 := base64.StdEncoding.EncodeToString(aead.Seal(nil, iv, []byte("legacy secret"), aad))
+	ivB64 := base64.StdEncoding.EncodeToString(iv)
+
+	envelope := EnvelopeFromColumns(ciphertextB64, ivB64, DataKeyID)
+	got, err := Decrypt(envelope, SingleKey(DataKeyID, key), aad)
+	if err != nil || string(got) != "legacy secret" {
+		t.Fatalf("legacy columns decrypted to %q, %v", got, err)
+	}
+	if _, err := Decrypt(EnvelopeFromColumns(ciphertextB64, "", DataKeyID), SingleKey(DataKeyID, key), aad); err == nil {
+		t.Error("legacy value without its IV: expected an error")
+	}
+
+	// Values already stored as envelopes pass through, whatever the IV column holds
+	current, err := EncryptWith(AlgXChaCha20Poly1305, []byte("new secret"), DataKeyID, key, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if EnvelopeFromColumns(current, ivB64, "other") != current {
+		t.Error("an envelope was rewritten")
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := map[string]Algorithm{
+		"":                   AlgXChaCha20Poly1305,
+		"xchacha20-poly1305": AlgXChaCha20Poly1305,
+		"XChaCha20Poly1305":  AlgXChaCha20Poly1305,
+		"aes256gcm":          AlgAES256GCM,
+		"AES-256-GCM":        AlgAES256GCM,
+	}
+	for name, want := range tests {
+		if got, err := ParseAlgorithm(name); err != nil || got != want {
+			t.Errorf("ParseAlgorithm(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseAlgorithm("des"); err == nil {
+		t.Error("ParseAlgorithm(des): expected an error")
+	}
+}
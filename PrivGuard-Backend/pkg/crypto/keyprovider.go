@@ -0,0 +1,103 @@
+// pkg/crypto/keyprovider.go
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyProvider sources the master key ring at startup
+type KeyProvider interface {
+	Name() string
+	LoadKeyRing(ctx context.Context) (*KeyRing, error)
+}
+
+// KeyProviderFromEnv builds the provider selected by KEY_PROVIDER ("env", "file" or "vault-transit").
+// KEY_PROVIDER=shamir is not a provider: the server starts sealed via InitSealed.
+func KeyProviderFromEnv() (KeyProvider, error) {
+	switch provider := os.Getenv("KEY_PROVIDER"); provider {
+	case "", "env":
+		return EnvKeyProvider{}, nil
+	case "file":
+		path := os.Getenv("MASTER_KEY_FILE")
+		if path == "" {
+			return nil, errors.New("MASTER_KEY_FILE must be set for the file key provider")
+		}
+		return FileKeyProvider{Path: path}, nil
+	case "vault-transit":
+		return VaultTransitKeyProviderFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown KEY_PROVIDER %q", provider)
+	}
+}
+
+// EnvKeyProvider reads base64 master keys from environment variables.
+//
+// MASTER_ENCRYPTION_KEYS holds comma-separated "id:base64key" pairs and
+// MASTER_ENCRYPTION_KEY_ID selects the active one. When only the legacy
+// MASTER_ENCRYPTION_KEY is set, it becomes a single-key ring with ID "v1".
+type EnvKeyProvider struct{}
+
+func (EnvKeyProvider) Name() string { return "env" }
+
+func (EnvKeyProvider) LoadKeyRing(ctx context.Context) (*KeyRing, error) {
+	keys := make(map[string][]byte)
+
+	if legacy := os.Getenv("MASTER_ENCRYPTION_KEY"); legacy != "" {
+		key, err := base64.StdEncoding.DecodeString(legacy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode MASTER_ENCRYPTION_KEY: %w", err)
+		}
+		keys[LegacyKeyID] = key
+	}
+
+	if list := os.Getenv("MASTER_ENCRYPTION_KEYS"); list != "" {
+		parsed, err := parseKeyList(list, func(b64 string) ([]byte, error) {
+			return base64.StdEncoding.DecodeString(b64)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("MASTER_ENCRYPTION_KEYS: %w", err)
+		}
+		for id, key := range parsed {
+			keys[id] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("MASTER_ENCRYPTION_KEY or MASTER_ENCRYPTION_KEYS environment variable not set")
+	}
+
+	activeID, err := resolveActiveID(keys, os.Getenv("MASTER_ENCRYPTION_KEY_ID"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(keys, activeID)
+}
+
+// parseKeyList parses comma-separated "id:value" pairs, decoding each value.
+// Only the first colon separates the ID, so values may contain colons.
+func parseKeyList(list string, decode func(string) ([]byte, error)) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for i, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// Never echo the entry itself: it holds key material
+		id, value, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || value == "" {
+			return nil, fmt.Errorf("entry %d must be id:value", i+1)
+		}
+		key, err := decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode master key %s: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
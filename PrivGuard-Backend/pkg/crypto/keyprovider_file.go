@@ -0,0 +1,82 @@
+// pkg/crypto/keyprovider_file.go
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// FileKeyProvider reads the master key ring from a JSON key file:
+//
+//	{"active_key_id": "v2", "keys": {"v1": "<base64>", "v2": "<base64>"}}
+//
+// The file must be a regular file owned by the process user and must not be
+// readable or writable by group or others (e.g. mode 0400 or 0600).
+type FileKeyProvider struct {
+	Path string
+}
+
+type keyFile struct {
+	ActiveKeyID string            `json:"active_key_id"`
+	Keys        map[string]string `json:"keys"`
+}
+
+func (p FileKeyProvider) Name() string { return "file" }
+
+func (p FileKeyProvider) LoadKeyRing(ctx context.Context) (*KeyRing, error) {
+	if err := checkKeyFilePermissions(p.Path); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	return parseKeyFile(data)
+}
+
+// parseKeyFile parses the JSON key file format into a key ring
+func parseKeyFile(data []byte) (*KeyRing, error) {
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.New("key file is not valid JSON")
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, b64 := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode master key %s: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	activeID, err := resolveActiveID(keys, file.ActiveKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(keys, activeID)
+}
+
+func checkKeyFilePermissions(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat key file: %w", err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("key file %s must be a regular file, not a symlink or device", path)
+	}
+
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("key file %s has mode %#o; it must not be accessible by group or others (use 0400 or 0600)", path, perm)
+	}
+
+	return checkKeyFileOwner(path, info)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+// pkg/crypto/keyprovider_file_other.go
+package crypto
+
+import "os"
+
+// File ownership is not checked on platforms without POSIX owners
+func checkKeyFileOwner(path string, info os.FileInfo) error {
+	return nil
+}
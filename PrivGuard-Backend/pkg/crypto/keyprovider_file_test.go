@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKeyFile(t *testing.T, contents string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(contents), mode); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile's mode is subject to the umask
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	k1, k2 := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	contents := `{"active_key_id": "v2", "keys": {"v1": "` + base64.StdEncoding.EncodeToString(k1) +
+		`", "v2": "` + base64.StdEncoding.EncodeToString(k2) + `"}}`
+
+	for _, mode := range []os.FileMode{0o400, 0o600} {
+		ring, err := FileKeyProvider{Path: writeKeyFile(t, contents, mode)}.LoadKeyRing(context.Background())
+		if err != nil {
+			t.Fatalf("mode %#o: %v", mode, err)
+		}
+		if activeID, key := ring.Active(); activeID != "v2" || !bytes.Equal(key, k2) {
+			t.Fatalf("mode %#o: active key = %s", mode, activeID)
+		}
+	}
+}
+
+func TestFileKeyProviderRejectsUnsafeFiles(t *testing.T) {
+	contents := `{"keys": {"v1": "` + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)) + `"}}`
+
+	for _, mode := range []os.FileMode{0o640, 0o604, 0o660, 0o644, 0o700 | 0o007} {
+		path := writeKeyFile(t, contents, mode)
+		_, err := FileKeyProvider{Path: path}.LoadKeyRing(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "must not be accessible by group or others") {
+			t.Errorf("mode %#o: error = %v, want a permission error", mode, err)
+		}
+	}
+
+	target := writeKeyFile(t, contents, 0o600)
+	link := filepath.Join(t.TempDir(), "link.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	_, err := FileKeyProvider{Path: link}.LoadKeyRing(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "must be a regular file") {
+		t.Errorf("symlink: error = %v, want it rejected", err)
+	}
+
+	_, err = FileKeyProvider{Path: filepath.Join(t.TempDir(), "missing.json")}.LoadKeyRing(context.Background())
+	if err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
+
+func TestParseKeyFile(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"single key", `{"keys": {"v1": "` + key + `"}}`, ""},
+		{"not JSON", `v1=` + key, "not valid JSON"},
+		{"bad base64", `{"keys": {"v1": "***"}}`, "failed to decode master key v1"},
+		{"no keys", `{"keys": {}}`, "key ring is empty"},
+		{"active key missing", `{"active_key_id": "v2", "keys": {"v1": "` + key + `"}}`, `"v2" is not in the key ring`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseKeyFile([]byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("parseKeyFile: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+// pkg/crypto/keyprovider_file_unix.go
+package crypto
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func checkKeyFileOwner(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot determine owner of key file %s", path)
+	}
+
+	if uid := os.Geteuid(); int(stat.Uid) != uid {
+		return fmt.Errorf("key file %s is owned by uid %d, expected %d", path, stat.Uid, uid)
+	}
+
+	return nil
+}
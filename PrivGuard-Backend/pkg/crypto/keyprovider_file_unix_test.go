@@ -0,0 +1,27 @@
+//go:build unix
+
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileKeyProviderRejectsOtherOwner(t *testing.T) {
+	contents := `{"keys": {"v1": "` + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)) + `"}}`
+	path := writeKeyFile(t, contents, 0o600)
+
+	// Only root can give a file away
+	if err := os.Chown(path, os.Geteuid()+1, -1); err != nil {
+		t.Skipf("cannot change file owner: %v", err)
+	}
+
+	_, err := FileKeyProvider{Path: path}.LoadKeyRing(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "is owned by uid") {
+		t.Fatalf("error = %v, want an owner error", err)
+	}
+}
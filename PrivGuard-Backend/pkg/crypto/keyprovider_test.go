@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEnvKeyProvider(t *testing.T) {
+	k1, k2 := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	b64 := base64.StdEncoding.EncodeToString
+
+	tests := []struct {
+		name       string
+		legacy     string
+		list       string
+		activeID   string
+		wantActive string
+		wantIDs    []string
+		wantErr    string
+	}{
+		{name: "legacy key only", legacy: b64(k1), wantActive: "v1", wantIDs: []string{"v1"}},
+		{name: "key list", list: "v1:" + b64(k1) + ", v2:" + b64(k2), activeID: "v2", wantActive: "v2", wantIDs: []string{"v1", "v2"}},
+		{name: "legacy and list", legacy: b64(k1), list: "v2:" + b64(k2), activeID: "v2", wantActive: "v2", wantIDs: []string{"v1", "v2"}},
+		{name: "single list key is active", list: "k7:" + b64(k2), wantActive: "k7", wantIDs: []string{"k7"}},
+		{name: "trailing comma", list: "v2:" + b64(k2) + ",", wantActive: "v2", wantIDs: []string{"v2"}},
+		{name: "nothing set", wantErr: "not set"},
+		{name: "several keys without active ID", list: "v1:" + b64(k1) + ",v2:" + b64(k2), wantErr: "active key ID must be set"},
+		{name: "active ID not in ring", list: "v1:" + b64(k1), activeID: "v9", wantErr: `"v9" is not in the key ring`},
+		{name: "entry without ID", list: ":" + b64(k1), wantErr: "entry 1 must be id:value"},
+		{name: "entry without colon", list: "v1:" + b64(k1) + ",garbage", wantErr: "entry 2 must be id:value"},
+		{name: "bad base64", list: "v1:not-base64!", wantErr: "failed to decode master key v1"},
+		{name: "short key", list: "v1:" + b64(k1[:16]), wantErr: "must be exactly 32 bytes"},
+		{name: "bad legacy key", legacy: "%%%", wantErr: "MASTER_ENCRYPTION_KEY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MASTER_ENCRYPTION_KEY", tt.legacy)
+			t.Setenv("MASTER_ENCRYPTION_KEYS", tt.list)
+			t.Setenv("MASTER_ENCRYPTION_KEY_ID", tt.activeID)
+
+			ring, err := EnvKeyProvider{}.LoadKeyRing(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeyRing: %v", err)
+			}
+			if activeID, _ := ring.Active(); activeID != tt.wantActive {
+				t.Errorf("active = %s, want %s", activeID, tt.wantActive)
+			}
+			if ids := strings.Join(ring.IDs(), ","); ids != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("IDs = %s, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestParseKeyListDoesNotEchoKeys(t *testing.T) {
+	_, err := parseKeyList("secretmaterial", func(v string) ([]byte, error) { return []byte(v), nil })
+	if err == nil || strings.Contains(err.Error(), "secretmaterial") {
+		t.Fatalf("error = %v, want an error that does not contain the entry", err)
+	}
+}
+
+func TestKeyProviderFromEnv(t *testing.T) {
+	t.Setenv("KEY_PROVIDER", "file")
+	t.Setenv("MASTER_KEY_FILE", "")
+	if _, err := KeyProviderFromEnv(); err == nil {
+		t.Error("file provider without MASTER_KEY_FILE: expected an error")
+	}
+
+	t.Setenv("MASTER_KEY_FILE", "/etc/privguard/keys.json")
+	if p, err := KeyProviderFromEnv(); err != nil || p.Name() != "file" {
+		t.Errorf("file provider = %v, %v", p, err)
+	}
+
+	t.Setenv("KEY_PROVIDER", "")
+	if p, err := KeyProviderFromEnv(); err != nil || p.Name() != "env" {
+		t.Errorf("default provider = %v, %v", p, err)
+	}
+
+	t.Setenv("KEY_PROVIDER", "kms")
+	if _, err := KeyProviderFromEnv(); err == nil {
+		t.Error("unknown provider: expected an error")
+	}
+}
@@ -0,0 +1,150 @@
+// pkg/crypto/keyprovider_vault.go
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultTransitKeyProvider unwraps master keys through a HashiCorp Vault
+// transit engine (or any server speaking the same decrypt API). Only the
+// transit ciphertexts are configured; raw key material never sits in the
+// environment.
+type VaultTransitKeyProvider struct {
+	Addr        string            // e.g. https://vault.internal:8200
+	Token       string            // sent as X-Vault-Token
+	Namespace   string            // optional X-Vault-Namespace
+	Mount       string            // transit mount path, default "transit"
+	KeyName     string            // transit key used to wrap the master keys
+	Wrapped     map[string]string // master key ID -> transit ciphertext ("vault:v1:...")
+	ActiveKeyID string
+	HTTPClient  *http.Client
+}
+
+// VaultTransitKeyProviderFromEnv configures the provider from VAULT_ADDR,
+// VAULT_TOKEN (or VAULT_TOKEN_FILE), VAULT_NAMESPACE, VAULT_TRANSIT_MOUNT,
+// VAULT_TRANSIT_KEY, MASTER_ENCRYPTION_KEYS_WRAPPED ("id:ciphertext" pairs)
+// and MASTER_ENCRYPTION_KEY_ID.
+func VaultTransitKeyProviderFromEnv() (*VaultTransitKeyProvider, error) {
+	p := &VaultTransitKeyProvider{
+		Addr:        os.Getenv("VAULT_ADDR"),
+		Token:       os.Getenv("VAULT_TOKEN"),
+		Namespace:   os.Getenv("VAULT_NAMESPACE"),
+		Mount:       os.Getenv("VAULT_TRANSIT_MOUNT"),
+		KeyName:     os.Getenv("VAULT_TRANSIT_KEY"),
+		ActiveKeyID: os.Getenv("MASTER_ENCRYPTION_KEY_ID"),
+		Wrapped:     make(map[string]string),
+	}
+
+	if p.Token == "" {
+		if path := os.Getenv("VAULT_TOKEN_FILE"); path != "" {
+			token, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read VAULT_TOKEN_FILE: %w", err)
+			}
+			p.Token = strings.TrimSpace(string(token))
+		}
+	}
+
+	if p.Addr == "" || p.Token == "" || p.KeyName == "" {
+		return nil, errors.New("VAULT_ADDR, VAULT_TOKEN and VAULT_TRANSIT_KEY must be set for the vault-transit key provider")
+	}
+
+	wrapped, err := parseKeyList(os.Getenv("MASTER_ENCRYPTION_KEYS_WRAPPED"), func(v string) ([]byte, error) {
+		return []byte(v), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MASTER_ENCRYPTION_KEYS_WRAPPED: %w", err)
+	}
+	for id, ciphertext := range wrapped {
+		p.Wrapped[id] = string(ciphertext)
+	}
+
+	return p, nil
+}
+
+func (p *VaultTransitKeyProvider) Name() string { return "vault-transit" }
+
+func (p *VaultTransitKeyProvider) LoadKeyRing(ctx context.Context) (*KeyRing, error) {
+	if len(p.Wrapped) == 0 {
+		return nil, errors.New("no wrapped master keys configured")
+	}
+
+	keys := make(map[string][]byte, len(p.Wrapped))
+	for id, ciphertext := range p.Wrapped {
+		key, err := p.decrypt(ctx, ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap master key %s: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	activeID, err := resolveActiveID(keys, p.ActiveKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(keys, activeID)
+}
+
+type transitDecryptResponse struct {
+	Data struct {
+		Plaintext string `json:"plaintext"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// decrypt calls POST /v1/<mount>/decrypt/<key> and returns the raw plaintext bytes
+func (p *VaultTransitKeyProvider) decrypt(ctx context.Context, ciphertext string) ([]byte, error) {
+	mount := strings.Trim(p.Mount, "/")
+	if mount == "" {
+		mount = "transit"
+	}
+	endpoint := strings.TrimRight(p.Addr, "/") + "/v1/" + mount + "/decrypt/" + url.PathEscape(p.KeyName)
+
+	body, err := json.Marshal(map[string]string{"ciphertext": ciphertext})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", p.Token)
+	if p.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.Namespace)
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("transit request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded transitDecryptResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("invalid transit response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transit decrypt returned %d: %s", resp.StatusCode, strings.Join(decoded.Errors, "; "))
+	}
+
+	// Transit returns the plaintext base64-encoded
+	return base64.StdEncoding.DecodeString(decoded.Data.Plaintext)
+}
@@ -0,0 +1,196 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// transitStandIn speaks enough of the Vault transit API to wrap and unwrap keys
+type transitStandIn struct {
+	token string
+	key   string
+
+	mu         sync.Mutex
+	plaintexts map[string]string // ciphertext -> base64 plaintext
+}
+
+func newTransitStandIn(t *testing.T) (*transitStandIn, *httptest.Server) {
+	s := &transitStandIn{token: "test-token", key: "privguard", plaintexts: make(map[string]string)}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func (s *transitStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != s.token {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors":["permission denied"]}`)
+		return
+	}
+	var body map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":["invalid JSON"]}`)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/transit/encrypt/" + s.key:
+		id := make([]byte, 12)
+		rand.Read(id)
+		ciphertext := "vault:v1:" + base64.StdEncoding.EncodeToString(id)
+		s.plaintexts[ciphertext] = body["plaintext"]
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": ciphertext}})
+	case "/v1/transit/decrypt/" + s.key:
+		plaintext, ok := s.plaintexts[body["ciphertext"]]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":["cipher: message authentication failed"]}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": plaintext}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors":[]}`)
+	}
+}
+
+// wrap encrypts a key through the stand-in, as an operator would with "vault write transit/encrypt"
+func (s *transitStandIn) wrap(t *testing.T, srv *httptest.Server, key []byte) string {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/transit/encrypt/"+s.key, bytes.NewReader(body))
+	req.Header.Set("X-Vault-Token", s.token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var decoded struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded.Data.Ciphertext
+}
+
+func randomKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVaultTransitKeyProviderUnwrapsKeys(t *testing.T) {
+	standIn, srv := newTransitStandIn(t)
+	v1, v2 := randomKey(t), randomKey(t)
+
+	p := &VaultTransitKeyProvider{
+		Addr:    srv.URL,
+		Token:   standIn.token,
+		KeyName: standIn.key,
+		Wrapped: map[string]string{
+			"v1": standIn.wrap(t, srv, v1),
+			"v2": standIn.wrap(t, srv, v2),
+		},
+		ActiveKeyID: "v2",
+	}
+	ring, err := p.LoadKeyRing(context.Background())
+	if err != nil {
+		t.Fatalf("LoadKeyRing: %v", err)
+	}
+
+	activeID, active := ring.Active()
+	if activeID != "v2" || !bytes.Equal(active, v2) {
+		t.Fatalf("active key = %s, want v2 with the wrapped key", activeID)
+	}
+	got, err := ring.Key("v1")
+	if err != nil || !bytes.Equal(got, v1) {
+		t.Fatalf("Key(v1) = %x, %v; want the wrapped key", got, err)
+	}
+}
+
+func TestVaultTransitKeyProviderErrors(t *testing.T) {
+	standIn, srv := newTransitStandIn(t)
+	wrapped := standIn.wrap(t, srv, randomKey(t))
+
+	badResponse := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>not transit</html>")
+	}))
+	defer badResponse.Close()
+	badPlaintext := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"plaintext":"not base64!"}}`)
+	}))
+	defer badPlaintext.Close()
+
+	tests := []struct {
+		name    string
+		addr    string
+		token   string
+		wrapped map[string]string
+		wantErr string
+	}{
+		{"wrong token", srv.URL, "other-token", map[string]string{"v1": wrapped}, "returned 403: permission denied"},
+		{"unknown ciphertext", srv.URL, standIn.token, map[string]string{"v1": "vault:v1:bm9wZQ=="}, "returned 400: cipher: message authentication failed"},
+		{"non-JSON response", badResponse.URL, standIn.token, map[string]string{"v1": wrapped}, "invalid transit response (status 200)"},
+		{"plaintext not base64", badPlaintext.URL, standIn.token, map[string]string{"v1": wrapped}, "illegal base64"},
+		{"no wrapped keys", srv.URL, standIn.token, nil, "no wrapped master keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &VaultTransitKeyProvider{Addr: tt.addr, Token: tt.token, KeyName: standIn.key, Wrapped: tt.wrapped}
+			_, err := p.LoadKeyRing(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadKeyRing error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultTransitKeyProviderFromEnv(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "https://vault.example:8200")
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_TRANSIT_KEY", "privguard")
+	t.Setenv("MASTER_ENCRYPTION_KEYS_WRAPPED", "v1:vault:v1:abc, v2:vault:v2:def")
+	t.Setenv("MASTER_ENCRYPTION_KEY_ID", "v2")
+
+	if _, err := VaultTransitKeyProviderFromEnv(); err == nil {
+		t.Fatal("expected an error without a token")
+	}
+
+	tokenFile := t.TempDir() + "/token"
+	if err := os.WriteFile(tokenFile, []byte("s.token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_TOKEN_FILE", tokenFile)
+
+	p, err := VaultTransitKeyProviderFromEnv()
+	if err != nil {
+		t.Fatalf("VaultTransitKeyProviderFromEnv: %v", err)
+	}
+	if p.Token != "s.token" {
+		t.Errorf("token = %q, want it read from VAULT_TOKEN_FILE and trimmed", p.Token)
+	}
+	// Only the first colon separates the ID from the ciphertext
+	if p.Wrapped["v1"] != "vault:v1:abc" || p.Wrapped["v2"] != "vault:v2:def" {
+		t.Errorf("wrapped = %v", p.Wrapped)
+	}
+}
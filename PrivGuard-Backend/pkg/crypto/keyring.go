@@ -0,0 +1,145 @@
+// pkg/crypto/keyring.go
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LegacyKeyID is the version assigned to the single MASTER_ENCRYPTION_KEY
+// used before key versioning existed.
+const LegacyKeyID = "v1"
+
+// KeyRing holds every known master key by ID; new ciphertexts always use the active one
+type KeyRing struct {
+	mu       sync.RWMutex
+	keys     map[string][]byte
+	activeID string
+}
+
+// NewKeyRing builds a key ring from the given keys and active key ID
+func NewKeyRing(keys map[string][]byte, activeID string) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("key ring is empty")
+	}
+
+	ring := &KeyRing{keys: make(map[string][]byte, len(keys)), activeID: activeID}
+	for id, key := range keys {
+		if id == "" || strings.ContainsAny(id, ":,") {
+			return nil, fmt.Errorf("invalid master key ID %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("master key %s must be exactly 32 bytes", id)
+		}
+		ring.keys[id] = key
+	}
+
+	if _, ok := ring.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active master key %q is not in the key ring", activeID)
+	}
+
+	return ring, nil
+}
+
+// Active returns the ID and a copy of the key used for new ciphertexts
+func (r *KeyRing) Active() (string, []byte) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.activeID, append([]byte(nil), r.keys[r.activeID]...)
+}
+
+// Key returns a copy of the master key with the given ID. An empty ID means LegacyKeyID.
+func (r *KeyRing) Key(id string) ([]byte, error) {
+	if id == "" {
+		id = LegacyKeyID
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("master key %q not found in key ring", id)
+	}
+	return append([]byte(nil), key...), nil
+}
+
+// wipe zeroes every key in the ring. Callers only ever hold copies,
+// so in-flight operations are not affected.
+func (r *KeyRing) wipe() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		for i := range key {
+			key[i] = 0
+		}
+	}
+	r.keys = nil
+}
+
+// IDs returns all key IDs in the ring, sorted
+func (r *KeyRing) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ErrKeyRingNotLoaded is returned when a key is requested before InitKeyRing ran
+var ErrKeyRingNotLoaded = errors.New("master key ring not loaded")
+
+var (
+	ringMu     sync.RWMutex
+	cachedRing *KeyRing
+)
+
+// InitKeyRing loads the master key ring from the given provider and caches it
+// for LoadKeyRing. It is called once at startup.
+func InitKeyRing(ctx context.Context, provider KeyProvider) (*KeyRing, error) {
+	ring, err := provider.LoadKeyRing(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s key provider: %w", provider.Name(), err)
+	}
+
+	ringMu.Lock()
+	cachedRing = ring
+	ringMu.Unlock()
+
+	return ring, nil
+}
+
+// LoadKeyRing returns the key ring loaded by InitKeyRing or by unsealing.
+// While the process is sealed it returns ErrSealed.
+func LoadKeyRing() (*KeyRing, error) {
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+
+	if cachedRing == nil {
+		if sealedMode {
+			return nil, ErrSealed
+		}
+		return nil, ErrKeyRingNotLoaded
+	}
+	return cachedRing, nil
+}
+
+// resolveActiveID picks the active key: the explicit ID if given, otherwise the only key
+func resolveActiveID(keys map[string][]byte, explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	if len(keys) > 1 {
+		return "", errors.New("an active key ID must be set when more than one master key is configured")
+	}
+	for id := range keys {
+		return id, nil
+	}
+	return "", errors.New("key ring is empty")
+}
@@ -0,0 +1,51 @@
+// pkg/crypto/merkle.go
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// Domain separation bytes so a leaf hash can never be mistaken for an inner node
+const (
+	merkleLeaf  byte = 0x00
+	merkleNode  byte = 0x01
+	merkleEmpty byte = 0x02
+)
+
+// MerkleRoot computes a keyed Merkle root over leaves in the given order.
+// Every node is an HMAC-SHA256, so a root can only be forged with the key.
+func MerkleRoot(key []byte, leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return keyedHash(key, merkleEmpty)
+	}
+
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = keyedHash(key, merkleLeaf, leaf)
+	}
+
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				// Odd node is promoted unchanged
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, keyedHash(key, merkleNode, level[i], level[i+1]))
+		}
+		level = next
+	}
+
+	return level[0]
+}
+
+func keyedHash(key []byte, prefix byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte{prefix})
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
@@ -0,0 +1,175 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// resetSealState puts the package back to "no key ring loaded, not sealed"
+func resetSealState(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		ringMu.Lock()
+		defer ringMu.Unlock()
+		sealedMode, sealedRing, pendingShares, unsealHooks = false, nil, nil, nil
+		cachedRing = nil
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func sealedKeyRingFile(t *testing.T, shares, threshold int) (string, []string, []byte) {
+	t.Helper()
+	key := bytes.Repeat([]byte{9}, 32)
+	keyFile := []byte(`{"active_key_id": "v1", "keys": {"v1": "` + base64.StdEncoding.EncodeToString(key) + `"}}`)
+
+	sealed, parts, err := CreateSealedKeyRing(keyFile, shares, threshold)
+	if err != nil {
+		t.Fatalf("CreateSealedKeyRing: %v", err)
+	}
+	data, err := json.Marshal(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "sealed.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := make([]string, len(parts))
+	for i, part := range parts {
+		encoded[i] = base64.StdEncoding.EncodeToString(part)
+	}
+	return path, encoded, key
+}
+
+func submitShares(t *testing.T, shares ...string) SealStatus {
+	t.Helper()
+	var status SealStatus
+	for _, share := range shares {
+		var err error
+		if status, err = SubmitUnsealShare(share); err != nil {
+			t.Fatalf("SubmitUnsealShare: %v", err)
+		}
+	}
+	return status
+}
+
+func TestSealUnsealCycle(t *testing.T) {
+	resetSealState(t)
+	path, shares, key := sealedKeyRingFile(t, 5, 3)
+
+	if err := InitSealed(path); err != nil {
+		t.Fatalf("InitSealed: %v", err)
+	}
+	hookRan := make(chan struct{}, 2)
+	OnUnseal(func() { hookRan <- struct{}{} })
+
+	for round, subset := range [][]string{shares[:3], shares[2:]} {
+		if !IsSealed() {
+			t.Fatalf("round %d: not sealed before unsealing", round)
+		}
+		if _, err := LoadKeyRing(); !errors.Is(err, ErrSealed) {
+			t.Fatalf("round %d: LoadKeyRing error = %v, want ErrSealed", round, err)
+		}
+
+		status := submitShares(t, subset[:2]...)
+		if !status.Sealed || status.Progress != 2 || status.Threshold != 3 || status.Shares != 5 {
+			t.Fatalf("round %d: status after 2 shares = %+v", round, status)
+		}
+		if status = submitShares(t, subset[2]); status.Sealed {
+			t.Fatalf("round %d: still sealed after the threshold", round)
+		}
+		<-hookRan
+
+		ring, err := LoadKeyRing()
+		if err != nil {
+			t.Fatalf("round %d: LoadKeyRing: %v", round, err)
+		}
+		if _, active := ring.Active(); !bytes.Equal(active, key) {
+			t.Fatalf("round %d: unsealed ring has the wrong key", round)
+		}
+
+		if err := Seal(); err != nil {
+			t.Fatalf("round %d: Seal: %v", round, err)
+		}
+		// Callers only hold copies, the ring itself is wiped
+		if len(ring.IDs()) != 0 {
+			t.Fatalf("round %d: sealed ring still holds keys", round)
+		}
+	}
+}
+
+func TestUnsealRejectsBadShares(t *testing.T) {
+	resetSealState(t)
+	path, shares, _ := sealedKeyRingFile(t, 3, 2)
+	_, foreign, _ := sealedKeyRingFile(t, 3, 2)
+	if err := InitSealed(path); err != nil {
+		t.Fatalf("InitSealed: %v", err)
+	}
+
+	if _, err := SubmitUnsealShare("not base64"); err == nil {
+		t.Error("malformed share: expected an error")
+	}
+	if _, err := SubmitUnsealShare(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("short share: expected an error")
+	}
+
+	submitShares(t, shares[0])
+	if _, err := SubmitUnsealShare(shares[0]); err == nil {
+		t.Error("duplicate share: expected an error")
+	}
+
+	// A share from another split yields a wrong key and resets progress
+	for _, share := range foreign {
+		status, err := SubmitUnsealShare(share)
+		if errors.Is(err, ErrInvalidUnsealShares) {
+			if !status.Sealed || status.Progress != 0 {
+				t.Fatalf("status after a wrong share = %+v, want sealed with no progress", status)
+			}
+			break
+		}
+		if err == nil {
+			t.Fatal("a foreign share unsealed the key ring")
+		}
+	}
+
+	if status := submitShares(t, shares[1], shares[2]); status.Sealed {
+		t.Fatal("correct shares did not unseal after a failed attempt")
+	}
+}
+
+func TestSealRequiresSealedMode(t *testing.T) {
+	resetSealState(t)
+	if err := Seal(); !errors.Is(err, ErrSealNotConfigured) {
+		t.Errorf("Seal error = %v, want ErrSealNotConfigured", err)
+	}
+	share := base64.StdEncoding.EncodeToString(make([]byte, 33))
+	if _, err := SubmitUnsealShare(share); !errors.Is(err, ErrSealNotConfigured) {
+		t.Errorf("SubmitUnsealShare error = %v, want ErrSealNotConfigured", err)
+	}
+}
+
+func TestInitSealedRejectsBadFiles(t *testing.T) {
+	resetSealState(t)
+	path, _, _ := sealedKeyRingFile(t, 3, 2)
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitSealed(path); err == nil {
+		t.Error("world-readable sealed key ring: expected an error")
+	}
+
+	header := filepath.Join(t.TempDir(), "sealed.json")
+	if err := os.WriteFile(header, []byte(`{"version": 2, "threshold": 1, "shares": 3}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitSealed(header); err == nil {
+		t.Error("threshold below 2: expected an error")
+	}
+}
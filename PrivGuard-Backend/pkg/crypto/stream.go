@@ -0,0 +1,178 @@
+// pkg/crypto/stream.go
+package crypto
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Large payloads such as attachments are encrypted as a stream of chunks so
+// they never have to sit in memory whole:
+//
+//	"PGS1" | 15-byte nonce prefix | chunk 0 | chunk 1 | ... | final chunk
+//
+// Every chunk holds up to StreamChunkSize plaintext bytes sealed with
+// XChaCha20-Poly1305 under nonce = prefix | 8-byte chunk counter | final flag,
+// so chunks can't be reordered, dropped or the stream cut short unnoticed.
+const (
+	StreamChunkSize  = 64 * 1024
+	streamMagic      = "PGS1"
+	streamPrefixSize = chacha20poly1305.NonceSizeX - 9
+	streamHeaderSize = len(streamMagic) + streamPrefixSize
+	streamSealedSize = StreamChunkSize + chacha20poly1305.Overhead
+)
+
+var ErrStreamCorrupt = errors.New("encrypted stream is corrupt or has been tampered with")
+
+// StreamCiphertextSize returns the encrypted size of an n-byte plaintext
+func StreamCiphertextSize(n int64) int64 {
+	chunks := n / StreamChunkSize
+	if n%StreamChunkSize != 0 || n == 0 {
+		chunks++
+	}
+	return int64(streamHeaderSize) + n + chunks*chacha20poly1305.Overhead
+}
+
+// EncryptStream encrypts src to dst with a 256-bit key, binding every chunk to
+// aad. It returns the number of plaintext bytes read.
+func EncryptStream(dst io.Writer, src io.Reader, key, aad []byte) (int64, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return 0, err
+	}
+
+	header := make([]byte, streamHeaderSize)
+	copy(header, streamMagic)
+	prefix := header[len(streamMagic):]
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return 0, err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return 0, err
+	}
+
+	in := bufio.NewReaderSize(src, StreamChunkSize)
+	plain := make([]byte, StreamChunkSize)
+	sealed := make([]byte, 0, streamSealedSize)
+	var read int64
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(in, plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return read, err
+		}
+		read += int64(n)
+
+		// A full chunk is only final if nothing follows it
+		final := n < StreamChunkSize
+		if !final {
+			if _, err := in.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return read, err
+			}
+		}
+
+		sealed = aead.Seal(sealed[:0], streamNonce(prefix, counter, final), plain[:n], aad)
+		if _, err := dst.Write(sealed); err != nil {
+			return read, err
+		}
+		if final {
+			return read, nil
+		}
+	}
+}
+
+// NewDecryptReader returns a reader of the plaintext of a stream written by
+// EncryptStream. Chunks are authenticated before any of their bytes are
+// returned; a tampered or truncated stream fails with ErrStreamCorrupt.
+func NewDecryptReader(src io.Reader, key, aad []byte) (io.Reader, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, ErrStreamCorrupt
+	}
+	if string(header[:len(streamMagic)]) != streamMagic {
+		return nil, ErrStreamCorrupt
+	}
+
+	return &streamReader{
+		src:    bufio.NewReaderSize(src, streamSealedSize),
+		aead:   aead,
+		prefix: header[len(streamMagic):],
+		aad:    aad,
+		sealed: make([]byte, streamSealedSize),
+	}, nil
+}
+
+type streamReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	aad     []byte
+	counter uint64
+	sealed  []byte
+	plain   []byte
+	done    bool
+	err     error
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk
+func (r *streamReader) next() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	final := n < streamSealedSize
+	if !final {
+		if _, err := r.src.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(r.sealed[:0], streamNonce(r.prefix, r.counter, final), r.sealed[:n], r.aad)
+	if err != nil {
+		return ErrStreamCorrupt
+	}
+	r.plain = plain
+	r.done = final
+	r.counter++
+	return nil
+}
+
+func streamNonce(prefix []byte, counter uint64, final bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[streamPrefixSize:], counter)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
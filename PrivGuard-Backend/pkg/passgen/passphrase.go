@@ -0,0 +1,172 @@
+// pkg/passgen/passphrase.go
+package passgen
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Passphrase limits
+const (
+	MinPassphraseWords = 3
+	MaxPassphraseWords = 20
+	maxSeparatorLength = 3
+	minWordlistSize    = 1024
+)
+
+// Capitalization styles for passphrase words
+const (
+	CapitalizeNone   = ""
+	CapitalizeFirst  = "first"  // every word starts with a capital letter
+	CapitalizeRandom = "random" // each word is capitalized with probability 1/2
+)
+
+var ErrNoWordlist = errors.New("passphrase wordlist could not be loaded")
+
+// effLargeWordlist is the EFF large wordlist (https://www.eff.org/dice): 7776
+// words, so every word adds log2(7776) ≈ 12.9 bits
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+// Wordlist is a list of distinct words passphrases are drawn from
+type Wordlist []string
+
+// PassphraseOptions configures GeneratePassphrase
+type PassphraseOptions struct {
+	Words         int
+	Separator     string
+	Capitalize    string
+	IncludeNumber bool // append a random digit to one random word
+}
+
+var (
+	defaultWordlist     Wordlist
+	defaultWordlistErr  error
+	defaultWordlistOnce sync.Once
+)
+
+// DefaultWordlist returns the embedded EFF large wordlist, or the list in
+// PASSPHRASE_WORDLIST_FILE when that is set, parsed once
+func DefaultWordlist() (Wordlist, error) {
+	defaultWordlistOnce.Do(func() {
+		path := os.Getenv("PASSPHRASE_WORDLIST_FILE")
+		if path == "" {
+			defaultWordlist, defaultWordlistErr = ParseWordlist(strings.NewReader(effLargeWordlist))
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			defaultWordlistErr = fmt.Errorf("%w: %v", ErrNoWordlist, err)
+			return
+		}
+		defer f.Close()
+		defaultWordlist, defaultWordlistErr = ParseWordlist(f)
+	})
+	return defaultWordlist, defaultWordlistErr
+}
+
+// ParseWordlist reads a wordlist with one word per line, either bare or in the
+// EFF dice format ("11111<tab>abacus"). Words must be distinct.
+func ParseWordlist(r io.Reader) (Wordlist, error) {
+	var words Wordlist
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		word := fields[len(fields)-1]
+		if len(fields) > 2 || len(fields) == 2 && strings.Trim(fields[0], "123456") != "" {
+			return nil, fmt.Errorf("malformed wordlist line %q", scanner.Text())
+		}
+		if seen[word] {
+			return nil, fmt.Errorf("duplicate word %q in wordlist", word)
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wordlist: %w", err)
+	}
+	if len(words) < minWordlistSize {
+		return nil, fmt.Errorf("wordlist has %d words, at least %d are required", len(words), minWordlistSize)
+	}
+	return words, nil
+}
+
+// GeneratePassphrase returns a diceware-style passphrase of words drawn
+// uniformly from the list. Its entropy counts the words, random
+// capitalization and the position and value of the digit.
+func GeneratePassphrase(list Wordlist, opts PassphraseOptions) (*Generated, error) {
+	if opts.Words < MinPassphraseWords || opts.Words > MaxPassphraseWords {
+		return nil, fmt.Errorf("%w: words must be between %d and %d", ErrInvalidOptions, MinPassphraseWords, MaxPassphraseWords)
+	}
+	if utf8.RuneCountInString(opts.Separator) > maxSeparatorLength {
+		return nil, fmt.Errorf("%w: separator is longer than %d characters", ErrInvalidOptions, maxSeparatorLength)
+	}
+	// Letters or digits in the separator would blur word boundaries and the digit
+	if strings.IndexFunc(opts.Separator, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsControl(r)
+	}) >= 0 {
+		return nil, fmt.Errorf("%w: separator can't contain letters, digits or control characters", ErrInvalidOptions)
+	}
+	switch opts.Capitalize {
+	case CapitalizeNone, CapitalizeFirst, CapitalizeRandom:
+	default:
+		return nil, fmt.Errorf("%w: capitalize must be first or random", ErrInvalidOptions)
+	}
+
+	entropy := float64(opts.Words) * math.Log2(float64(len(list)))
+	words := make([]string, opts.Words)
+	for i := range words {
+		n, err := randIndex(len(list))
+		if err != nil {
+			return nil, err
+		}
+		words[i] = list[n]
+
+		capitalize := opts.Capitalize == CapitalizeFirst
+		if opts.Capitalize == CapitalizeRandom {
+			coin, err := randIndex(2)
+			if err != nil {
+				return nil, err
+			}
+			capitalize = coin == 1
+			entropy++
+		}
+		if capitalize {
+			first, size := utf8.DecodeRuneInString(words[i])
+			words[i] = string(unicode.ToUpper(first)) + words[i][size:]
+		}
+	}
+
+	if opts.IncludeNumber {
+		position, err := randIndex(len(words))
+		if err != nil {
+			return nil, err
+		}
+		digit, err := randIndex(10)
+		if err != nil {
+			return nil, err
+		}
+		words[position] += string(rune('0' + digit))
+		entropy += math.Log2(float64(10 * len(words)))
+	}
+
+	return &Generated{
+		Value:       strings.Join(words, opts.Separator),
+		EntropyBits: math.Round(entropy*100) / 100,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package passgen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// testWords returns n distinct lowercase words: "aaaa", "aaab", ...
+func testWords(n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		word := []byte("aaaa")
+		for j, k := len(word)-1, i; j >= 0; j, k = j-1, k/26 {
+			word[j] += byte(k % 26)
+		}
+		words[i] = string(word)
+	}
+	return words
+}
+
+func TestParseWordlist(t *testing.T) {
+	words := testWords(minWordlistSize)
+	dice := make([]string, len(words))
+	for i, word := range words {
+		dice[i] = fmt.Sprintf("%05d\t%s", 11111+i%5, word)
+	}
+
+	for name, input := range map[string]string{
+		"bare":        strings.Join(words, "\n"),
+		"dice format": strings.Join(dice, "\r\n"),
+		"blank lines": "\n\n" + strings.Join(words, "\n\n") + "\n",
+	} {
+		list, err := ParseWordlist(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(list) != len(words) || list[0] != words[0] || list[len(list)-1] != words[len(words)-1] {
+			t.Errorf("%s: parsed %d words, want %d in order", name, len(list), len(words))
+		}
+	}
+
+	list, err := DefaultWordlist()
+	if err != nil || len(list) != 7776 || list[0] != "abacus" {
+		t.Fatalf("DefaultWordlist = %d words starting %q, %v; want the EFF large list", len(list), list[0], err)
+	}
+}
+
+func TestParseWordlistRejectsMalformedLists(t *testing.T) {
+	words := testWords(minWordlistSize)
+	with := func(line string) string {
+		return strings.Join(append([]string{line}, words[1:]...), "\n")
+	}
+	for name, input := range map[string]string{
+		"empty":          "",
+		"too short":      strings.Join(words[1:], "\n"),
+		"duplicate":      with(words[1]),
+		"three fields":   with("11111\tabacus\textra"),
+		"die face of 7":  with("11117\tabacus"),
+		"die face of 0":  with("01111\tabacus"),
+		"two bare words": with("ice cream"),
+		"duplicate dice": with("11111\t" + words[2]),
+	} {
+		if _, err := ParseWordlist(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: ParseWordlist accepted the list", name)
+		}
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	list := Wordlist(testWords(minWordlistSize))
+	for _, tc := range []struct {
+		name string
+		opts PassphraseOptions
+		bits float64
+	}{
+		{"plain", PassphraseOptions{Words: 4, Separator: "-"}, 40},
+		{"capitalized", PassphraseOptions{Words: 4, Separator: " ", Capitalize: CapitalizeFirst}, 40},
+		{"random capitals", PassphraseOptions{Words: 4, Separator: ".", Capitalize: CapitalizeRandom}, 44},
+		{"with a number", PassphraseOptions{Words: 5, Separator: "_", IncludeNumber: true}, 50 + math.Log2(50)},
+		{"no separator", PassphraseOptions{Words: 3}, 30},
+	} {
+		generated, err := GeneratePassphrase(list, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if want := math.Round(tc.bits*100) / 100; generated.EntropyBits != want {
+			t.Errorf("%s: %v bits, want %v", tc.name, generated.EntropyBits, want)
+		}
+		if tc.opts.Separator == "" {
+			continue
+		}
+		parts := strings.Split(generated.Value, tc.opts.Separator)
+		if len(parts) != tc.opts.Words {
+			t.Errorf("%s: %q has %d words, want %d", tc.name, generated.Value, len(parts), tc.opts.Words)
+		}
+		digits := strings.IndexFunc(generated.Value, unicode.IsDigit) >= 0
+		if digits != tc.opts.IncludeNumber {
+			t.Errorf("%s: %q contains a digit: %v", tc.name, generated.Value, digits)
+		}
+		for _, part := range parts {
+			if tc.opts.Capitalize == CapitalizeFirst && !unicode.IsUpper(rune(part[0])) {
+				t.Errorf("%s: %q isn't capitalized", tc.name, part)
+			}
+		}
+	}
+}
+
+func TestGeneratePassphraseRejectsBadSeparators(t *testing.T) {
+	list := Wordlist(testWords(minWordlistSize))
+	for _, separator := range []string{"----", "a", "é", "1", "-x-", "\t", "\n", "٣"} {
+		if _, err := GeneratePassphrase(list, PassphraseOptions{Words: 4, Separator: separator}); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("separator %q: error = %v, want ErrInvalidOptions", separator, err)
+		}
+	}
+	for _, separator := range []string{"", " ", "-", "...", "–", "🔑"} {
+		if _, err := GeneratePassphrase(list, PassphraseOptions{Words: 4, Separator: separator}); err != nil {
+			t.Errorf("separator %q: %v", separator, err)
+		}
+	}
+
+	for name, opts := range map[string]PassphraseOptions{
+		"too few words":      {Words: MinPassphraseWords - 1},
+		"too many words":     {Words: MaxPassphraseWords + 1},
+		"unknown capitalize": {Words: 4, Capitalize: "upper"},
+	} {
+		if _, err := GeneratePassphrase(list, opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: error = %v, want ErrInvalidOptions", name, err)
+		}
+	}
+}
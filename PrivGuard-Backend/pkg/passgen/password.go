@@ -0,0 +1,223 @@
+// pkg/passgen/password.go
+package passgen
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Character classes a password can draw from
+const (
+	Lowercase = "abcdefghijklmnopqrstuvwxyz"
+	Uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits    = "0123456789"
+	Symbols   = "!#$%&()*+,-./:;<=>?@[]^_{}~"
+
+	// Ambiguous characters are easily confused when read or typed by hand
+	Ambiguous = "Il1|O0o`'\""
+)
+
+// Password length limits
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+// Generation is retried while the minimum counts aren't met, so every password
+// satisfying them is equally likely. Options that would need more than this
+// many tries on average are rejected up front.
+const maxExpectedTries = 1000
+
+var ErrInvalidOptions = errors.New("invalid generator options")
+
+// PasswordOptions configures GeneratePassword. At least one class must be enabled;
+// Min* counts only apply to enabled classes.
+type PasswordOptions struct {
+	Length           int
+	Lowercase        bool
+	Uppercase        bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+	MinLowercase     int
+	MinUppercase     int
+	MinDigits        int
+	MinSymbols       int
+}
+
+// Generated is a generated secret with the entropy of the distribution it was drawn from
+type Generated struct {
+	Value       string
+	EntropyBits float64
+}
+
+type charClass struct {
+	chars string
+	min   int
+}
+
+// GeneratePassword returns a random password drawn uniformly from all passwords
+// of the given length over the enabled classes that meet the minimum counts.
+// Its entropy is log2 of the number of such passwords.
+func GeneratePassword(opts PasswordOptions) (*Generated, error) {
+	if opts.Length < MinPasswordLength || opts.Length > MaxPasswordLength {
+		return nil, fmt.Errorf("%w: length must be between %d and %d", ErrInvalidOptions, MinPasswordLength, MaxPasswordLength)
+	}
+
+	classes, err := opts.classes()
+	if err != nil {
+		return nil, err
+	}
+
+	var alphabet strings.Builder
+	required := 0
+	for _, class := range classes {
+		alphabet.WriteString(class.chars)
+		required += class.min
+	}
+	if required > opts.Length {
+		return nil, fmt.Errorf("%w: minimum counts add up to more than the length", ErrInvalidOptions)
+	}
+	chars := []rune(alphabet.String())
+
+	valid := countPasswords(classes, opts.Length)
+	total := new(big.Int).Exp(big.NewInt(int64(len(chars))), big.NewInt(int64(opts.Length)), nil)
+	if new(big.Int).Mul(valid, big.NewInt(maxExpectedTries)).Cmp(total) < 0 {
+		return nil, fmt.Errorf("%w: minimum counts are too high for the length", ErrInvalidOptions)
+	}
+
+	password := make([]rune, opts.Length)
+	for attempt := 0; attempt < maxExpectedTries*20; attempt++ {
+		for i := range password {
+			n, err := randIndex(len(chars))
+			if err != nil {
+				return nil, err
+			}
+			password[i] = chars[n]
+		}
+		if meetsMinimums(password, classes) {
+			return &Generated{Value: string(password), EntropyBits: log2(valid)}, nil
+		}
+	}
+	return nil, errors.New("failed to generate a password meeting the minimum counts")
+}
+
+// classes returns the enabled character classes with ambiguous characters removed
+func (opts PasswordOptions) classes() ([]charClass, error) {
+	var classes []charClass
+	add := func(enabled bool, chars string, min int) error {
+		if min < 0 {
+			return fmt.Errorf("%w: minimum counts can't be negative", ErrInvalidOptions)
+		}
+		if !enabled {
+			if min > 0 {
+				return fmt.Errorf("%w: minimum count set for a disabled character class", ErrInvalidOptions)
+			}
+			return nil
+		}
+		if opts.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(Ambiguous, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		classes = append(classes, charClass{chars: chars, min: min})
+		return nil
+	}
+
+	if err := add(opts.Lowercase, Lowercase, opts.MinLowercase); err != nil {
+		return nil, err
+	}
+	if err := add(opts.Uppercase, Uppercase, opts.MinUppercase); err != nil {
+		return nil, err
+	}
+	if err := add(opts.Digits, Digits, opts.MinDigits); err != nil {
+		return nil, err
+	}
+	if err := add(opts.Symbols, Symbols, opts.MinSymbols); err != nil {
+		return nil, err
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("%w: enable at least one character class", ErrInvalidOptions)
+	}
+	return classes, nil
+}
+
+func meetsMinimums(password []rune, classes []charClass) bool {
+	for _, class := range classes {
+		if class.min == 0 {
+			continue
+		}
+		count := 0
+		for _, r := range password {
+			if strings.ContainsRune(class.chars, r) {
+				count++
+			}
+		}
+		if count < class.min {
+			return false
+		}
+	}
+	return true
+}
+
+// countPasswords counts the strings of the given length in which every class
+// appears at least its minimum number of times. ways[j] holds the number of
+// ways to fill j of the positions with the classes seen so far.
+func countPasswords(classes []charClass, length int) *big.Int {
+	ways := make([]*big.Int, length+1)
+	for j := range ways {
+		ways[j] = new(big.Int)
+	}
+	ways[0].SetInt64(1)
+
+	for _, class := range classes {
+		size := big.NewInt(int64(len(class.chars)))
+		next := make([]*big.Int, length+1)
+		for j := range next {
+			next[j] = new(big.Int)
+		}
+		for used := 0; used <= length; used++ {
+			if ways[used].Sign() == 0 {
+				continue
+			}
+			// Give k more positions to this class, chosen among the used+k filled so far
+			for k := class.min; used+k <= length; k++ {
+				term := new(big.Int).Binomial(int64(used+k), int64(k))
+				term.Mul(term, new(big.Int).Exp(size, big.NewInt(int64(k)), nil))
+				term.Mul(term, ways[used])
+				next[used+k].Add(next[used+k], term)
+			}
+		}
+		ways = next
+	}
+	return ways[length]
+}
+
+// randIndex returns a uniform random integer in [0, n) from crypto/rand
+func randIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return int(i.Int64()), nil
+}
+
+// log2 of a positive big integer, rounded to two decimals
+func log2(n *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(n).Float64()
+	bits := math.Log2(f)
+	if math.IsInf(f, 1) {
+		// Beyond float64 range: shift down first
+		shift := n.BitLen() - 64
+		f, _ = new(big.Float).SetInt(new(big.Int).Rsh(n, uint(shift))).Float64()
+		bits = math.Log2(f) + float64(shift)
+	}
+	return math.Round(bits*100) / 100
+}
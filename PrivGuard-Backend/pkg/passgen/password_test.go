@@ -0,0 +1,160 @@
+package passgen
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// bruteCount counts the strings over the classes meeting the minimums by
+// enumerating all of them
+func bruteCount(classes []charClass, length int) int64 {
+	var alphabet []rune
+	for _, class := range classes {
+		alphabet = append(alphabet, []rune(class.chars)...)
+	}
+	password := make([]rune, length)
+	var count int64
+	var fill func(i int)
+	fill = func(i int) {
+		if i == length {
+			if meetsMinimums(password, classes) {
+				count++
+			}
+			return
+		}
+		for _, r := range alphabet {
+			password[i] = r
+			fill(i + 1)
+		}
+	}
+	fill(0)
+	return count
+}
+
+func TestCountPasswordsMatchesEnumeration(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		classes []charClass
+		length  int
+	}{
+		{"one class", []charClass{{"abc", 0}}, 4},
+		{"one class with a minimum", []charClass{{"abc", 4}}, 4},
+		{"two classes", []charClass{{"ab", 1}, {"xyz", 0}}, 4},
+		{"two minimums", []charClass{{"ab", 1}, {"xyz", 2}}, 5},
+		{"three classes", []charClass{{"ab", 1}, {"x", 1}, {"0123", 2}}, 5},
+		{"minimums fill the length", []charClass{{"ab", 2}, {"xy", 2}}, 4},
+	} {
+		want := bruteCount(tc.classes, tc.length)
+		if got := countPasswords(tc.classes, tc.length); !got.IsInt64() || got.Int64() != want {
+			t.Errorf("%s: countPasswords = %s, want %d", tc.name, got, want)
+		}
+	}
+}
+
+func TestCountPasswordsBeyondFloatRange(t *testing.T) {
+	// 89 characters at the maximum length: no minimums means every string counts
+	all := []charClass{{Lowercase, 0}, {Uppercase, 0}, {Digits, 0}, {Symbols, 0}}
+	got := countPasswords(all, MaxPasswordLength)
+	want := new(big.Int).Exp(big.NewInt(89), big.NewInt(MaxPasswordLength), nil)
+	if got.Cmp(want) != 0 {
+		t.Fatalf("countPasswords = %s, want 89^%d", got, MaxPasswordLength)
+	}
+
+	for _, tc := range []struct {
+		n    *big.Int
+		bits float64
+	}{
+		{big.NewInt(1), 0},
+		{big.NewInt(1024), 10},
+		{new(big.Int).Lsh(big.NewInt(1), 2000), 2000},
+		{new(big.Int).Lsh(big.NewInt(3), 1500), math.Round((1500+math.Log2(3))*100) / 100},
+	} {
+		if got := log2(tc.n); got != tc.bits {
+			t.Errorf("log2(2^%d...) = %v, want %v", tc.n.BitLen()-1, got, tc.bits)
+		}
+	}
+}
+
+func TestGeneratePasswordEntropy(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts PasswordOptions
+		bits float64
+	}{
+		{"digits", PasswordOptions{Length: 8, Digits: true}, 8 * math.Log2(10)},
+		{"digits with a full minimum", PasswordOptions{Length: 8, Digits: true, MinDigits: 8}, 8 * math.Log2(10)},
+		{"lowercase without ambiguous", PasswordOptions{Length: 10, Lowercase: true, ExcludeAmbiguous: true}, 10 * math.Log2(24)},
+		{"all classes", PasswordOptions{Length: 16, Lowercase: true, Uppercase: true, Digits: true, Symbols: true}, 16 * math.Log2(89)},
+		{"digits without ambiguous", PasswordOptions{Length: 8, Digits: true, ExcludeAmbiguous: true}, 8 * math.Log2(8)},
+	} {
+		generated, err := GeneratePassword(tc.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if want := math.Round(tc.bits*100) / 100; generated.EntropyBits != want {
+			t.Errorf("%s: %v bits, want %v", tc.name, generated.EntropyBits, want)
+		}
+		if len(generated.Value) != tc.opts.Length {
+			t.Errorf("%s: %q has %d characters, want %d", tc.name, generated.Value, len(generated.Value), tc.opts.Length)
+		}
+	}
+
+	// One of each: every string over the 36 characters except the all-letter
+	// and all-digit ones
+	opts := PasswordOptions{Length: 8, Lowercase: true, Digits: true, MinLowercase: 1, MinDigits: 1}
+	generated, err := GeneratePassword(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := math.Pow(36, 8) - math.Pow(26, 8) - math.Pow(10, 8)
+	if want := math.Round(math.Log2(valid)*100) / 100; generated.EntropyBits != want {
+		t.Errorf("lowercase and digits with minimums: %v bits, want %v", generated.EntropyBits, want)
+	}
+}
+
+func TestGeneratePasswordMeetsMinimums(t *testing.T) {
+	opts := PasswordOptions{
+		Length: 8, Lowercase: true, Uppercase: true, Digits: true, Symbols: true,
+		ExcludeAmbiguous: true, MinLowercase: 2, MinUppercase: 2, MinDigits: 2, MinSymbols: 1,
+	}
+	for i := 0; i < 500; i++ {
+		generated, err := GeneratePassword(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := map[string]int{}
+		for _, r := range generated.Value {
+			for _, class := range []string{Lowercase, Uppercase, Digits, Symbols} {
+				if strings.ContainsRune(class, r) {
+					counts[class]++
+				}
+			}
+			if strings.ContainsRune(Ambiguous, r) {
+				t.Fatalf("%q contains the ambiguous character %q", generated.Value, r)
+			}
+		}
+		if counts[Lowercase] < 2 || counts[Uppercase] < 2 || counts[Digits] < 2 || counts[Symbols] < 1 {
+			t.Fatalf("%q doesn't meet the minimums: %v", generated.Value, counts)
+		}
+	}
+}
+
+func TestGeneratePasswordRejectsInvalidOptions(t *testing.T) {
+	for name, opts := range map[string]PasswordOptions{
+		"too short":              {Length: MinPasswordLength - 1, Lowercase: true},
+		"too long":               {Length: MaxPasswordLength + 1, Lowercase: true},
+		"no classes":             {Length: 12},
+		"negative minimum":       {Length: 12, Lowercase: true, MinLowercase: -1},
+		"disabled class minimum": {Length: 12, Lowercase: true, MinDigits: 1},
+		"minimums over length":   {Length: 8, Lowercase: true, Digits: true, MinLowercase: 5, MinDigits: 4},
+		"minimums too unlikely":  {Length: 8, Lowercase: true, Uppercase: true, Digits: true, Symbols: true, MinSymbols: 8},
+		"mostly digits":          {Length: 20, Lowercase: true, Uppercase: true, Symbols: true, Digits: true, MinDigits: 15},
+	} {
+		if generated, err := GeneratePassword(opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: GeneratePassword = %+v, %v; want ErrInvalidOptions", name, generated, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package shamir
+
+import "testing"
+
+func TestMulMatchesBitwiseMultiplication(t *testing.T) {
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			if got, want := mul(byte(a), byte(b)), mulNoTable(byte(a), byte(b)); got != want {
+				t.Fatalf("mul(%#x, %#x) = %#x, want %#x", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestKnownProducts(t *testing.T) {
+	// From FIPS-197 section 4.2
+	tests := []struct{ a, b, want byte }{
+		{0x57, 0x83, 0xc1},
+		{0x57, 0x13, 0xfe},
+		{0x57, 0x02, 0xae},
+		{0x01, 0xff, 0xff},
+		{0x00, 0xff, 0x00},
+	}
+	for _, tt := range tests {
+		if got := mul(tt.a, tt.b); got != tt.want {
+			t.Errorf("mul(%#x, %#x) = %#x, want %#x", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestEveryElementHasAnInverse(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		inv := div(1, byte(a))
+		if mul(byte(a), inv) != 1 {
+			t.Fatalf("%#x * %#x != 1", a, inv)
+		}
+		for b := 0; b < 256; b++ {
+			if got := mul(div(byte(b), byte(a)), byte(a)); got != byte(b) {
+				t.Fatalf("(%#x / %#x) * %#x = %#x", b, a, a, got)
+			}
+		}
+	}
+}
+
+func TestTablesCoverTheField(t *testing.T) {
+	// Generator 3 must reach every non-zero element exactly once
+	seen := make(map[byte]bool)
+	for i := 0; i < 255; i++ {
+		seen[expTable[i]] = true
+		if expTable[i] != expTable[i+255] {
+			t.Fatalf("expTable[%d] != expTable[%d]", i, i+255)
+		}
+	}
+	if len(seen) != 255 || seen[0] {
+		t.Fatalf("generator reaches %d elements, want all 255 non-zero ones", len(seen))
+	}
+}
+
+func TestDivisionByZeroPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("div by zero did not panic")
+		}
+	}()
+	div(1, 0)
+}
@@ -0,0 +1,153 @@
+// pkg/shamir/shamir.go
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Shares are the secret's polynomial evaluations followed by one byte
+// holding the share's non-zero x coordinate, so every share is len(secret)+1 bytes.
+
+// Split divides secret into n shares, any k of which reconstruct it
+func Split(secret []byte, n, k int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret must not be empty")
+	}
+	if k < 2 || n < k || n > 255 {
+		return nil, fmt.Errorf("invalid share parameters: need 2 <= threshold (%d) <= shares (%d) <= 255", k, n)
+	}
+
+	// Distinct random x coordinates in 1..255
+	xs, err := randomCoordinates(n)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = xs[i]
+	}
+
+	coeffs := make([]byte, k)
+	for idx, b := range secret {
+		// f(0) = secret byte, the remaining coefficients are random
+		coeffs[0] = b
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i, x := range xs {
+			shares[i][idx] = evaluate(coeffs, x)
+		}
+	}
+	wipe(coeffs)
+
+	return shares, nil
+}
+
+// Combine reconstructs the secret from at least threshold shares.
+// With fewer shares, or foreign shares, it returns garbage rather than an error;
+// callers must verify the result (e.g. by decrypting something with it).
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are required")
+	}
+
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("share is too short")
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, errors.New("shares have invalid or duplicate coordinates")
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	for idx := range secret {
+		for i, share := range shares {
+			ys[i] = share[idx]
+		}
+		secret[idx] = interpolateAtZero(xs, ys)
+	}
+	wipe(ys)
+
+	return secret, nil
+}
+
+// ShareX returns the x coordinate identifying a share
+func ShareX(share []byte) byte {
+	if len(share) == 0 {
+		return 0
+	}
+	return share[len(share)-1]
+}
+
+func randomCoordinates(n int) ([]byte, error) {
+	var perm [255]byte
+	for i := range perm {
+		perm[i] = byte(i + 1)
+	}
+	// Fisher-Yates shuffle with crypto/rand
+	for i := len(perm) - 1; i > 0; i-- {
+		var b [1]byte
+		var j int
+		for {
+			if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+				return nil, err
+			}
+			// Rejection sampling avoids modulo bias
+			if limit := 256 - 256%(i+1); int(b[0]) < limit {
+				j = int(b[0]) % (i + 1)
+				break
+			}
+		}
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return append([]byte(nil), perm[:n]...), nil
+}
+
+// evaluate computes the polynomial with the given coefficients at x using Horner's method
+func evaluate(coeffs []byte, x byte) byte {
+	var result byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result = add(mul(result, x), coeffs[i])
+	}
+	return result
+}
+
+// interpolateAtZero computes the Lagrange interpolation of the points at x = 0
+func interpolateAtZero(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// basis *= x_j / (x_j - x_i); subtraction is XOR in GF(256)
+			basis = mul(basis, div(xs[j], add(xs[j], xs[i])))
+		}
+		result = add(result, mul(ys[i], basis))
+	}
+	return result
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
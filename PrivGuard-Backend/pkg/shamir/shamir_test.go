@@ -0,0 +1,168 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+// subsets calls fn with every k-element subset of 0..n-1
+func subsets(n, k int, fn func([]int)) {
+	var walk func(start int, chosen []int)
+	walk = func(start int, chosen []int) {
+		if len(chosen) == k {
+			fn(chosen)
+			return
+		}
+		for i := start; i < n; i++ {
+			walk(i+1, append(chosen, i))
+		}
+	}
+	walk(0, nil)
+}
+
+func pick(shares [][]byte, idx []int) [][]byte {
+	picked := make([][]byte, len(idx))
+	for i, j := range idx {
+		picked[i] = append([]byte(nil), shares[j]...)
+	}
+	return picked
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{ n, k int }{
+		{2, 2}, {3, 2}, {3, 3}, {5, 3}, {6, 4}, {7, 7},
+	}
+	for _, tt := range tests {
+		shares, err := Split(secret, tt.n, tt.k)
+		if err != nil {
+			t.Fatalf("Split(n=%d, k=%d): %v", tt.n, tt.k, err)
+		}
+		if len(shares) != tt.n {
+			t.Fatalf("Split(n=%d, k=%d) returned %d shares", tt.n, tt.k, len(shares))
+		}
+
+		for k := tt.k; k <= tt.n; k++ {
+			subsets(tt.n, k, func(idx []int) {
+				got, err := Combine(pick(shares, idx))
+				if err != nil {
+					t.Fatalf("n=%d k=%d shares %v: %v", tt.n, tt.k, idx, err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Fatalf("n=%d k=%d shares %v did not recover the secret", tt.n, tt.k, idx)
+				}
+			})
+		}
+
+		// Below the threshold the result is unrelated to the secret
+		if tt.k > 2 {
+			subsets(tt.n, tt.k-1, func(idx []int) {
+				got, err := Combine(pick(shares, idx))
+				if err != nil {
+					t.Fatalf("n=%d k=%d shares %v: %v", tt.n, tt.k, idx, err)
+				}
+				if bytes.Equal(got, secret) {
+					t.Fatalf("n=%d k=%d: %d shares %v recovered the secret", tt.n, tt.k, tt.k-1, idx)
+				}
+			})
+		}
+	}
+}
+
+func TestSharesHaveDistinctNonZeroCoordinates(t *testing.T) {
+	shares, err := Split([]byte("secret"), 255, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[byte]bool)
+	for _, share := range shares {
+		if len(share) != len("secret")+1 {
+			t.Fatalf("share length %d, want %d", len(share), len("secret")+1)
+		}
+		x := ShareX(share)
+		if x == 0 || seen[x] {
+			t.Fatalf("coordinate %d is zero or repeated", x)
+		}
+		seen[x] = true
+	}
+}
+
+func TestSplitRejectsInvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret []byte
+		n, k   int
+	}{
+		{"empty secret", nil, 3, 2},
+		{"threshold 1", []byte("s"), 3, 1},
+		{"threshold above shares", []byte("s"), 2, 3},
+		{"too many shares", []byte("s"), 256, 2},
+	}
+	for _, tt := range tests {
+		if _, err := Split(tt.secret, tt.n, tt.k); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestCombineRejectsBadShares(t *testing.T) {
+	shares, err := Split([]byte("correct horse battery staple"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Split([]byte("a different secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zeroX := append([]byte(nil), shares[1]...)
+	zeroX[len(zeroX)-1] = 0
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"no shares", nil},
+		{"one share", shares[:1]},
+		{"duplicate share", [][]byte{shares[0], shares[0]}},
+		{"zero coordinate", [][]byte{shares[0], zeroX}},
+		{"different lengths", [][]byte{shares[0], other[1]}},
+		{"too short", [][]byte{{1}, {2}}},
+	}
+	for _, tt := range tests {
+		if _, err := Combine(tt.shares); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestCombineWithForeignShareReturnsGarbage(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, err := Split(secret, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Split(bytes.Repeat([]byte{7}, len(secret)), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same length, distinct coordinates: Combine can't tell, callers must verify
+	for _, o := range other {
+		if ShareX(o) == ShareX(shares[0]) {
+			continue
+		}
+		got, err := Combine([][]byte{shares[0], o})
+		if err != nil {
+			t.Fatalf("Combine: %v", err)
+		}
+		if bytes.Equal(got, secret) {
+			t.Fatal("a foreign share recovered the secret")
+		}
+		return
+	}
+}
@@ -9,12 +9,17 @@ import (
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/blobstore"
+	"github.com/SAURABH-CHOUDHARI/privguard-backend/pkg/breach"
 )
 
 // Repository wraps the DB instance
 type Repository struct {
 	DB          *gorm.DB
 	RedisClient *redis.Client
+	Blobs       blobstore.Store // attachment contents, nil when attachments aren't configured
+	Breaches    *breach.Checker // compromised password lookups, nil when disabled
 }
 
 func (r Repository) FindCredentialByID(passkeyID string) (any, error) {
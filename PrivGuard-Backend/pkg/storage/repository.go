@@ -59,6 +59,7 @@ func (r *Repository) DeleteCache(key string) error {
     return r.RedisClient.Del(context.Background(), key).Err()
 }
 
+// DeletePasswordFromVault moves an entry to the trash; it is purged later
 func (r *Repository) DeletePasswordFromVault(vaultID string, serviceID string) error {
 	return r.DB.
 		Where("vault_id = ? AND id = ?", vaultID, serviceID).
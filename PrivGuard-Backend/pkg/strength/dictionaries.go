@@ -0,0 +1,61 @@
+// pkg/strength/dictionaries.go
+package strength
+
+import (
+	"embed"
+	"strings"
+	"sync"
+)
+
+// Dictionaries passwords are matched against
+const (
+	DictionaryPasswords  = "passwords"
+	DictionaryEnglish    = "english"
+	DictionaryNames      = "names"
+	DictionaryUserInputs = "user_inputs"
+)
+
+type dictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+// The frequency lists of zxcvbn, one lowercase word per line from most to least
+// common. First names and surnames are ranked separately but reported as names.
+//
+//go:embed data/*.txt
+var frequencyLists embed.FS
+
+var frequencyListFiles = []struct{ name, file string }{
+	{DictionaryPasswords, "data/passwords.txt"},
+	{DictionaryEnglish, "data/english.txt"},
+	{DictionaryNames, "data/male_names.txt"},
+	{DictionaryNames, "data/female_names.txt"},
+	{DictionaryNames, "data/surnames.txt"},
+}
+
+// rankedDictionaries parses the embedded lists on first use
+var rankedDictionaries = sync.OnceValue(func() []dictionary {
+	dicts := make([]dictionary, 0, len(frequencyListFiles))
+	for _, list := range frequencyListFiles {
+		words, err := frequencyLists.ReadFile(list.file)
+		if err != nil {
+			panic("strength: missing frequency list " + list.file)
+		}
+		dicts = append(dicts, newDictionary(list.name, string(words)))
+	}
+	return dicts
+})
+
+// newDictionary ranks the words by their position in the list. A word listed
+// twice keeps its first (most common) rank.
+func newDictionary(name, words string) dictionary {
+	fields := strings.Fields(words)
+	d := dictionary{name: name, ranks: make(map[string]int, len(fields))}
+	for _, word := range fields {
+		if _, ok := d.ranks[word]; !ok {
+			d.ranks[word] = len(d.ranks) + 1
+		}
+	}
+	return d
+}
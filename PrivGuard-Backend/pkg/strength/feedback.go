@@ -0,0 +1,127 @@
+// pkg/strength/feedback.go
+package strength
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Feedback explains a weak score and how to improve it
+type Feedback struct {
+	Warning     string   `json:"warning,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Passwords scoring above this get no feedback
+const feedbackMaxScore = 2
+
+const suggestLongerPassword = "Add another word or two. Uncommon words are better."
+
+func feedbackFor(score int, sequence []Match) Feedback {
+	if len(sequence) == 0 {
+		return Feedback{Suggestions: []string{
+			"Use a few words, avoid common phrases",
+			"No need for symbols, digits, or uppercase letters",
+		}}
+	}
+	if score > feedbackMaxScore {
+		return Feedback{}
+	}
+
+	// The longest match is what gives the password away
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if m.J-m.I > longest.J-longest.I {
+			longest = m
+		}
+	}
+
+	feedback := matchFeedback(longest, len(sequence) == 1)
+	feedback.Suggestions = append([]string{suggestLongerPassword}, feedback.Suggestions...)
+	return feedback
+}
+
+func matchFeedback(m Match, soleMatch bool) Feedback {
+	switch m.Pattern {
+	case PatternDictionary:
+		return dictionaryFeedback(m, soleMatch)
+	case PatternSpatial:
+		warning := "Short keyboard patterns are easy to guess"
+		if m.Turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+		return Feedback{
+			Warning:     warning,
+			Suggestions: []string{"Use a longer keyboard pattern with more turns"},
+		}
+	case PatternRepeat:
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		if len([]rune(m.BaseToken)) == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+		return Feedback{
+			Warning:     warning,
+			Suggestions: []string{"Avoid repeated words and characters"},
+		}
+	case PatternSequence:
+		return Feedback{
+			Warning:     "Sequences like abc or 6543 are easy to guess",
+			Suggestions: []string{"Avoid sequences"},
+		}
+	case PatternYear:
+		return Feedback{
+			Warning:     "Recent years are easy to guess",
+			Suggestions: []string{"Avoid recent years", "Avoid years that are associated with you"},
+		}
+	case PatternDate:
+		return Feedback{
+			Warning:     "Dates are often easy to guess",
+			Suggestions: []string{"Avoid dates and years that are associated with you"},
+		}
+	}
+	return Feedback{}
+}
+
+func dictionaryFeedback(m Match, soleMatch bool) Feedback {
+	var feedback Feedback
+	switch m.Dictionary {
+	case DictionaryPasswords:
+		switch {
+		case soleMatch && !m.L33T && !m.Reversed && m.Rank <= 10:
+			feedback.Warning = "This is a top-10 common password"
+		case soleMatch && !m.L33T && !m.Reversed && m.Rank <= 100:
+			feedback.Warning = "This is a top-100 common password"
+		case soleMatch:
+			feedback.Warning = "This is a very common password"
+		default:
+			feedback.Warning = "This is similar to a commonly used password"
+		}
+	case DictionaryEnglish:
+		if soleMatch {
+			feedback.Warning = "A word by itself is easy to guess"
+		}
+	case DictionaryNames:
+		if soleMatch {
+			feedback.Warning = "Names and surnames by themselves are easy to guess"
+		} else {
+			feedback.Warning = "Common names and surnames are easy to guess"
+		}
+	case DictionaryUserInputs:
+		feedback.Warning = "Avoid using details of the account, like the service name or username"
+	}
+
+	runes := []rune(m.Token)
+	switch {
+	case unicode.IsUpper(runes[0]) && strings.ToLower(string(runes[1:])) == string(runes[1:]):
+		feedback.Suggestions = append(feedback.Suggestions, "Capitalization doesn't help very much")
+	case strings.ToUpper(m.Token) == m.Token && strings.ToLower(m.Token) != m.Token:
+		feedback.Suggestions = append(feedback.Suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	}
+	if m.Reversed && len(runes) >= 4 {
+		feedback.Suggestions = append(feedback.Suggestions, "Reversed words aren't much harder to guess")
+	}
+	if m.L33T {
+		feedback.Suggestions = append(feedback.Suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+	return feedback
+}
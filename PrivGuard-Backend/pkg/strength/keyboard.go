@@ -0,0 +1,176 @@
+// pkg/strength/keyboard.go
+package strength
+
+import (
+	"math"
+	"strings"
+)
+
+// keyboardGraph records which keys sit next to each other. Keys are placed on
+// a grid from their row and horizontal offset and neighbours are found
+// geometrically, so the layouts below are all that needs maintaining.
+type keyboardGraph struct {
+	name      string
+	keys      map[rune]keyPosition
+	shifted   map[rune]bool
+	slanted   bool
+	positions int
+	degree    float64 // average number of neighbours per key
+}
+
+type keyPosition struct {
+	row int
+	x   float64
+}
+
+// Directions between neighbouring keys, used to count turns
+const (
+	dirLeft = iota
+	dirRight
+	dirUpLeft
+	dirUp
+	dirUpRight
+	dirDownLeft
+	dirDown
+	dirDownRight
+)
+
+// Each row is a list of keys as "unshifted shifted" pairs, with the row's
+// offset from the left edge in key widths
+type keyboardRow struct {
+	offset float64
+	keys   string
+}
+
+var keyboardGraphs = []*keyboardGraph{
+	newKeyboardGraph("qwerty", true, []keyboardRow{
+		{0, "`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+"},
+		{1.5, "qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|"},
+		{1.75, "aA sS dD fF gG hH jJ kK lL ;: '\""},
+		{2.25, "zZ xX cC vV bB nN mM ,< .> /?"},
+	}),
+	newKeyboardGraph("keypad", false, []keyboardRow{
+		{1, "/ * -"},
+		{0, "7 8 9 +"},
+		{0, "4 5 6"},
+		{0, "1 2 3"},
+		{0, "0 ."},
+	}),
+}
+
+func newKeyboardGraph(name string, slanted bool, rows []keyboardRow) *keyboardGraph {
+	g := &keyboardGraph{
+		name:    name,
+		keys:    make(map[rune]keyPosition),
+		shifted: make(map[rune]bool),
+		slanted: slanted,
+	}
+	for row, r := range rows {
+		for i, key := range strings.Fields(r.keys) {
+			pos := keyPosition{row: row, x: r.offset + float64(i)}
+			for n, char := range []rune(key) {
+				g.keys[char] = pos
+				if n > 0 {
+					g.shifted[char] = true
+				}
+			}
+			g.positions++
+		}
+	}
+
+	// Average degree over distinct keys, counting each unshifted character once
+	neighbours := 0
+	for a, pa := range g.keys {
+		if g.shifted[a] {
+			continue
+		}
+		for b, pb := range g.keys {
+			if !g.shifted[b] && g.adjacent(pa, pb) {
+				neighbours++
+			}
+		}
+	}
+	g.degree = float64(neighbours) / float64(g.positions)
+	return g
+}
+
+// adjacent reports whether two distinct key positions touch. On a slanted
+// keyboard rows are offset so keys in neighbouring rows touch when their
+// centres are less than a key apart; the keypad is a plain grid with diagonals.
+func (g *keyboardGraph) adjacent(a, b keyPosition) bool {
+	if a == b {
+		return false
+	}
+	dx := math.Abs(a.x - b.x)
+	switch a.row - b.row {
+	case 0:
+		return dx == 1
+	case 1, -1:
+		if g.slanted {
+			return dx < 1
+		}
+		return dx <= 1
+	}
+	return false
+}
+
+// direction returns the direction from key a to key b if they are neighbours
+func (g *keyboardGraph) direction(a, b rune) (int, bool) {
+	pa, ok := g.keys[a]
+	if !ok {
+		return 0, false
+	}
+	pb, ok := g.keys[b]
+	if !ok || !g.adjacent(pa, pb) {
+		return 0, false
+	}
+
+	dx := pb.x - pa.x
+	switch {
+	case pb.row == pa.row && dx < 0:
+		return dirLeft, true
+	case pb.row == pa.row:
+		return dirRight, true
+	case pb.row < pa.row && dx < 0:
+		return dirUpLeft, true
+	case pb.row < pa.row && dx == 0:
+		return dirUp, true
+	case pb.row < pa.row:
+		return dirUpRight, true
+	case dx < 0:
+		return dirDownLeft, true
+	case dx == 0:
+		return dirDown, true
+	default:
+		return dirDownRight, true
+	}
+}
+
+// guesses counts the keyboard patterns of up to this length and number of
+// turns, from any starting key, times the ways shift could have been used
+func (g *keyboardGraph) guesses(length, turns, shifted int) float64 {
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		possibleTurns := turns
+		if i-1 < possibleTurns {
+			possibleTurns = i - 1
+		}
+		for j := 1; j <= possibleTurns; j++ {
+			guesses += binomial(i-1, j-1) * float64(g.positions) * math.Pow(g.degree, float64(j))
+		}
+	}
+
+	if shifted > 0 {
+		unshifted := length - shifted
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= shifted && i <= unshifted; i++ {
+				variations += binomial(shifted+unshifted, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
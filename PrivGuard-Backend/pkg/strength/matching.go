@@ -0,0 +1,550 @@
+// pkg/strength/matching.go
+package strength
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Match patterns
+const (
+	PatternDictionary = "dictionary"
+	PatternSpatial    = "spatial"
+	PatternRepeat     = "repeat"
+	PatternSequence   = "sequence"
+	PatternDate       = "date"
+	PatternYear       = "year"
+	PatternBruteforce = "bruteforce"
+)
+
+// Match is a guessable piece of a password, password[I..J] in runes
+type Match struct {
+	Pattern string
+	I, J    int
+	Token   string
+	Guesses float64
+
+	// dictionary
+	Dictionary string
+	Rank       int
+	Reversed   bool
+	L33T       bool
+
+	// spatial
+	Graph   string
+	Turns   int
+	Shifted int
+
+	// repeat
+	BaseToken   string
+	RepeatCount int
+
+	// date and year
+	Year      int
+	Separator string
+
+	log10 float64
+}
+
+// Dates and years are guessed outward from the current year
+const minYearSpace = 20
+
+// omnimatch runs every matcher over the password
+func omnimatch(password []rune, userInputs []string) []Match {
+	var matches []Match
+	matches = append(matches, dictionaryMatches(password, userInputs)...)
+	matches = append(matches, reverseDictionaryMatches(password, userInputs)...)
+	matches = append(matches, l33tMatches(password, userInputs)...)
+	matches = append(matches, spatialMatches(password)...)
+	matches = append(matches, repeatMatches(password)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+	return matches
+}
+
+// dictionaryMatches finds every substring that is a ranked dictionary word.
+// User inputs rank in the order given.
+func dictionaryMatches(password []rune, userInputs []string) []Match {
+	lower := []rune(strings.ToLower(string(password)))
+	inputRanks := make(map[string]int, len(userInputs))
+	for i, input := range userInputs {
+		if _, ok := inputRanks[input]; !ok {
+			inputRanks[input] = i + 1
+		}
+	}
+
+	dicts := rankedDictionaries()
+	var matches []Match
+	for i := range lower {
+		for j := i; j < len(lower); j++ {
+			word := string(lower[i : j+1])
+			for _, dict := range dicts {
+				rank, ok := dict.ranks[word]
+				if !ok {
+					continue
+				}
+				matches = append(matches, dictionaryMatch(password, i, j, dict.name, rank))
+			}
+			if rank, ok := inputRanks[word]; ok {
+				matches = append(matches, dictionaryMatch(password, i, j, DictionaryUserInputs, rank))
+			}
+		}
+	}
+	return matches
+}
+
+func dictionaryMatch(password []rune, i, j int, dictionary string, rank int) Match {
+	token := string(password[i : j+1])
+	return Match{
+		Pattern:    PatternDictionary,
+		I:          i,
+		J:          j,
+		Token:      token,
+		Dictionary: dictionary,
+		Rank:       rank,
+		Guesses:    float64(rank) * uppercaseVariations(token),
+	}
+}
+
+// reverseDictionaryMatches catches words typed backwards
+func reverseDictionaryMatches(password []rune, userInputs []string) []Match {
+	n := len(password)
+	reversed := make([]rune, n)
+	for i, r := range password {
+		reversed[n-1-i] = r
+	}
+
+	var matches []Match
+	for _, m := range dictionaryMatches(reversed, userInputs) {
+		if m.J-m.I < 1 {
+			continue
+		}
+		m.I, m.J = n-1-m.J, n-1-m.I
+		m.Token = string(password[m.I : m.J+1])
+		m.Reversed = true
+		m.Guesses *= 2
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// l33tTable maps substituted characters to the letters they stand for
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'},
+	'8': {'b'},
+	'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'},
+	'6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'0': {'o'},
+	'$': {'s'}, '5': {'s'},
+	'7': {'t'}, '+': {'t'},
+	'%': {'x'},
+	'2': {'z'},
+}
+
+// At most this many substitution tables are tried per password
+const maxL33tSubs = 64
+
+// l33tMatches undoes common character substitutions and looks the result up in
+// the dictionaries. Only matches that actually contain a substitution count.
+func l33tMatches(password []rune, userInputs []string) []Match {
+	var present []rune
+	seen := make(map[rune]bool)
+	for _, r := range password {
+		if _, ok := l33tTable[r]; ok && !seen[r] {
+			seen[r] = true
+			present = append(present, r)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+
+	// Every combination of one reading per substituted character
+	subs := []map[rune]rune{{}}
+	for _, r := range present {
+		var next []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range l33tTable[r] {
+				extended := make(map[rune]rune, len(sub)+1)
+				for k, v := range sub {
+					extended[k] = v
+				}
+				extended[r] = letter
+				next = append(next, extended)
+				if len(next) == maxL33tSubs {
+					break
+				}
+			}
+		}
+		subs = next
+	}
+
+	var matches []Match
+	dedupe := make(map[[2]int]map[string]bool)
+	for _, sub := range subs {
+		translated := make([]rune, len(password))
+		for i, r := range password {
+			if letter, ok := sub[r]; ok {
+				translated[i] = letter
+			} else {
+				translated[i] = r
+			}
+		}
+		for _, m := range dictionaryMatches(translated, userInputs) {
+			token := password[m.I : m.J+1]
+			used := make(map[rune]rune)
+			for _, r := range token {
+				if letter, ok := sub[r]; ok {
+					used[r] = letter
+				}
+			}
+			// Single characters like "1" or "@" aren't l33t words
+			if len(used) == 0 || m.J == m.I {
+				continue
+			}
+			key := [2]int{m.I, m.J}
+			if dedupe[key] == nil {
+				dedupe[key] = make(map[string]bool)
+			}
+			if dedupe[key][m.Dictionary] {
+				continue
+			}
+			dedupe[key][m.Dictionary] = true
+
+			m.Token = string(token)
+			m.L33T = true
+			m.Guesses *= l33tVariations(token, used)
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// uppercaseVariations counts the ways a word could have been capitalized;
+// capitalizing the first or last letter, or all of them, is treated as one bit
+func uppercaseVariations(token string) float64 {
+	if strings.ToLower(token) == token {
+		return 1
+	}
+	runes := []rune(token)
+	upper, lower := 0, 0
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if lower == 0 || upper == 1 && (unicode.IsUpper(runes[0]) || unicode.IsUpper(runes[len(runes)-1])) {
+		return 2
+	}
+	variations := 0.0
+	for i := 1; i <= upper && i <= lower; i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// l33tVariations counts the ways the substitutions could have been applied
+func l33tVariations(token []rune, used map[rune]rune) float64 {
+	variations := 1.0
+	for subbed, letter := range used {
+		s, u := 0, 0
+		for _, r := range token {
+			if r == subbed {
+				s++
+			} else if unicode.ToLower(r) == letter {
+				u++
+			}
+		}
+		if s == 0 || u == 0 {
+			variations *= 2
+			continue
+		}
+		possibilities := 0.0
+		for i := 1; i <= s && i <= u; i++ {
+			possibilities += binomial(s+u, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+// spatialMatches finds runs of three or more keys that are next to each other
+// on a keyboard
+func spatialMatches(password []rune) []Match {
+	var matches []Match
+	for _, g := range keyboardGraphs {
+		i := 0
+		for i < len(password)-1 {
+			j := i
+			turns, shifted := 0, 0
+			if g.shifted[password[i]] {
+				shifted++
+			}
+			lastDirection := -1
+			for j+1 < len(password) {
+				direction, ok := g.direction(password[j], password[j+1])
+				if !ok {
+					break
+				}
+				if direction != lastDirection {
+					turns++
+					lastDirection = direction
+				}
+				if g.shifted[password[j+1]] {
+					shifted++
+				}
+				j++
+			}
+			if j-i+1 >= 3 {
+				matches = append(matches, Match{
+					Pattern: PatternSpatial,
+					I:       i,
+					J:       j,
+					Token:   string(password[i : j+1]),
+					Graph:   g.name,
+					Turns:   turns,
+					Shifted: shifted,
+					Guesses: g.guesses(j-i+1, turns, shifted),
+				})
+			}
+			i = j + 1
+		}
+	}
+	return matches
+}
+
+// repeatMatches finds a base string repeated two or more times in a row,
+// preferring the repeat that covers the most characters
+func repeatMatches(password []rune) []Match {
+	var matches []Match
+	n := len(password)
+	for i := 0; i < n-1; {
+		bestBase, bestCount := 0, 0
+		for base := 1; i+2*base <= n; base++ {
+			count := 1
+			for i+(count+1)*base <= n && string(password[i+count*base:i+(count+1)*base]) == string(password[i:i+base]) {
+				count++
+			}
+			if count >= 2 && base*count > bestBase*bestCount {
+				bestBase, bestCount = base, count
+			}
+		}
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		base := password[i : i+bestBase]
+		baseGuesses := 0.0
+		if bestBase == 1 {
+			baseGuesses = minGuessesSingleChar + 1
+		} else {
+			lg, _ := mostGuessableSequence(base, omnimatch(base, nil))
+			baseGuesses = math.Pow(10, lg)
+		}
+		j := i + bestBase*bestCount - 1
+		matches = append(matches, Match{
+			Pattern:     PatternRepeat,
+			I:           i,
+			J:           j,
+			Token:       string(password[i : j+1]),
+			BaseToken:   string(base),
+			RepeatCount: bestCount,
+			Guesses:     baseGuesses * float64(bestCount),
+		})
+		i = j + 1
+	}
+	return matches
+}
+
+// Steps between consecutive characters a sequence may use, like "aceg"
+const maxSequenceDelta = 5
+
+// sequenceMatches finds runs like "abc", "7654" or "aceg" with a constant step
+func sequenceMatches(password []rune) []Match {
+	var matches []Match
+	n := len(password)
+	for i := 0; i < n-2; {
+		delta := int(password[i+1]) - int(password[i])
+		if delta == 0 || delta > maxSequenceDelta || delta < -maxSequenceDelta {
+			i++
+			continue
+		}
+		j := i + 1
+		for j+1 < n && int(password[j+1])-int(password[j]) == delta {
+			j++
+		}
+		if j-i+1 < 3 {
+			i++
+			continue
+		}
+
+		token := password[i : j+1]
+		first := token[0]
+		var base float64
+		switch {
+		case strings.ContainsRune("aAzZ019", first):
+			base = 4
+		case unicode.IsDigit(first):
+			base = 10
+		default:
+			base = 26
+		}
+		if delta < 0 {
+			base *= 2
+		}
+		matches = append(matches, Match{
+			Pattern: PatternSequence,
+			I:       i,
+			J:       j,
+			Token:   string(token),
+			Guesses: base * float64(len(token)),
+		})
+		i = j
+	}
+	return matches
+}
+
+// Date separators, as in "12/03/1990" or "1990-03-12"
+const dateSeparators = " /\\_.-"
+
+// dateMatches finds recent years and day/month/year dates with or without separators
+func dateMatches(password []rune) []Match {
+	now := time.Now().Year()
+	var matches []Match
+	n := len(password)
+	for i := 0; i < n; i++ {
+		for j := i + 3; j < n && j < i+10; j++ {
+			token := string(password[i : j+1])
+
+			if j-i+1 == 4 && isDigits(token) {
+				if year, _ := strconv.Atoi(token); year >= 1900 && year <= now+30 {
+					matches = append(matches, Match{
+						Pattern: PatternYear,
+						I:       i,
+						J:       j,
+						Token:   token,
+						Year:    year,
+						Guesses: yearSpace(year, now),
+					})
+				}
+			}
+
+			year, separator, ok := parseDate(token, now)
+			if !ok {
+				continue
+			}
+			guesses := yearSpace(year, now) * 365
+			if separator != "" {
+				guesses *= 4
+			}
+			matches = append(matches, Match{
+				Pattern:   PatternDate,
+				I:         i,
+				J:         j,
+				Token:     token,
+				Year:      year,
+				Separator: separator,
+				Guesses:   guesses,
+			})
+		}
+	}
+	return matches
+}
+
+func yearSpace(year, now int) float64 {
+	return math.Max(math.Abs(float64(year-now)), minYearSpace)
+}
+
+// parseDate reads day, month and year in any common order, written either as
+// 4-8 digits or as three numbers split by the same separator
+func parseDate(token string, now int) (int, string, bool) {
+	var parts [][]string
+	separator := ""
+	if isDigits(token) {
+		if len(token) > 8 {
+			return 0, "", false
+		}
+		for a := 1; a < len(token)-1; a++ {
+			for b := a + 1; b < len(token); b++ {
+				parts = append(parts, []string{token[:a], token[a:b], token[b:]})
+			}
+		}
+	} else {
+		if len(token) < 6 {
+			return 0, "", false
+		}
+		idx := strings.IndexAny(token, dateSeparators)
+		if idx <= 0 {
+			return 0, "", false
+		}
+		separator = token[idx : idx+1]
+		fields := strings.Split(token, separator)
+		if len(fields) != 3 {
+			return 0, "", false
+		}
+		for _, f := range fields {
+			if f == "" || !isDigits(f) {
+				return 0, "", false
+			}
+		}
+		parts = append(parts, fields)
+	}
+
+	for _, p := range parts {
+		if year, ok := dateYear(p, now); ok {
+			return year, separator, true
+		}
+	}
+	return 0, "", false
+}
+
+// dateYear accepts year-first or year-last orderings of a day, month and year
+func dateYear(p []string, now int) (int, bool) {
+	for _, order := range [][3]int{{2, 0, 1}, {2, 1, 0}, {0, 1, 2}, {0, 2, 1}} {
+		yearStr, a, b := p[order[0]], p[order[1]], p[order[2]]
+		if len(yearStr) != 2 && len(yearStr) != 4 || len(a) > 2 || len(b) > 2 {
+			continue
+		}
+		year, _ := strconv.Atoi(yearStr)
+		if len(yearStr) == 2 {
+			// Two-digit years: 50-99 are 19xx, the rest 20xx
+			if year >= 50 {
+				year += 1900
+			} else {
+				year += 2000
+			}
+		} else if year < 1000 || year > now+30 {
+			continue
+		}
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		if validDayMonth(x, y) || validDayMonth(y, x) {
+			return year, true
+		}
+	}
+	return 0, false
+}
+
+func validDayMonth(day, month int) bool {
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
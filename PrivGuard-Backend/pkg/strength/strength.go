@@ -0,0 +1,219 @@
+// pkg/strength/strength.go
+package strength
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Version identifies the estimator. Bump it whenever scoring changes so the
+// recompute job re-scores stored passwords.
+const Version = 2
+
+// Passwords are only analysed up to this many characters; anything longer is
+// well past the strongest score anyway
+const maxAnalysedLength = 100
+
+// Scoring constants, following zxcvbn
+const (
+	bruteforceCardinality  = 10
+	minGuessesSingleChar   = 10
+	minGuessesMultiChar    = 50
+	minGuessesBeforeGrowth = 10000
+	scoreDelta             = 5
+)
+
+// Result is the estimate for one password
+type Result struct {
+	Guesses      float64 // estimated guesses an attacker needs
+	GuessesLog10 float64
+	Score        int  // 0 (too guessable) to 4 (very unguessable), as in zxcvbn
+	Percent      int8 // 0-100, the scale stored on vault entries
+	Feedback     Feedback
+	Sequence     []Match // the matches that make up the cheapest way to guess it
+}
+
+// Estimate scores a password. userInputs are strings an attacker targeting this
+// entry would try first, like the service name, its domain and the username.
+func Estimate(password string, userInputs ...string) Result {
+	runes := []rune(password)
+	if len(runes) > maxAnalysedLength {
+		runes = runes[:maxAnalysedLength]
+	}
+
+	var inputs []string
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if utf8.RuneCountInString(input) >= 3 {
+			inputs = append(inputs, input)
+			// Domains: also try the name without the TLD
+			if name, _, ok := strings.Cut(strings.TrimPrefix(input, "www."), "."); ok && len(name) >= 3 {
+				inputs = append(inputs, name)
+			}
+		}
+	}
+
+	log10Guesses, sequence := mostGuessableSequence(runes, omnimatch(runes, inputs))
+	result := Result{
+		GuessesLog10: math.Round(log10Guesses*100) / 100,
+		Guesses:      math.Pow(10, log10Guesses),
+		Sequence:     sequence,
+	}
+	result.Score = scoreFor(result.Guesses)
+	result.Percent = int8(math.Min(100, math.Round(log10Guesses*10)))
+	result.Feedback = feedbackFor(result.Score, sequence)
+	return result
+}
+
+func scoreFor(guesses float64) int {
+	switch {
+	case guesses < 1e3+scoreDelta:
+		return 0
+	case guesses < 1e6+scoreDelta:
+		return 1
+	case guesses < 1e8+scoreDelta:
+		return 2
+	case guesses < 1e10+scoreDelta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// mostGuessableSequence finds the sequence of non-overlapping matches (with
+// bruteforce filling the gaps) that needs the fewest guesses. Like zxcvbn, a
+// sequence of k matches costs k! * prod(guesses) + 10000^(k-1): the attacker
+// also has to guess how the pieces are ordered, and longer sequences are
+// penalised so a password isn't explained away as many tiny matches.
+// Everything is computed in log10 to stay clear of float overflow.
+func mostGuessableSequence(password []rune, matches []Match) (float64, []Match) {
+	n := len(password)
+	if n == 0 {
+		return 0, nil
+	}
+
+	byEnd := make([][]Match, n)
+	for _, m := range matches {
+		m.log10 = math.Log10(m.Guesses)
+		if m.J-m.I+1 < n {
+			min := float64(minGuessesMultiChar)
+			if m.I == m.J {
+				min = minGuessesSingleChar
+			}
+			if m.Guesses < min {
+				m.Guesses, m.log10 = min, math.Log10(min)
+			}
+		}
+		byEnd[m.J] = append(byEnd[m.J], m)
+	}
+
+	// Bruteforce candidates don't depend on k, so build each (i, j) once
+	bruteforceByEnd := make([][]Match, n)
+	for j := range bruteforceByEnd {
+		bruteforceByEnd[j] = make([]Match, j+1)
+		for i := 0; i <= j; i++ {
+			bruteforceByEnd[j][i] = bruteforceMatch(password, i, j)
+		}
+	}
+
+	// best[k][j]: cheapest log10 product covering password[:j+1] with k matches
+	type cell struct {
+		cost float64
+		prev int // end of the previous match, -1 at the start
+		m    Match
+		ok   bool
+	}
+	best := make([][]cell, n+1)
+	for k := range best {
+		best[k] = make([]cell, n)
+	}
+
+	for j := 0; j < n; j++ {
+		for k := 1; k <= j+1; k++ {
+			consider := func(m Match) {
+				cost := m.log10
+				prev := m.I - 1
+				if prev >= 0 {
+					if !best[k-1][prev].ok {
+						return
+					}
+					cost += best[k-1][prev].cost
+				} else if k != 1 {
+					return
+				}
+				if c := best[k][j]; !c.ok || cost < c.cost {
+					best[k][j] = cell{cost: cost, prev: prev, m: m, ok: true}
+				}
+			}
+			for _, m := range byEnd[j] {
+				consider(m)
+			}
+			for _, m := range bruteforceByEnd[j] {
+				consider(m)
+			}
+		}
+	}
+
+	total, bestK := math.Inf(1), 0
+	for k := 1; k <= n; k++ {
+		c := best[k][n-1]
+		if !c.ok {
+			continue
+		}
+		lgamma, _ := math.Lgamma(float64(k + 1))
+		cost := logSum(lgamma/math.Ln10+c.cost, float64(k-1)*math.Log10(minGuessesBeforeGrowth))
+		if cost < total {
+			total, bestK = cost, k
+		}
+	}
+
+	sequence := make([]Match, bestK)
+	for k, j := bestK, n-1; k > 0; k-- {
+		c := best[k][j]
+		sequence[k-1] = c.m
+		j = c.prev
+	}
+	return total, sequence
+}
+
+func bruteforceMatch(password []rune, i, j int) Match {
+	length := j - i + 1
+	guesses := math.Pow(bruteforceCardinality, float64(length))
+	if length == 1 {
+		guesses = minGuessesSingleChar + 1
+	} else if guesses < minGuessesMultiChar+1 {
+		guesses = minGuessesMultiChar + 1
+	}
+	return Match{
+		Pattern: PatternBruteforce,
+		I:       i,
+		J:       j,
+		Token:   string(password[i : j+1]),
+		Guesses: guesses,
+		log10:   math.Log10(guesses),
+	}
+}
+
+// logSum returns log10(10^a + 10^b)
+func logSum(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log10(1+math.Pow(10, b-a))
+}
+
+// binomial returns n choose k as a float
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	if k > n-k {
+		k = n - k
+	}
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+	return r
+}
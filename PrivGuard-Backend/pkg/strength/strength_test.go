@@ -0,0 +1,376 @@
+package strength
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// findMatch returns the first match with the pattern and token
+func findMatch(matches []Match, pattern, token string) (Match, bool) {
+	for _, m := range matches {
+		if m.Pattern == pattern && m.Token == token {
+			return m, true
+		}
+	}
+	return Match{}, false
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want))
+}
+
+func TestDictionaryMatches(t *testing.T) {
+	for _, tc := range []struct {
+		password   string
+		inputs     []string
+		token      string
+		dictionary string
+		rank       int
+		reversed   bool
+		l33t       bool
+		guesses    float64
+	}{
+		{"password", nil, "password", DictionaryPasswords, 1, false, false, 1},
+		{"xxdragonxx", nil, "dragon", DictionaryPasswords, 7, false, false, 7},
+		// Capitalized first letter, all caps and a mixed word
+		{"Dragon", nil, "Dragon", DictionaryPasswords, 7, false, false, 14},
+		{"DRAGON", nil, "DRAGON", DictionaryPasswords, 7, false, false, 14},
+		{"drAGon", nil, "drAGon", DictionaryPasswords, 7, false, false, 7 * (6 + 15)},
+		{"drowssap", nil, "drowssap", DictionaryPasswords, 1, true, false, 2},
+		// Two substituted characters, each alone in the word: 2 * 2 variations
+		{"r0s3bud", nil, "r0s3bud", DictionaryPasswords, 245, false, true, 245 * 4},
+		{"acmecorp99", []string{"github", "acmecorp"}, "acmecorp", DictionaryUserInputs, 2, false, false, 2},
+	} {
+		matches := omnimatch([]rune(tc.password), tc.inputs)
+		var found bool
+		for _, m := range matches {
+			if m.Pattern != PatternDictionary || m.Token != tc.token || m.Dictionary != tc.dictionary ||
+				m.Reversed != tc.reversed || m.L33T != tc.l33t {
+				continue
+			}
+			found = true
+			if m.Rank != tc.rank || !closeTo(m.Guesses, tc.guesses) {
+				t.Errorf("%s: %q rank %d, %v guesses; want rank %d, %v guesses", tc.password, m.Token, m.Rank, m.Guesses, tc.rank, tc.guesses)
+			}
+		}
+		if !found {
+			t.Errorf("%s: no %s match for %q (reversed %v, l33t %v)", tc.password, tc.dictionary, tc.token, tc.reversed, tc.l33t)
+		}
+	}
+}
+
+func TestL33tNeedsASubstitution(t *testing.T) {
+	for _, m := range l33tMatches([]rune("password1"), nil) {
+		if m.Token == "password" || m.I == m.J {
+			t.Errorf("l33t match %q without a substitution", m.Token)
+		}
+	}
+	if matches := l33tMatches([]rune("password"), nil); len(matches) != 0 {
+		t.Errorf("l33t matches for a password with no substitutable characters: %+v", matches)
+	}
+}
+
+func TestSpatialMatches(t *testing.T) {
+	qwerty := keyboardGraphs[0]
+	for _, tc := range []struct {
+		password string
+		token    string
+		graph    string
+		turns    int
+		shifted  int
+	}{
+		{"qwerty", "qwerty", "qwerty", 1, 0},
+		{"xxasdfgxx", "asdfg", "qwerty", 1, 0},
+		{"QWErty", "QWErty", "qwerty", 1, 3},
+		{"1qaz", "1qaz", "qwerty", 1, 0},
+		{"zaqwsx", "zaqwsx", "qwerty", 3, 0},
+		{"7896", "7896", "keypad", 2, 0},
+	} {
+		var m Match
+		var found bool
+		for _, candidate := range spatialMatches([]rune(tc.password)) {
+			if candidate.Token == tc.token && candidate.Graph == tc.graph {
+				m, found = candidate, true
+			}
+		}
+		if !found {
+			t.Errorf("%s: no %s match for %q", tc.password, tc.graph, tc.token)
+			continue
+		}
+		if m.Turns != tc.turns || m.Shifted != tc.shifted {
+			t.Errorf("%s: %d turns, %d shifted; want %d, %d", tc.password, m.Turns, m.Shifted, tc.turns, tc.shifted)
+		}
+	}
+
+	// Straight runs of 2..6 keys, one turn each: 5 * positions * degree
+	plain := qwerty.guesses(6, 1, 0)
+	if want := 5 * float64(qwerty.positions) * qwerty.degree; !closeTo(plain, want) {
+		t.Errorf("qwerty guesses = %v, want %v", plain, want)
+	}
+	// Three of six keys shifted: C(6,1) + C(6,2) + C(6,3) ways
+	if got := qwerty.guesses(6, 1, 3); !closeTo(got, plain*41) {
+		t.Errorf("shifted guesses = %v, want %v", got, plain*41)
+	}
+	if got := qwerty.guesses(6, 1, 6); !closeTo(got, plain*2) {
+		t.Errorf("all-shifted guesses = %v, want %v", got, plain*2)
+	}
+
+	for _, password := range []string{"qw", "qaaq", "zpqm"} {
+		if matches := spatialMatches([]rune(password)); len(matches) != 0 {
+			t.Errorf("%s: unexpected spatial matches %+v", password, matches)
+		}
+	}
+}
+
+func TestSequenceMatches(t *testing.T) {
+	for _, tc := range []struct {
+		password string
+		token    string
+		guesses  float64
+	}{
+		{"abcdef", "abcdef", 4 * 6},
+		{"zyxwvu", "zyxwvu", 4 * 2 * 6},
+		{"13579", "13579", 4 * 5},
+		{"xxdefghi", "defghi", 26 * 6},
+		{"6789", "6789", 10 * 4},
+		{"aceg", "aceg", 4 * 4},
+		{"PQRS", "PQRS", 26 * 4},
+	} {
+		m, ok := findMatch(sequenceMatches([]rune(tc.password)), PatternSequence, tc.token)
+		if !ok || !closeTo(m.Guesses, tc.guesses) {
+			t.Errorf("%s: sequence %q = %+v, %v; want %v guesses", tc.password, tc.token, m, ok, tc.guesses)
+		}
+	}
+
+	// Steps of more than maxSequenceDelta, and runs of two, aren't sequences
+	for _, password := range []string{"agms", "ab", "aab", "aku"} {
+		if matches := sequenceMatches([]rune(password)); len(matches) != 0 {
+			t.Errorf("%s: unexpected sequence matches %+v", password, matches)
+		}
+	}
+}
+
+func TestRepeatMatches(t *testing.T) {
+	for _, tc := range []struct {
+		password string
+		token    string
+		base     string
+		count    int
+		guesses  float64
+	}{
+		{"aaaaaa", "aaaaaa", "a", 6, (minGuessesSingleChar + 1) * 6},
+		// "abc" is a sequence of 12 guesses, 13 with the single-match term
+		{"abcabcabc", "abcabcabc", "abc", 3, 13 * 3},
+		{"xx!!!", "xx", "x", 2, (minGuessesSingleChar + 1) * 2},
+		// The repeat covering the most characters wins over "aa" repeated
+		{"aabaab", "aabaab", "aab", 2, 0},
+	} {
+		m, ok := findMatch(repeatMatches([]rune(tc.password)), PatternRepeat, tc.token)
+		if !ok || m.BaseToken != tc.base || m.RepeatCount != tc.count {
+			t.Errorf("%s: repeat %q = %+v, %v; want base %q x%d", tc.password, tc.token, m, ok, tc.base, tc.count)
+			continue
+		}
+		if tc.guesses != 0 && !closeTo(m.Guesses, tc.guesses) {
+			t.Errorf("%s: repeat guesses = %v, want %v", tc.password, m.Guesses, tc.guesses)
+		}
+	}
+}
+
+func TestDateMatches(t *testing.T) {
+	now := time.Now().Year()
+	for _, tc := range []struct {
+		password  string
+		pattern   string
+		token     string
+		year      int
+		separator string
+		guesses   float64
+	}{
+		{"12/03/1990", PatternDate, "12/03/1990", 1990, "/", yearSpace(1990, now) * 365 * 4},
+		{"1990-03-12", PatternDate, "1990-03-12", 1990, "-", yearSpace(1990, now) * 365 * 4},
+		{"19900312", PatternDate, "19900312", 1990, "", yearSpace(1990, now) * 365},
+		{"xx3.7.85", PatternDate, "3.7.85", 1985, ".", yearSpace(1985, now) * 365 * 4},
+		{"120349", PatternDate, "120349", 2049, "", yearSpace(2049, now) * 365},
+		{"love1990", PatternYear, "1990", 1990, "", yearSpace(1990, now)},
+		// Recent years are still at least minYearSpace guesses
+		{"x" + time.Now().Format("2006"), PatternYear, time.Now().Format("2006"), now, "", minYearSpace},
+	} {
+		m, ok := findMatch(dateMatches([]rune(tc.password)), tc.pattern, tc.token)
+		if !ok || m.Year != tc.year || m.Separator != tc.separator || !closeTo(m.Guesses, tc.guesses) {
+			t.Errorf("%s: %s %q = %+v, %v; want year %d, separator %q, %v guesses",
+				tc.password, tc.pattern, tc.token, m, ok, tc.year, tc.separator, tc.guesses)
+		}
+	}
+
+	for _, token := range []string{"13/13/1990", "12/03-1990", "32131990", "12//1990"} {
+		if _, ok := findMatch(dateMatches([]rune(token)), PatternDate, token); ok {
+			t.Errorf("%s matched as a date", token)
+		}
+	}
+	if _, ok := findMatch(dateMatches([]rune("1899")), PatternYear, "1899"); ok {
+		t.Error("1899 matched as a year")
+	}
+}
+
+func TestUserInputs(t *testing.T) {
+	without := Estimate("Acmecorp!")
+	with := Estimate("Acmecorp!", "  AcmeCorp.com ", "ab")
+	if with.Guesses >= without.Guesses {
+		t.Fatalf("user inputs didn't make the password more guessable: %v >= %v", with.Guesses, without.Guesses)
+	}
+	// The domain is tried first and its name without the TLD second
+	m, ok := findMatch(with.Sequence, PatternDictionary, "Acmecorp")
+	if !ok || m.Dictionary != DictionaryUserInputs || m.Rank != 2 {
+		t.Fatalf("sequence %+v has no user input match for the domain's name", with.Sequence)
+	}
+	// Inputs shorter than three characters are ignored
+	if got := Estimate("ab12345678", "ab"); got.Guesses != Estimate("ab12345678").Guesses {
+		t.Fatal("a two-character user input changed the estimate")
+	}
+}
+
+func TestScoreFor(t *testing.T) {
+	for _, tc := range []struct {
+		guesses float64
+		score   int
+	}{
+		{1, 0},
+		{1e3, 0},
+		{1e3 + scoreDelta - 1, 0},
+		{1e3 + scoreDelta, 1},
+		{1e6 + scoreDelta - 1, 1},
+		{1e6 + scoreDelta, 2},
+		{1e8 + scoreDelta - 1, 2},
+		{1e8 + scoreDelta, 3},
+		{1e10 + scoreDelta - 1, 3},
+		{1e10 + scoreDelta, 4},
+		{1e30, 4},
+	} {
+		if got := scoreFor(tc.guesses); got != tc.score {
+			t.Errorf("scoreFor(%v) = %d, want %d", tc.guesses, got, tc.score)
+		}
+	}
+}
+
+func TestMostGuessableSequence(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		password string
+		matches  []Match
+		guesses  float64 // total guesses of the best sequence
+		patterns []string
+	}{
+		{
+			name:     "empty",
+			password: "",
+			guesses:  1,
+		},
+		{
+			name:     "bruteforce only",
+			password: "abcdef",
+			guesses:  1e6 + 1,
+			patterns: []string{PatternBruteforce},
+		},
+		{
+			// 2! * 100 * 100 + 10000: cheaper than 10^8 bruteforce
+			name:     "two matches",
+			password: "abcdefgh",
+			matches: []Match{
+				{Pattern: PatternDictionary, I: 0, J: 3, Token: "abcd", Guesses: 100},
+				{Pattern: PatternDictionary, I: 4, J: 7, Token: "efgh", Guesses: 100},
+			},
+			guesses:  2*100*100 + 1e4,
+			patterns: []string{PatternDictionary, PatternDictionary},
+		},
+		{
+			// A match covering the whole password keeps its guesses
+			name:     "whole password",
+			password: "abcdefgh",
+			matches:  []Match{{Pattern: PatternDictionary, I: 0, J: 7, Token: "abcdefgh", Guesses: 1}},
+			guesses:  2,
+			patterns: []string{PatternDictionary},
+		},
+		{
+			// A partial match is raised to minGuessesMultiChar, then followed by one
+			// bruteforced character: 2! * 50 * 11 + 10000
+			name:     "minimum guesses",
+			password: "abcdefghij",
+			matches:  []Match{{Pattern: PatternDictionary, I: 0, J: 8, Token: "abcdefghi", Guesses: 1}},
+			guesses:  2*minGuessesMultiChar*(minGuessesSingleChar+1) + 1e4,
+			patterns: []string{PatternDictionary, PatternBruteforce},
+		},
+		{
+			// Splitting into many cheap pieces costs more than one bruteforce run
+			name:     "growth penalty",
+			password: "abc",
+			matches: []Match{
+				{Pattern: PatternDictionary, I: 0, J: 0, Token: "a", Guesses: 1},
+				{Pattern: PatternDictionary, I: 1, J: 1, Token: "b", Guesses: 1},
+				{Pattern: PatternDictionary, I: 2, J: 2, Token: "c", Guesses: 1},
+			},
+			guesses:  1e3 + 1,
+			patterns: []string{PatternBruteforce},
+		},
+	} {
+		log10, sequence := mostGuessableSequence([]rune(tc.password), tc.matches)
+		if got := math.Pow(10, log10); !closeTo(got, tc.guesses) {
+			t.Errorf("%s: %v guesses, want %v", tc.name, got, tc.guesses)
+		}
+		if len(sequence) != len(tc.patterns) {
+			t.Errorf("%s: sequence %+v, want patterns %v", tc.name, sequence, tc.patterns)
+			continue
+		}
+		next := 0
+		for i, m := range sequence {
+			if m.Pattern != tc.patterns[i] || m.I != next {
+				t.Errorf("%s: sequence %+v, want patterns %v covering the password in order", tc.name, sequence, tc.patterns)
+				break
+			}
+			next = m.J + 1
+		}
+		if len(sequence) > 0 && next != len([]rune(tc.password)) {
+			t.Errorf("%s: sequence ends at %d of %d", tc.name, next, len([]rune(tc.password)))
+		}
+	}
+}
+
+// Scores zxcvbn gives these passwords
+func TestEstimateReferencePasswords(t *testing.T) {
+	for _, tc := range []struct {
+		password string
+		score    int
+	}{
+		{"password", 0},
+		{"123456", 0},
+		{"qwerty", 0},
+		{"letmein", 0},
+		{"trustno1", 0},
+		{"dragon", 0},
+		{"abcdef", 0},
+		{"aaaaaa", 0},
+		{"1qaz2wsx", 0},
+		{"eheuczkqyq", 3},
+		{"correcthorsebatterystaple", 4},
+		{"rWibMFACxAUGZmxhVncy", 4},
+		{"Ba9ZyWABu99[BK#6MBgbH88Tofv)vs$w", 4},
+	} {
+		got := Estimate(tc.password)
+		if got.Score != tc.score {
+			t.Errorf("%s: score %d (10^%.2f guesses), want %d", tc.password, got.Score, got.GuessesLog10, tc.score)
+		}
+		if got.Score > feedbackMaxScore && got.Feedback.Warning != "" {
+			t.Errorf("%s: warning %q on a strong password", tc.password, got.Feedback.Warning)
+		}
+	}
+}
+
+func TestEstimateCapsLength(t *testing.T) {
+	long := ""
+	for len(long) < maxAnalysedLength+50 {
+		long += "x9!Qz"
+	}
+	if got, want := Estimate(long), Estimate(long[:maxAnalysedLength]); got.Guesses != want.Guesses {
+		t.Fatalf("characters past maxAnalysedLength changed the estimate: %v != %v", got.Guesses, want.Guesses)
+	}
+}
@@ -6,11 +6,13 @@ import { Input } from "@/components/ui/input";
 import { Button } from "@/components/ui/button";
 import { ShieldCheck, ShieldAlert, Loader2 } from "lucide-react";
 import { toast } from "sonner";
+import { useAuth } from "@clerk/clerk-react";
 
 export default function PasswordBreachCheck() {
     const [password, setPassword] = useState("");
     const [isLoading, setIsLoading] = useState(false);
     const [result, setResult] = useState<null | number>(null);
+    const { getToken } = useAuth();
 
     const checkPassword = async () => {
         if (!password) return;
@@ -23,7 +25,12 @@ export default function PasswordBreachCheck() {
             const prefix = hash.slice(0, 5);
             const suffix = hash.slice(5);
 
-            const res = await axios.get(`https://api.pwnedpasswords.com/range/${prefix}`);
+            // Only the hash prefix leaves the browser; the backend proxies and caches the range
+            const token = await getToken({ template: "new" });
+            const res = await axios.get(
+                `${import.meta.env.VITE_BACKEND_ADDR}/api/protected/vault/breach-range/${prefix}`,
+                { headers: { Authorization: token }, responseType: "text" }
+            );
             const lines = res.data.split("\n");
 
             const match = lines.find((line: string ) => line.startsWith(suffix));